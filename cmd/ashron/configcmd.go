@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// configCmd implements "ashron config".
+type configCmd struct {
+	Validate struct{}      `cmd:"" help:"Check the config files strictly and report every problem"`
+	Show     configShowCmd `cmd:"" help:"Print the merged configuration"`
+	Path     struct{}      `cmd:"" help:"Print the config files in effect, in layering order"`
+}
+
+type configShowCmd struct {
+	Resolved bool `help:"Show the values in effect, defaults and --profile included, instead of the files as written"`
+}
+
+// run runs the config subcommand; profile is the one --profile selects.
+func (c *configCmd) run(command, profile string, w io.Writer) error {
+	switch command {
+	case "config validate":
+		return validateConfig(w)
+	case "config show":
+		return config.Show(w, c.Show.Resolved, profile)
+	case "config path":
+		for _, path := range config.Files() {
+			if _, err := fmt.Fprintln(w, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown command %q", command)
+}
+
+func validateConfig(w io.Writer) error {
+	issues, err := config.Check()
+	if err != nil {
+		return err
+	}
+	errors := 0
+	for _, issue := range issues {
+		if !issue.Warning {
+			errors++
+		}
+		fmt.Fprintln(w, issue)
+	}
+	if errors > 0 {
+		return fmt.Errorf("%d error(s) in the configuration", errors)
+	}
+	fmt.Fprintf(w, "Configuration OK (%s)", strings.Join(config.Files(), ", "))
+	if len(issues) > 0 {
+		fmt.Fprintf(w, " with %d warning(s)", len(issues))
+	}
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/headless"
+	"github.com/tokuhirom/ashron/internal/jsonschema"
+	"github.com/tokuhirom/ashron/internal/tui"
+)
+
+// execCmd implements "ashron exec".
+type execCmd struct {
+	Prompt        string `arg:"" help:"Prompt to run (\"-\" reads it from stdin)"`
+	OutputSchema  string `help:"JSON Schema file the final answer must match" name:"output-schema" type:"existingfile"`
+	SchemaRetries int    `help:"Turns allowed to fix an answer that does not match --output-schema" name:"schema-retries" default:"2"`
+}
+
+// main runs the command and returns the exit status. Errors are written to
+// stderr directly: once logger.Setup has run without a log file, the log
+// package discards them.
+func (c *execCmd) main(cfg *config.Config, stdin io.Reader, stdout, stderr io.Writer) int {
+	if err := c.run(cfg, stdin, stdout, stderr); err != nil {
+		fmt.Fprintf(stderr, "ashron exec: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func (c *execCmd) run(cfg *config.Config, stdin io.Reader, stdout, stderr io.Writer) error {
+	prompt := c.Prompt
+	if prompt == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("read prompt: %w", err)
+		}
+		prompt = string(data)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("empty prompt")
+	}
+
+	opts := headless.Options{SchemaRetries: c.SchemaRetries, Progress: stderr}
+	if c.OutputSchema != "" {
+		data, err := os.ReadFile(c.OutputSchema)
+		if err != nil {
+			return fmt.Errorf("read output schema: %w", err)
+		}
+		schema, err := jsonschema.Compile(data)
+		if err != nil {
+			return fmt.Errorf("output schema %s: %w", c.OutputSchema, err)
+		}
+		opts.Schema = schema
+		setResponseFormat(cfg, &config.ResponseFormat{
+			Type:   config.ResponseFormatJSONSchema,
+			Name:   "output",
+			Schema: schema.Raw(),
+		})
+	}
+
+	activeCtx, err := cfg.ActiveContext()
+	if err != nil {
+		return err
+	}
+	client, err := api.NewClientForModel(cfg, cfg.Default.Provider, cfg.Default.Model, activeCtx)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	answer, err := headless.Run(ctx, cfg, client, tui.InitialMessages(&cfg.Tools), prompt, opts)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, answer)
+	return err
+}
+
+// setResponseFormat overrides the response format of the default model.
+func setResponseFormat(cfg *config.Config, rf *config.ResponseFormat) {
+	prov, ok := cfg.Providers[cfg.Default.Provider]
+	if !ok {
+		return
+	}
+	model, ok := prov.Models[cfg.Default.Model]
+	if !ok {
+		return
+	}
+	model.ResponseFormat = rf
+	prov.Models[cfg.Default.Model] = model
+}
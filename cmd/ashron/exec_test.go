@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/logger"
+)
+
+func TestExecReportsErrorsOnStderr(t *testing.T) {
+	// Without a log file, logging is discarded; errors must still show.
+	if err := logger.Setup(""); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		Default: config.DefaultConfig{Provider: "local", Model: "m"},
+		Providers: map[string]config.ProviderConfig{"local": {
+			Type:              config.ProviderTypeOpenAICompat,
+			BaseURL:           "http://127.0.0.1:1/v1",
+			APIKey:            "dummy-key",
+			Timeout:           5 * time.Second,
+			ConnectTimeout:    5 * time.Second,
+			FirstTokenTimeout: 5 * time.Second,
+			IdleTimeout:       5 * time.Second,
+			Retry:             config.RetryConfig{MaxAttempts: 1},
+			Models:            map[string]config.ModelConfig{"m": {Model: "m"}},
+		}},
+		Tools:          config.ToolsConfig{MaxOutputSize: 10000, CommandTimeout: time.Second},
+		DefaultContext: config.ContextConfig{MaxTokens: 4096, MaxMessages: 50, CompactionRatio: 0.5},
+	}
+
+	var stdout, stderr bytes.Buffer
+	c := &execCmd{Prompt: "hello"}
+	if status := c.main(cfg, strings.NewReader(""), &stdout, &stderr); status != 1 {
+		t.Fatalf("status = %d, want 1", status)
+	}
+	if !strings.Contains(stderr.String(), "ashron exec: ") || !strings.Contains(stderr.String(), "127.0.0.1:1") {
+		t.Fatalf("stderr = %q, want the provider error", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+
+	stderr.Reset()
+	c = &execCmd{Prompt: " "}
+	if status := c.main(cfg, strings.NewReader(""), &stdout, &stderr); status != 1 || !strings.Contains(stderr.String(), "empty prompt") {
+		t.Fatalf("status = %d, stderr = %q", status, stderr.String())
+	}
+}
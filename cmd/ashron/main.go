@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"log"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
@@ -33,10 +35,18 @@ var cli struct {
 	Yolo    bool   `help:"Disable sandbox and require no tool approvals (dangerous)"`
 	Resume  string `help:"Resume a previous session by ID" name:"resume"`
 	Pick    bool   `help:"Show interactive session picker to resume a previous session" name:"pick"`
+	Record  string `help:"Record every API request/response to this cassette file" name:"record"`
+	Replay  string `help:"Serve API responses from this cassette file instead of the network" name:"replay"`
+	Profile string `help:"Apply a named profile from the config" env:"ASHRON_PROFILE"`
 
 	Acp bool `help:"Run as an ACP (Agent Client Protocol) server over stdin/stdout" name:"acp"`
 
 	Version kong.VersionFlag `help:"Show version and exit"`
+
+	Chat   struct{}  `cmd:"" default:"1" hidden:"" help:"Start an interactive session (default)"`
+	Usage  usageCmd  `cmd:"" help:"Report token usage and cost across sessions"`
+	Exec   execCmd   `cmd:"" help:"Run a prompt non-interactively and print the final answer"`
+	Config configCmd `cmd:"" help:"Validate and inspect the configuration"`
 }
 
 func main() {
@@ -45,12 +55,37 @@ func main() {
 		kong.Description("AI Coding Assistant\n\nAn interactive AI-powered coding assistant that helps with software engineering tasks."),
 		kong.Vars{"version": fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date)},
 	)
-	_ = ctx
+
+	if ctx.Command() == "usage" {
+		if err := cli.Usage.run(os.Stdout); err != nil {
+			fatal("ashron usage", err)
+		}
+		return
+	}
+	if strings.HasPrefix(ctx.Command(), "config ") {
+		if err := cli.Config.run(ctx.Command(), cli.Profile, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "ashron config: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// Load configuration
-	cfg, err := loadConfig()
+	interactive := !cli.Acp && !strings.HasPrefix(ctx.Command(), "exec")
+	cfg, err := loadConfig(interactive)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		exitConfigError("Failed to load configuration", err)
+	}
+	if cli.Profile != "" {
+		if cfg, err = cfg.WithProfile(cli.Profile); err != nil {
+			exitConfigError("Failed to load configuration", err)
+		}
+	}
+	if !interactive {
+		// The TUI shows them itself.
+		for _, w := range cfg.Warnings {
+			fmt.Fprintln(os.Stderr, w)
+		}
 	}
 
 	// Override with command-line flags
@@ -67,6 +102,7 @@ func main() {
 	if cli.APIKey != "" {
 		if prov, ok := cfg.Providers[provName]; ok {
 			prov.APIKey = cli.APIKey
+			prov.APIKeyEnv = ""
 			cfg.Providers[provName] = prov
 		}
 	}
@@ -76,6 +112,17 @@ func main() {
 			cfg.Providers[provName] = prov
 		}
 	}
+	if cli.Record != "" || cli.Replay != "" {
+		for name, prov := range cfg.Providers {
+			if cli.Record != "" {
+				prov.Record = cli.Record
+			}
+			if cli.Replay != "" {
+				prov.Replay = cli.Replay
+			}
+			cfg.Providers[name] = prov
+		}
+	}
 	if cli.Yolo {
 		cfg.Tools.Yolo = true
 		cfg.Tools.SandboxMode = "off"
@@ -83,7 +130,7 @@ func main() {
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
-		log.Fatalf("Invalid configuration: %v", err)
+		exitConfigError("Invalid configuration", err)
 	}
 
 	// Setup logging
@@ -99,24 +146,27 @@ func main() {
 	slog.Info("Starting Ashron", "version", version, "commit", commit)
 	tui.SetBuildInfo(version, commit, date)
 
+	if strings.HasPrefix(ctx.Command(), "exec") {
+		if status := cli.Exec.main(cfg, os.Stdin, os.Stdout, os.Stderr); status != 0 {
+			logger.Close()
+			os.Exit(status)
+		}
+		return
+	}
+
 	// ACP server mode: communicate with an editor via JSON-RPC 2.0 over stdin/stdout.
 	if cli.Acp {
-		_, providerCfg, err := cfg.ActiveProvider()
-		if err != nil {
-			log.Fatalf("ACP: failed to get provider: %v", err)
-		}
-		_, modelCfg, err := cfg.ActiveModel()
+		activeCtx, err := cfg.ActiveContext()
 		if err != nil {
-			log.Fatalf("ACP: failed to get model: %v", err)
+			fatal("ACP: failed to get context config", err)
 		}
-		activeCtx, err := cfg.ActiveContext()
+		apiClient, err := api.NewClientForModel(cfg, cfg.Default.Provider, cfg.Default.Model, activeCtx)
 		if err != nil {
-			log.Fatalf("ACP: failed to get context config: %v", err)
+			fatal("ACP: failed to create API client", err)
 		}
-		apiClient := api.NewClient(providerCfg, modelCfg, activeCtx)
 		acpServer := acp.NewServer(cfg, apiClient, version)
 		if err := acpServer.Run(); err != nil {
-			log.Fatalf("ACP server error: %v", err)
+			fatal("ACP server error", err)
 		}
 		return
 	}
@@ -127,7 +177,7 @@ func main() {
 		var loadErr error
 		sess, loadErr = session.Load(cli.Resume)
 		if loadErr != nil {
-			log.Fatalf("Failed to load session: %v", loadErr)
+			fatal("Failed to load session", loadErr)
 		}
 	} else if cli.Pick {
 		summaries, listErr := session.ListSummaries(30)
@@ -143,7 +193,7 @@ func main() {
 				var loadErr error
 				sess, loadErr = session.Load(pick.SessionID)
 				if loadErr != nil {
-					log.Fatalf("Failed to load selected session: %v", loadErr)
+					fatal("Failed to load selected session", loadErr)
 				}
 			}
 		}
@@ -152,7 +202,7 @@ func main() {
 	// Create the simple TUI model (streaming mode)
 	tuiModel, err := tui.NewSimpleModel(cfg, sess)
 	if err != nil {
-		log.Fatalf("Failed to create application: %v", err)
+		fatal("Failed to create application", err)
 	}
 
 	p := tea.NewProgram(tuiModel)
@@ -172,6 +222,58 @@ func main() {
 	}
 }
 
-func loadConfig() (*config.Config, error) {
+// exitConfigError reports a configuration problem readably and exits.
+func exitConfigError(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+	fmt.Fprintf(os.Stderr, "Run \"ashron config validate\" to check every config file (%s).\n", config.FilePath())
+	os.Exit(1)
+}
+
+// fatal reports err on stderr and exits. Once logger.Setup has run, the log
+// package writes to the log file, or nowhere without one, so log.Fatalf
+// would exit silently.
+func fatal(msg string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+	logger.Close()
+	os.Exit(1)
+}
+
+// loadConfig loads the configuration. A project config that grants approvals
+// or runs commands is asked about once in interactive sessions; until it is
+// trusted those keys are ignored.
+func loadConfig(interactive bool) (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	p := cfg.Project
+	if p == nil || p.Trusted || len(p.Sensitive) == 0 {
+		return cfg, nil
+	}
+	if !interactive || !stdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "Ignoring %s from untrusted %s; start ashron interactively to trust it.\n",
+			strings.Join(p.Sensitive, ", "), p.Path)
+		return cfg, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s sets:\n", p.Path)
+	for _, key := range p.Sensitive {
+		fmt.Fprintf(os.Stderr, "  %s\n", key)
+	}
+	fmt.Fprint(os.Stderr, "These can grant tool approvals, run commands, choose the model, or change where\n"+
+		"API requests go and how they are authenticated. Trust this project config? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+		fmt.Fprintln(os.Stderr, "Ignoring those keys for this session.")
+		return cfg, nil
+	}
+	if err := config.TrustProject(p); err != nil {
+		return nil, fmt.Errorf("failed to trust project config: %w", err)
+	}
 	return config.Load()
 }
+
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/session"
+	"github.com/tokuhirom/ashron/internal/usage"
+)
+
+// usageCmd implements "ashron usage".
+type usageCmd struct {
+	By   []string `help:"Group by any of: day, project, model" default:"day,project,model"`
+	Days int      `help:"Only include the last N days (0 for all)" default:"30"`
+}
+
+func (c *usageCmd) run(w io.Writer) error {
+	for _, key := range c.By {
+		if !slices.Contains(usage.Keys, key) {
+			return fmt.Errorf("unknown grouping %q (supported: %s)", key, strings.Join(usage.Keys, ", "))
+		}
+	}
+
+	summaries, err := session.ListSummaries(0)
+	if err != nil {
+		return err
+	}
+	since := ""
+	if c.Days > 0 {
+		since = time.Now().AddDate(0, 0, 1-c.Days).Format(time.DateOnly)
+	}
+	sessions := make([]usage.SessionRecords, 0, len(summaries))
+	for _, s := range summaries {
+		sessions = append(sessions, usage.SessionRecords{
+			Project: s.WorkingDir,
+			Records: usage.Since(s.Usage, since),
+		})
+	}
+
+	rows := usage.AggregateSessions(sessions, c.By)
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "No usage recorded.")
+		return err
+	}
+	return usage.WriteTable(w, rows, c.By)
+}
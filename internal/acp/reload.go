@@ -0,0 +1,78 @@
+package acp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// watchConfig reloads the configuration whenever its files are edited,
+// until ctx is done.
+func (s *Server) watchConfig(ctx context.Context) {
+	ticker := time.NewTicker(config.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if s.watcher.Changed() {
+				s.reloadConfig()
+			}
+		}
+	}
+}
+
+// reloadConfig re-reads the config files and applies them to the prompts
+// that start from now on, reporting what changed. An invalid configuration
+// is reported and the current one kept.
+func (s *Server) reloadConfig() {
+	s.mu.Lock()
+	cur := s.cfg
+	s.mu.Unlock()
+
+	cfg, changes, err := cur.Reload()
+	if err == nil {
+		err = s.applyConfig(cfg)
+	}
+	if err != nil {
+		slog.Error("ACP: config reload failed", "error", err)
+		fmt.Fprintf(s.stderr, "Config reload failed; keeping the current configuration: %v\n", err)
+		return
+	}
+	s.watcher.Reset(cfg)
+	if len(changes) == 0 {
+		return
+	}
+	slog.Info("ACP: reloaded configuration", "changes", len(changes))
+	fmt.Fprintf(s.stderr, "Reloaded configuration (model: %s/%s):\n", cfg.Default.Provider, cfg.Default.Model)
+	for _, c := range changes {
+		fmt.Fprintf(s.stderr, "  %s\n", c)
+	}
+	for _, w := range cfg.Warnings {
+		fmt.Fprintln(s.stderr, w)
+	}
+}
+
+// applyConfig replaces the configuration, API client and tool executor.
+// Prompts in progress finish with the ones they started with.
+func (s *Server) applyConfig(cfg *config.Config) error {
+	activeCtx, err := cfg.ActiveContext()
+	if err != nil {
+		return err
+	}
+	apiClient, err := api.NewClientForModel(cfg, cfg.Default.Provider, cfg.Default.Model, activeCtx)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.apiClient = apiClient
+	s.toolExec = newExecutor(cfg, s.toolExec.ResultStore)
+	return nil
+}
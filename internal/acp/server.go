@@ -5,9 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -23,6 +23,7 @@ type session struct {
 	id       string
 	cwd      string
 	messages []api.Message
+	toolset  tools.Toolset
 	cancel   context.CancelFunc
 }
 
@@ -33,13 +34,19 @@ type pendingCall struct {
 
 // Server implements the ACP (Agent Client Protocol) over stdin/stdout JSON-RPC 2.0.
 type Server struct {
+	version string
+
+	// mu guards the configuration, which a reload replaces, and sessions.
+	mu        sync.Mutex
 	cfg       *config.Config
 	apiClient *api.Client
 	toolExec  *tools.Executor
-	version   string
+	sessions  map[string]*session
 
-	mu       sync.Mutex
-	sessions map[string]*session
+	// watcher notices edits to the config files; reloads are reported on
+	// stderr.
+	watcher *config.Watcher
+	stderr  io.Writer
 
 	writerMu sync.Mutex
 	encoder  *json.Encoder
@@ -54,23 +61,34 @@ type Server struct {
 // NewServer creates a new ACP server. Tools run in yolo mode (auto-approve all);
 // dangerous tools are gated via session/request_permission sent to the client.
 func NewServer(cfg *config.Config, apiClient *api.Client, version string) *Server {
-	// ACP server approves tools itself via session/request_permission;
-	// set Yolo so the executor never blocks waiting for TUI approval.
-	toolsCfg := cfg.Tools
-	toolsCfg.Yolo = true
 	return &Server{
 		cfg:       cfg,
 		apiClient: apiClient,
-		toolExec:  tools.NewExecutor(&toolsCfg, tools.NewResultStore()),
+		toolExec:  newExecutor(cfg, tools.NewResultStore()),
 		version:   version,
 		sessions:  make(map[string]*session),
+		watcher:   config.NewWatcher(cfg),
+		stderr:    os.Stderr,
 		encoder:   json.NewEncoder(os.Stdout),
 		pending:   make(map[int64]*pendingCall),
 	}
 }
 
+// newExecutor creates the tool executor for cfg.
+func newExecutor(cfg *config.Config, store *tools.ResultStore) *tools.Executor {
+	// ACP server approves tools itself via session/request_permission;
+	// set Yolo so the executor never blocks waiting for TUI approval.
+	toolsCfg := cfg.Tools
+	toolsCfg.Yolo = true
+	return tools.NewExecutor(&toolsCfg, store)
+}
+
 // Run reads newline-delimited JSON-RPC messages from stdin until EOF.
 func (s *Server) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.watchConfig(ctx)
+
 	scanner := bufio.NewScanner(os.Stdin)
 	scanner.Buffer(make([]byte, 4*1024*1024), 4*1024*1024)
 
@@ -224,8 +242,18 @@ func (s *Server) handleSessionPrompt(req Request) {
 	ctx, cancel := context.WithCancel(context.Background())
 	s.mu.Lock()
 	sess.cancel = cancel
+	// The whole turn uses the configuration it started with.
+	apiClient, toolExec := s.apiClient, s.toolExec
 	s.mu.Unlock()
 	defer cancel()
+	var answeredBy string // "provider/model" serving the current request
+	ctx = api.WithFallbackNotifier(ctx, func(info api.FallbackInfo) {
+		answeredBy = info.To
+		s.sendThought(params.SessionID, info.String()+": "+info.Err.Error())
+	})
+	ctx = api.WithRetryNotifier(ctx, func(info api.RetryInfo) {
+		s.sendThought(params.SessionID, info.String()+": "+info.Err.Error())
+	})
 
 	// Switch to the session's working directory.
 	if sess.cwd != "" {
@@ -235,7 +263,7 @@ func (s *Server) handleSessionPrompt(req Request) {
 	}
 
 	sess.messages = append(sess.messages, api.NewUserMessage(params.Prompt))
-	builtinTools := tools.SelectBuiltinTools(params.Prompt)
+	builtinTools := sess.toolset.Select(params.Prompt)
 
 	// Agentic loop: stream → execute tools → stream again until no tool calls.
 	for {
@@ -244,18 +272,21 @@ func (s *Server) handleSessionPrompt(req Request) {
 			return
 		}
 
-		stream, err := s.apiClient.StreamChatCompletionWithTools(ctx, sess.messages, builtinTools)
+		answeredBy = apiClient.Name()
+		stream, err := apiClient.StreamChatCompletionWithTools(ctx, sess.messages, builtinTools)
 		if err != nil {
 			if ctx.Err() != nil {
 				s.sendResult(req.ID, SessionPromptResult{StopReason: "cancelled"})
 				return
 			}
-			s.sendError(req.ID, -32000, "API error: "+err.Error())
+			s.sendError(req.ID, -32000, withNextAction("API error: "+err.Error(), err))
 			return
 		}
 
 		var fullContent strings.Builder
 		var toolCalls []api.ToolCall
+		var reasoningItems []json.RawMessage
+		var reasoning strings.Builder
 		toolCallArgs := make(map[int]*strings.Builder)
 		toolCallsByIndex := make(map[int]*api.ToolCall)
 
@@ -265,7 +296,7 @@ func (s *Server) handleSessionPrompt(req Request) {
 				return
 			}
 			if event.Error != nil {
-				s.sendError(req.ID, -32000, "stream error: "+event.Error.Error())
+				s.sendError(req.ID, -32000, withNextAction("stream error: "+event.Error.Error(), event.Error))
 				return
 			}
 			if event.Data == nil || len(event.Data.Choices) == 0 {
@@ -273,6 +304,18 @@ func (s *Server) handleSessionPrompt(req Request) {
 			}
 
 			choice := event.Data.Choices[0]
+			reasoningItems = append(reasoningItems, choice.Delta.ReasoningItems...)
+
+			if choice.Delta.ReasoningContent != "" {
+				reasoning.WriteString(choice.Delta.ReasoningContent)
+				s.sendNotification("session/update", SessionUpdateParams{
+					SessionID: params.SessionID,
+					Update: SessionUpdate{
+						SessionUpdate: "agent_thought_chunk",
+						Chunk:         choice.Delta.ReasoningContent,
+					},
+				})
+			}
 
 			if choice.Delta.Content != "" {
 				fullContent.WriteString(choice.Delta.Content)
@@ -315,14 +358,21 @@ func (s *Server) handleSessionPrompt(req Request) {
 					}
 					toolCalls = append(toolCalls, *tc)
 				}
-				sess.messages = append(sess.messages, api.Message{
-					Role:      "assistant",
-					Content:   fullContent.String(),
-					ToolCalls: toolCalls,
-				})
+				msg := api.Message{
+					Role:           "assistant",
+					Content:        fullContent.String(),
+					ToolCalls:      toolCalls,
+					ReasoningItems: reasoningItems,
+					Model:          answeredBy,
+				}
+				if apiClient.EchoesReasoning() {
+					msg.ReasoningContent = reasoning.String()
+				}
+				sess.messages = append(sess.messages, msg)
 				break
 			}
 		}
+		api.DrainStream(stream)
 
 		if len(toolCalls) == 0 {
 			break
@@ -365,9 +415,8 @@ func (s *Server) handleSessionPrompt(req Request) {
 				},
 			})
 
-			result := s.toolExec.Execute(tc)
-			historyOutput := tools.CompactToolResultForHistory(tc.Function.Name, result.Output)
-			sess.messages = append(sess.messages, api.NewToolMessage(tc.ID, historyOutput))
+			result := toolExec.Execute(tc)
+			sess.messages = append(sess.messages, tools.ToolMessage(tc, result, apiClient.SupportsVision()))
 
 			status := "completed"
 			if result.Error != nil {
@@ -390,35 +439,9 @@ func (s *Server) handleSessionPrompt(req Request) {
 
 // isAutoApproved returns true if a tool call should be executed without prompting the user.
 func (s *Server) isAutoApproved(tc api.ToolCall) bool {
-	if s.cfg.Tools.Yolo {
-		return true
-	}
-	for _, name := range s.cfg.Tools.AutoApproveTools {
-		if name == tc.Function.Name {
-			return true
-		}
-	}
-	if tc.Function.Name == "execute_command" {
-		var args tools.ExecuteCommandArgs
-		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-			return false
-		}
-		if strings.EqualFold(tools.EffectiveSandboxMode(&s.cfg.Tools, args), "off") {
-			return false
-		}
-		for _, cmd := range s.cfg.Tools.AutoApproveCommands {
-			if strings.HasPrefix(cmd, "/") && strings.HasSuffix(cmd, "/") {
-				pattern := strings.TrimPrefix(strings.TrimSuffix(cmd, "/"), "/")
-				matched, err := regexp.MatchString(pattern, args.Command)
-				if err == nil && matched {
-					return true
-				}
-			} else if cmd == args.Command {
-				return true
-			}
-		}
-	}
-	return false
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return tools.IsAutoApproved(&s.cfg.Tools, tc)
 }
 
 // requestPermission sends a session/request_permission call to the client and
@@ -490,6 +513,26 @@ func (s *Server) requestPermission(ctx context.Context, sessionID string, tc api
 	}
 }
 
+// sendThought reports progress that is not part of the answer (retries,
+// fallbacks) as an agent_thought_chunk session update.
+func (s *Server) sendThought(sessionID, text string) {
+	s.sendNotification("session/update", SessionUpdateParams{
+		SessionID: sessionID,
+		Update: SessionUpdate{
+			SessionUpdate: "agent_thought_chunk",
+			Chunk:         text,
+		},
+	})
+}
+
+// withNextAction appends api.SuggestNextAction's hint for err to message.
+func withNextAction(message string, err error) string {
+	if hint := api.SuggestNextAction(err); hint != "" {
+		return message + "\n" + hint
+	}
+	return message
+}
+
 func (s *Server) sendResult(id *int64, result interface{}) {
 	resp := Response{
 		JSONRPC: "2.0",
@@ -0,0 +1,160 @@
+package acp
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestHandleSessionPromptRunsToolLoop(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "acp.yaml")
+	if err := os.WriteFile(script, []byte(`
+turns:
+  - reasoning: Look at the types first.
+    tool_calls:
+      - name: read_file
+        arguments: {path: types.go}
+  - content: The file defines the protocol types.
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prov := &config.ProviderConfig{Type: config.ProviderTypeScript, Script: script}
+	client := api.NewClient(prov, &config.ModelConfig{Model: "scripted"}, &config.ContextConfig{MaxTokens: 4096})
+	cfg := &config.Config{Tools: config.ToolsConfig{
+		AutoApproveTools: []string{"read_file"},
+		MaxOutputSize:    1024 * 1024,
+		CommandTimeout:   time.Second,
+	}}
+
+	s := NewServer(cfg, client, "test")
+	var out bytes.Buffer
+	s.encoder = json.NewEncoder(&out)
+	s.sessions["sess_1"] = &session{id: "sess_1"}
+
+	id := int64(7)
+	params, _ := json.Marshal(SessionPromptParams{SessionID: "sess_1", Prompt: "what is in types.go?"})
+	s.handleSessionPrompt(Request{JSONRPC: "2.0", ID: &id, Method: "session/prompt", Params: params})
+
+	var updates []string
+	var message strings.Builder
+	var result *SessionPromptResult
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg struct {
+			Method string `json:"method"`
+			Params struct {
+				Update SessionUpdate `json:"update"`
+			} `json:"params"`
+			Result *SessionPromptResult `json:"result"`
+			Error  *RPCError            `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("bad output line %q: %v", line, err)
+		}
+		if msg.Error != nil {
+			t.Fatalf("prompt failed: %s", msg.Error.Message)
+		}
+		if msg.Result != nil {
+			result = msg.Result
+			continue
+		}
+		u := msg.Params.Update
+		switch u.SessionUpdate {
+		case "agent_message_chunk":
+			message.WriteString(u.Chunk)
+		case "tool_call", "tool_call_update":
+			updates = append(updates, u.SessionUpdate+":"+u.Status)
+		default:
+			updates = append(updates, u.SessionUpdate)
+		}
+	}
+
+	if result == nil || result.StopReason != "completed" {
+		t.Fatalf("result = %+v", result)
+	}
+	if message.String() != "The file defines the protocol types." {
+		t.Fatalf("message = %q", message.String())
+	}
+	want := "agent_thought_chunk,tool_call:in_progress,tool_call_update:completed"
+	// Streamed chunks repeat their update; count each once.
+	if got := strings.Join(slices.Compact(updates), ","); got != want {
+		t.Fatalf("updates = %s, want %s", got, want)
+	}
+
+	msgs := s.sessions["sess_1"].messages
+	if len(msgs) != 4 || msgs[2].Role != "tool" || !strings.Contains(msgs[2].Content, "package acp") {
+		t.Fatalf("unexpected session history: %+v", msgs)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	path := filepath.Join(dir, "ashron", "ashron.yaml")
+	content := `
+default: {provider: local, model: m}
+providers:
+  local:
+    type: openai-compat
+    base_url: http://127.0.0.1:0
+    api_key: dummy-key
+    models:
+      m: {model: m}
+tools:
+  auto_approve_tools: [read_file]
+`
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeCtx, _ := cfg.ActiveContext()
+	client, err := api.NewClientForModel(cfg, "local", "m", activeCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(cfg, client, "test")
+	var stderr bytes.Buffer
+	s.stderr = &stderr
+	list := api.ToolCall{Function: api.FunctionCall{Name: "list_directory", Arguments: "{}"}}
+	if s.isAutoApproved(list) {
+		t.Fatal("list_directory is not auto-approved yet")
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(content, "[read_file]", "[read_file, list_directory]", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !s.watcher.Changed() {
+		t.Fatal("the edit should be noticed")
+	}
+	s.reloadConfig()
+	if !s.isAutoApproved(list) || s.apiClient == client {
+		t.Fatal("the reload should apply the new approvals and rebuild the client")
+	}
+	if want := "~ tools.auto_approve_tools: [read_file] -> [read_file, list_directory]"; !strings.Contains(stderr.String(), want) {
+		t.Fatalf("stderr lacks %q:\n%s", want, stderr.String())
+	}
+
+	if err := os.WriteFile(path, []byte(content+"  command_timeout: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stderr.Reset()
+	s.reloadConfig()
+	if !s.isAutoApproved(list) || !strings.Contains(stderr.String(), "Config reload failed") {
+		t.Fatalf("an invalid config should be refused:\n%s", stderr.String())
+	}
+}
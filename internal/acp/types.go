@@ -97,7 +97,7 @@ type SessionUpdateParams struct {
 
 type SessionUpdate struct {
 	SessionUpdate string `json:"sessionUpdate"`
-	// agent_message_chunk
+	// agent_message_chunk / agent_thought_chunk
 	Chunk string `json:"chunk,omitempty"`
 	// tool_call / tool_call_update
 	ToolCallID string `json:"toolCallId,omitempty"`
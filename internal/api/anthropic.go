@@ -0,0 +1,441 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+const (
+	// anthropicDefaultBaseURL is used when an anthropic provider has no base_url.
+	anthropicDefaultBaseURL = "https://api.anthropic.com/v1"
+
+	// anthropicVersion is sent as the anthropic-version header on every request.
+	anthropicVersion = "2023-06-01"
+
+	// anthropicDefaultMaxTokens is used when the model config does not set
+	// max_output_tokens. The Messages API requires max_tokens on every request.
+	anthropicDefaultMaxTokens = 8192
+)
+
+// anthropicRequest is the request body for POST /messages.
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        any                `json:"system,omitempty"` // string or []anthropicContentBlock
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   float32            `json:"temperature,omitempty"`
+	TopP          float32            `json:"top_p,omitempty"`
+	TopK          int                `json:"top_k,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// anthropicContentBlock covers the text, image, tool_use and tool_result
+// block types.
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	// text
+	Text string `json:"text,omitempty"`
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	// Content is a string, or a []anthropicContentBlock when the tool
+	// returned images.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   any    `json:"content,omitempty"`
+
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks a prompt cache breakpoint: the request prefix
+// up to and including the marked block or tool is cached.
+type anthropicCacheControl struct {
+	Type string `json:"type"` // "ephemeral"
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// anthropicBlocks converts the text and image parts of a message into
+// content blocks.
+func anthropicBlocks(msg Message) []anthropicContentBlock {
+	if len(msg.Parts) == 0 {
+		if msg.Content == "" {
+			return nil
+		}
+		return []anthropicContentBlock{{Type: "text", Text: msg.Content}}
+	}
+	var blocks []anthropicContentBlock
+	for _, p := range msg.Parts {
+		switch {
+		case p.Type == ContentPartText && p.Text != "":
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: p.Text})
+		case p.Type == ContentPartImage && p.ImageURL != nil:
+			source := &anthropicImageSource{Type: "url", URL: p.ImageURL.URL}
+			if mime, data, ok := p.ImageURL.DataURL(); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mime, Data: data}
+			}
+			blocks = append(blocks, anthropicContentBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+type anthropicTool struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  FunctionParameters     `json:"input_schema"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicResponse is the non-streaming /messages response.
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// toUsage converts Anthropic usage, where input_tokens excludes cache reads
+// and writes, into a Usage whose PromptTokens counts the whole prompt.
+func (u anthropicUsage) toUsage() Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return Usage{
+		PromptTokens:        prompt,
+		CompletionTokens:    u.OutputTokens,
+		TotalTokens:         prompt + u.OutputTokens,
+		PromptTokensDetails: cachedDetails(u.CacheReadInputTokens),
+	}
+}
+
+// anthropicStreamEvent is the union of all SSE event payloads we care about.
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *anthropicResponse     `json:"message,omitempty"`
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"`
+	Delta        *anthropicStreamDelta  `json:"delta,omitempty"`
+	Usage        *anthropicUsage        `json:"usage,omitempty"`
+	Error        *APIError              `json:"error,omitempty"`
+}
+
+type anthropicStreamDelta struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// anthropicProvider speaks the native Anthropic Messages API (POST /messages).
+type anthropicProvider struct {
+	c *Client
+}
+
+func (p *anthropicProvider) defaultBaseURL() string {
+	return anthropicDefaultBaseURL
+}
+
+func (p *anthropicProvider) setAuth(req *http.Request, apiKey string) {
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if apiKey != "" {
+		req.Header.Set("x-api-key", apiKey)
+	}
+}
+
+// buildRequest maps the OpenAI-shaped conversation onto a Messages API request.
+func (p *anthropicProvider) buildRequest(messages []Message, tools []Tool, format *config.ResponseFormat) *anthropicRequest {
+	c := p.c
+	system, converted := toAnthropicMessages(messages)
+	maxTokens := c.modelCfg.MaxOutputTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+	req := &anthropicRequest{
+		Model:         c.modelCfg.Model,
+		Messages:      converted,
+		MaxTokens:     maxTokens,
+		Temperature:   c.modelCfg.Temperature,
+		TopP:          c.modelCfg.TopP,
+		TopK:          c.modelCfg.TopK,
+		StopSequences: c.modelCfg.Stop,
+	}
+	if instruction := schemaInstruction(format); instruction != "" {
+		system = strings.TrimSpace(system + "\n\n" + instruction)
+	}
+	if system != "" {
+		req.System = system
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: t.Function.Parameters,
+		})
+	}
+	if c.modelCfg.PromptCache == nil || *c.modelCfg.PromptCache {
+		req.addCacheBreakpoints()
+	}
+	return req
+}
+
+// addCacheBreakpoints marks the tools, the system prompt and the last two
+// user turns for prompt caching, the four breakpoints the API allows. The
+// Messages API only caches up to explicit breakpoints; marking the latest
+// turn caches the conversation so far, and the turn before it is where the
+// previous request's cache entry ends, so that entry is read back.
+func (r *anthropicRequest) addCacheBreakpoints() {
+	ephemeral := &anthropicCacheControl{Type: "ephemeral"}
+	if n := len(r.Tools); n > 0 {
+		r.Tools[n-1].CacheControl = ephemeral
+	}
+	if system, ok := r.System.(string); ok && system != "" {
+		r.System = []anthropicContentBlock{{Type: "text", Text: system, CacheControl: ephemeral}}
+	}
+	marked := 0
+	for i := len(r.Messages) - 1; i >= 0 && marked < 2; i-- {
+		msg := &r.Messages[i]
+		if msg.Role != "user" || len(msg.Content) == 0 {
+			continue
+		}
+		msg.Content[len(msg.Content)-1].CacheControl = ephemeral
+		marked++
+	}
+}
+
+// toAnthropicMessages converts chat messages to the Messages API layout.
+//
+// Leading system messages become the top-level system prompt. System messages
+// that appear later in the conversation (collaboration mode switches,
+// compaction summaries) are sent as user text, since the Messages API only
+// accepts a single system prompt. Tool results are sent as tool_result blocks
+// in a user turn, and consecutive turns with the same role are merged because
+// the API requires user and assistant turns to alternate.
+func toAnthropicMessages(messages []Message) (string, []anthropicMessage) {
+	var systemParts []string
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		if messages[i].Content != "" {
+			systemParts = append(systemParts, messages[i].Content)
+		}
+		i++
+	}
+
+	var out []anthropicMessage
+	appendBlocks := func(role string, blocks ...anthropicContentBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, msg := range messages[i:] {
+		switch msg.Role {
+		case "system", "user":
+			appendBlocks("user", anthropicBlocks(msg)...)
+		case "assistant":
+			var blocks []anthropicContentBlock
+			if msg.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+			appendBlocks("assistant", blocks...)
+		case "tool":
+			var content any = msg.Content
+			if len(msg.Parts) > 0 {
+				content = anthropicBlocks(msg)
+			}
+			appendBlocks("user", anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   content,
+			})
+		}
+	}
+
+	return strings.Join(systemParts, "\n\n"), out
+}
+
+// anthropicFinishReason maps a Messages API stop_reason onto the OpenAI
+// finish_reason values that stream consumers already understand.
+func anthropicFinishReason(stopReason string) string {
+	switch stopReason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	case "":
+		return ""
+	default:
+		return "stop"
+	}
+}
+
+// streamChat sends a streaming /messages request and translates the
+// Anthropic SSE events into OpenAI-style StreamResponse chunks.
+func (p *anthropicProvider) streamChat(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
+	req := p.buildRequest(messages, tools, p.c.modelCfg.ResponseFormat)
+	req.Stream = true
+
+	resp, err := p.c.postStream(ctx, "/messages", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		model     string
+		usage     Usage
+		toolIndex = make(map[int]int) // content block index -> tool call index
+	)
+
+	return streamSSE(resp, func(data string, emit func(StreamEvent)) bool {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			slog.Warn("Failed to parse streaming event", "error", err, "data", data)
+			emit(StreamEvent{Error: fmt.Errorf("parse chunk: %w", err)})
+			return true
+		}
+
+		chunk := StreamResponse{Model: model}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				model = ev.Message.Model
+				usage = ev.Message.Usage.toUsage()
+			}
+			return true
+		case "content_block_start":
+			if ev.ContentBlock == nil || ev.ContentBlock.Type != "tool_use" {
+				return true
+			}
+			idx := len(toolIndex)
+			toolIndex[ev.Index] = idx
+			chunk.Choices = []Choice{{Delta: Message{ToolCalls: []ToolCall{{
+				Index:    idx,
+				ID:       ev.ContentBlock.ID,
+				Type:     "function",
+				Function: FunctionCall{Name: ev.ContentBlock.Name},
+			}}}}}
+		case "content_block_delta":
+			if ev.Delta == nil {
+				return true
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				chunk.Choices = []Choice{{Delta: Message{Content: ev.Delta.Text}}}
+			case "thinking_delta":
+				chunk.Choices = []Choice{{Delta: Message{ReasoningContent: ev.Delta.Thinking}}}
+			case "input_json_delta":
+				idx, ok := toolIndex[ev.Index]
+				if !ok || ev.Delta.PartialJSON == "" {
+					return true
+				}
+				chunk.Choices = []Choice{{Delta: Message{ToolCalls: []ToolCall{{
+					Index:    idx,
+					Function: FunctionCall{Arguments: ev.Delta.PartialJSON},
+				}}}}}
+			default:
+				return true
+			}
+		case "message_delta":
+			if ev.Usage != nil {
+				usage.CompletionTokens = ev.Usage.OutputTokens
+			}
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			finalUsage := usage
+			chunk.Usage = &finalUsage
+			reason := ""
+			if ev.Delta != nil {
+				reason = anthropicFinishReason(ev.Delta.StopReason)
+			}
+			chunk.Choices = []Choice{{FinishReason: reason}}
+		case "message_stop":
+			slog.Debug("Stream completed")
+			return false
+		case "error":
+			if ev.Error != nil {
+				emit(StreamEvent{Error: fmt.Errorf("API error: %s (type: %s)", ev.Error.Message, ev.Error.Type)})
+			} else {
+				emit(StreamEvent{Error: fmt.Errorf("API error: %s", data)})
+			}
+			return false
+		default:
+			// ping, content_block_stop and unknown events carry nothing
+			// that maps onto a chat completion delta.
+			return true
+		}
+
+		emit(StreamEvent{Data: &chunk})
+		return true
+	}), nil
+}
+
+func (p *anthropicProvider) summarize(ctx context.Context, messages []Message) (string, *Usage, error) {
+	req := p.buildRequest(append(messages, summarizeInstruction), nil, summaryFormat(p.c.modelCfg.ResponseFormat))
+
+	resp, err := p.c.postJSON(ctx, "/messages", req)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarize request: %w", err)
+	}
+	defer closeBody(resp)
+
+	var completion anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", nil, fmt.Errorf("parse summarize response: %w", err)
+	}
+
+	var sb strings.Builder
+	for _, block := range completion.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	if sb.Len() == 0 {
+		return "", nil, fmt.Errorf("empty summarize response")
+	}
+	usage := completion.Usage.toUsage()
+	return sb.String(), &usage, nil
+}
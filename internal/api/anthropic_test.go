@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newAnthropicTestClient(baseURL string) *Client {
+	return NewClient(
+		&config.ProviderConfig{
+			Type:    config.ProviderTypeAnthropic,
+			BaseURL: baseURL,
+			APIKey:  "test-key",
+			Timeout: 5 * time.Second,
+		},
+		&config.ModelConfig{Model: "claude-test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+}
+
+func TestToAnthropicMessages(t *testing.T) {
+	system, msgs := toAnthropicMessages([]Message{
+		NewSystemMessage("base prompt"),
+		NewSystemMessage("agents.md"),
+		NewUserMessage("read it"),
+		{Role: "assistant", Content: "sure", ToolCalls: []ToolCall{
+			{ID: "toolu_1", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"a"}`}},
+			{ID: "toolu_2", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `not json`}},
+		}},
+		NewToolMessage("toolu_1", "content-a"),
+		NewToolMessage("toolu_2", "content-b"),
+		NewSystemMessage("mode switched"),
+		NewUserMessage("thanks"),
+	})
+
+	if system != "base prompt\n\nagents.md" {
+		t.Fatalf("unexpected system prompt: %q", system)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 alternating messages, got %d: %+v", len(msgs), msgs)
+	}
+	if msgs[0].Role != "user" || msgs[1].Role != "assistant" || msgs[2].Role != "user" {
+		t.Fatalf("unexpected roles: %s %s %s", msgs[0].Role, msgs[1].Role, msgs[2].Role)
+	}
+
+	assistant := msgs[1].Content
+	if len(assistant) != 3 || assistant[1].Type != "tool_use" || assistant[1].ID != "toolu_1" {
+		t.Fatalf("unexpected assistant blocks: %+v", assistant)
+	}
+	if string(assistant[2].Input) != "{}" {
+		t.Fatalf("invalid tool arguments should become {}, got %s", assistant[2].Input)
+	}
+
+	// tool results, the mid-conversation system message and the next user
+	// message are merged into a single user turn.
+	last := msgs[2].Content
+	if len(last) != 4 {
+		t.Fatalf("expected 4 blocks in last user turn, got %+v", last)
+	}
+	if last[0].Type != "tool_result" || last[0].ToolUseID != "toolu_1" || last[0].Content != "content-a" {
+		t.Fatalf("unexpected tool_result block: %+v", last[0])
+	}
+	if last[2].Text != "mode switched" || last[3].Text != "thanks" {
+		t.Fatalf("unexpected trailing text blocks: %+v", last[2:])
+	}
+}
+
+func TestAnthropicStreamTranslatesEvents(t *testing.T) {
+	var gotReq anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("x-api-key") != "test-key" || r.Header.Get("anthropic-version") == "" {
+			http.Error(w, "missing auth headers", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_1","model":"claude-test","content":[],"usage":{"input_tokens":21,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the file."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Let me "}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"look."}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"read_file","input":{}}}`,
+			`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"path\":"}}`,
+			`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"a.txt\"}"}}`,
+			`{"type":"content_block_stop","index":2}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, ev := range events {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(ev), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+		}
+	}))
+	defer server.Close()
+
+	client := newAnthropicTestClient(server.URL)
+	tools := []Tool{{Type: "function", Function: FunctionDef{
+		Name:       "read_file",
+		Parameters: FunctionParameters{Type: "object", Properties: map[string]FunctionProperty{"path": {Type: "string"}}},
+	}}}
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{
+		NewSystemMessage("sys"),
+		NewUserMessage("hi"),
+	}, tools)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+
+	var content, reasoning strings.Builder
+	var args strings.Builder
+	var toolID, toolName, finish string
+	var usage *Usage
+	for ev := range stream {
+		if ev.Error != nil {
+			t.Fatalf("stream error: %v", ev.Error)
+		}
+		if ev.Data.Usage != nil {
+			usage = ev.Data.Usage
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+			reasoning.WriteString(ch.Delta.ReasoningContent)
+			for _, tc := range ch.Delta.ToolCalls {
+				if tc.Index != 0 {
+					t.Fatalf("unexpected tool call index %d", tc.Index)
+				}
+				if tc.ID != "" {
+					toolID, toolName = tc.ID, tc.Function.Name
+				}
+				args.WriteString(tc.Function.Arguments)
+			}
+			if ch.FinishReason != "" {
+				finish = ch.FinishReason
+			}
+		}
+	}
+
+	system, _ := json.Marshal(gotReq.System)
+	if string(system) != `[{"cache_control":{"type":"ephemeral"},"text":"sys","type":"text"}]` || gotReq.MaxTokens != anthropicDefaultMaxTokens || !gotReq.Stream {
+		t.Fatalf("unexpected request: %+v (system %s)", gotReq, system)
+	}
+	if len(gotReq.Tools) != 1 || gotReq.Tools[0].Name != "read_file" || gotReq.Tools[0].CacheControl == nil {
+		t.Fatalf("unexpected tools in request: %+v", gotReq.Tools)
+	}
+	if content.String() != "Let me look." || reasoning.String() != "Need the file." {
+		t.Fatalf("unexpected content: %q (reasoning %q)", content.String(), reasoning.String())
+	}
+	if toolID != "toolu_1" || toolName != "read_file" || args.String() != `{"path":"a.txt"}` {
+		t.Fatalf("unexpected tool call: id=%q name=%q args=%q", toolID, toolName, args.String())
+	}
+	if finish != "tool_calls" {
+		t.Fatalf("finish reason = %q, want tool_calls", finish)
+	}
+	if usage == nil || usage.PromptTokens != 21 || usage.CompletionTokens != 9 || usage.TotalTokens != 30 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestAnthropicSummarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"stream":true`) {
+			t.Errorf("summarize should not stream: %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"msg_1","model":"claude-test","content":[{"type":"text","text":"- summary"}],"stop_reason":"end_turn","usage":{"input_tokens":5,"output_tokens":2}}`)
+	}))
+	defer server.Close()
+
+	client := newAnthropicTestClient(server.URL)
+	summary, err := client.Summarize(context.Background(), []Message{NewUserMessage("hello")})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "- summary" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+}
+
+func TestAnthropicErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens is too large"}}`)
+	}))
+	defer server.Close()
+
+	client := newAnthropicTestClient(server.URL)
+	_, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "max_tokens is too large") {
+		t.Fatalf("expected API error, got %v", err)
+	}
+}
+
+func TestAnthropicCacheBreakpoints(t *testing.T) {
+	messages := []Message{
+		NewSystemMessage("sys"),
+		NewUserMessage("first"),
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_1", Function: FunctionCall{Name: "read_file", Arguments: "{}"}}}},
+		NewToolMessage("toolu_1", "content"),
+		NewUserMessage("more"),
+		{Role: "assistant", Content: "ok"},
+		NewUserMessage("last"),
+	}
+
+	client := newAnthropicTestClient("http://unused")
+	req := client.provider.(*anthropicProvider).buildRequest(messages, nil, nil)
+	var marked []string
+	for _, msg := range req.Messages {
+		for _, b := range msg.Content {
+			if b.CacheControl != nil {
+				marked = append(marked, b.Type+":"+b.Text)
+			}
+		}
+	}
+	// The tool result and "more" are merged into one user turn.
+	if strings.Join(marked, ",") != "text:more,text:last" {
+		t.Fatalf("unexpected breakpoints: %v", marked)
+	}
+	if _, ok := req.System.([]anthropicContentBlock); !ok {
+		t.Fatalf("system prompt should be a cached block: %#v", req.System)
+	}
+
+	disabled := false
+	client.modelCfg.PromptCache = &disabled
+	req = client.provider.(*anthropicProvider).buildRequest(messages, nil, nil)
+	if req.System != "sys" || req.Messages[len(req.Messages)-1].Content[0].CacheControl != nil {
+		t.Fatalf("prompt_cache: false should not add breakpoints: %+v", req)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// azureProvider is the OpenAI-compatible dialect at Azure OpenAI's
+// per-deployment URLs, /openai/deployments/<deployment>/chat/completions
+// with an api-version query parameter. The key is sent in the api-key
+// header; Entra ID tokens use auth_scheme: bearer with api_key_command.
+type azureProvider struct {
+	openAIProvider
+}
+
+func newAzureProvider(c *Client) *azureProvider {
+	deployment := c.modelCfg.Deployment
+	if deployment == "" {
+		deployment = c.modelCfg.Model
+	}
+	version := c.providerCfg.APIVersion
+	if version == "" {
+		version = config.DefaultAzureAPIVersion
+	}
+	return &azureProvider{openAIProvider{
+		c:        c,
+		chatPath: "/openai/deployments/" + url.PathEscape(deployment) + "/chat/completions?api-version=" + url.QueryEscape(version),
+	}}
+}
+
+// defaultBaseURL is empty: every Azure resource has its own endpoint, so
+// base_url is required (see config.Validate).
+func (p *azureProvider) defaultBaseURL() string {
+	return ""
+}
+
+func (p *azureProvider) setAuth(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("api-key", apiKey)
+	}
+}
+
+func (p *azureProvider) listModels(context.Context) ([]string, error) {
+	return nil, errors.New("azure-openai serves deployments; configure them under models")
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestAzureOpenAIStreamsFromDeployment(t *testing.T) {
+	var got *http.Request
+	var body ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(context.Background())
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		// Azure opens the stream with a prompt filter chunk without choices.
+		_, _ = io.WriteString(w, "data: {\"choices\":[],\"prompt_filter_results\":[]}\n\n")
+		writeChunk(w, "hello")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	prov := &config.ProviderConfig{
+		Type:    config.ProviderTypeAzureOpenAI,
+		BaseURL: server.URL + "/",
+		APIKey:  "azure-key",
+		Timeout: 5 * time.Second,
+		Retry:   config.RetryConfig{MaxAttempts: 1},
+	}
+	model := &config.ModelConfig{Model: "gpt-4.1", Deployment: "prod gpt"}
+	client := NewClient(prov, model, &config.ContextConfig{MaxTokens: 4096})
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+	content, err := collectStream(t, stream)
+	if err != nil || content != "hello" {
+		t.Fatalf("content = %q, err = %v", content, err)
+	}
+
+	if got.URL.EscapedPath() != "/openai/deployments/prod%20gpt/chat/completions" {
+		t.Fatalf("path = %q", got.URL.EscapedPath())
+	}
+	if v := got.URL.Query().Get("api-version"); v != config.DefaultAzureAPIVersion {
+		t.Fatalf("api-version = %q", v)
+	}
+	if got.Header.Get("Api-Key") != "azure-key" || got.Header.Get("Authorization") != "" {
+		t.Fatalf("auth headers = %v", got.Header)
+	}
+	if !body.Stream || len(body.Messages) != 1 {
+		t.Fatalf("request body = %+v", body)
+	}
+}
+
+func TestAzureOpenAIEntraIDToken(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Clone(context.Background())
+		_, _ = io.WriteString(w, okCompletion)
+	}))
+	defer server.Close()
+
+	err := summarizeOnce(newTransportTestClient(config.ProviderConfig{
+		Type:       config.ProviderTypeAzureOpenAI,
+		BaseURL:    server.URL,
+		APIKey:     "entra-token",
+		AuthScheme: config.AuthSchemeBearer,
+		APIVersion: "2025-01-01-preview",
+	}))
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if got.URL.Path != "/openai/deployments/test/chat/completions" || got.URL.Query().Get("api-version") != "2025-01-01-preview" {
+		t.Fatalf("url = %s", got.URL)
+	}
+	if got.Header.Get("Authorization") != "Bearer entra-token" || got.Header.Get("Api-Key") != "" {
+		t.Fatalf("auth headers = %v", got.Header)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/tokuhirom/ashron/internal/cassette"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// newTransport returns the HTTP transport for a provider and the wire format
+// to speak over it. Requests go through the provider's proxy and TLS
+// settings (see baseTransport). Providers with a replay cassette are answered from the
+// cassette; a "replay" provider takes its wire format from the recording.
+// When Record is set, every exchange is appended to that cassette as well.
+func newTransport(cfg *config.ProviderConfig) (http.RoundTripper, string) {
+	wireType := cfg.Type
+	transport, err := baseTransport(cfg)
+	if err != nil {
+		// NewClient cannot fail; surface the problem on the first request.
+		slog.Error("Failed to configure HTTP transport", "error", err)
+		transport = failingTransport{err: err}
+	}
+
+	if cfg.Replay != "" {
+		entries, err := cassette.Load(cfg.Replay)
+		if err != nil {
+			// NewClient cannot fail; surface the problem on the first request.
+			slog.Error("Failed to load replay cassette", "path", cfg.Replay, "error", err)
+			transport = failingTransport{err: err}
+		} else {
+			player := cassette.NewPlayer(entries)
+			transport = player
+			if wireType == config.ProviderTypeReplay {
+				wireType = player.ProviderType()
+			}
+			slog.Info("Replaying API responses from cassette",
+				slog.String("path", cfg.Replay),
+				slog.Int("entries", len(entries)),
+				slog.String("type", wireType))
+		}
+	}
+	if wireType == config.ProviderTypeReplay {
+		wireType = config.ProviderTypeOpenAICompat
+	}
+
+	if cfg.Record != "" {
+		slog.Info("Recording API exchanges to cassette", "path", cfg.Record)
+		transport = &cassette.Recorder{
+			Path:         cfg.Record,
+			ProviderType: firstNonEmpty(wireType, config.ProviderTypeOpenAICompat),
+			Transport:    transport,
+			OnError: func(err error) {
+				slog.Warn("Failed to record cassette entry", "path", cfg.Record, "error", err)
+			},
+		}
+	}
+	return transport, wireType
+}
+
+// failingTransport fails every request with err, as a SetupError.
+type failingTransport struct{ err error }
+
+func (t failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, &SetupError{Err: t.err}
+}
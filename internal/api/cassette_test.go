@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestReplayProviderUsesRecordedWireFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"content":[{"type":"text","text":"- recorded summary"}],"stop_reason":"end_turn"}`)
+	}))
+	path := filepath.Join(t.TempDir(), "anthropic.jsonl")
+
+	recorder := NewClient(
+		&config.ProviderConfig{Type: config.ProviderTypeAnthropic, BaseURL: server.URL, Timeout: 5 * time.Second, Record: path},
+		&config.ModelConfig{Model: "claude-test"},
+		&config.ContextConfig{},
+	)
+	if _, err := recorder.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	server.Close()
+
+	replayer := NewClient(
+		&config.ProviderConfig{Type: config.ProviderTypeReplay, Timeout: 5 * time.Second, Replay: path, Retry: config.DefaultRetryConfig},
+		&config.ModelConfig{Model: "claude-test"},
+		&config.ContextConfig{},
+	)
+	summary, err := replayer.Summarize(context.Background(), []Message{NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if summary != "- recorded summary" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+
+	// A request that was never recorded fails instead of reaching the network.
+	if _, err := replayer.Summarize(context.Background(), []Message{NewUserMessage("other")}); err == nil {
+		t.Fatal("expected error for unrecorded request")
+	}
+}
@@ -16,14 +16,66 @@ import (
 	"github.com/tokuhirom/ashron/internal/config"
 )
 
-// Client handles communication with the OpenAI-compatible API
+// Client handles communication with the model provider. The wire format is
+// chosen by ProviderConfig.Type and implemented by a provider; callers only
+// see StreamChatCompletionWithTools and Summarize.
 type Client struct {
 	providerCfg   *config.ProviderConfig
 	modelCfg      *config.ModelConfig
 	contextConfig *config.ContextConfig
 
-	httpClient *http.Client
-	baseURL    string
+	// httpClient bounds whole requests by the provider timeout;
+	// streamClient has no overall limit and relies on the connect,
+	// first-token and idle timeouts instead (see postStream).
+	httpClient   *http.Client
+	streamClient *http.Client
+	baseURL      string
+	provider     provider
+
+	// name is the "provider/model" label set by NewClientForModel.
+	name string
+	// fallbacks are tried in order when a request fails with a retryable
+	// error after all retries.
+	fallbacks []*Client
+	// usageObserver receives the usage of every request (see
+	// SetUsageObserver).
+	usageObserver func(UsageReport)
+	// limiter is shared with every client for the same provider; nil when
+	// the provider has no rate_limit.
+	limiter *rateLimiter
+}
+
+// provider implements one provider wire format. Implementations translate
+// the OpenAI-shaped Message/Tool types into their own request format and
+// translate streamed responses back into StreamResponse chunks, so consumers
+// (TUI, ACP server, subagents) never need to know which API is in use.
+type provider interface {
+	// defaultBaseURL is used when the provider config has no base_url.
+	defaultBaseURL() string
+	// setAuth adds authentication headers for apiKey to an outgoing request.
+	setAuth(req *http.Request, apiKey string)
+	streamChat(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error)
+	summarize(ctx context.Context, messages []Message) (string, *Usage, error)
+	// listModels returns the IDs of the models the provider serves.
+	listModels(ctx context.Context) ([]string, error)
+}
+
+// newProvider returns the provider implementation for wire format typ.
+func newProvider(c *Client, typ string) provider {
+	switch typ {
+	case config.ProviderTypeOpenAIResponses:
+		return &responsesProvider{c: c}
+	case config.ProviderTypeAnthropic:
+		return &anthropicProvider{c: c}
+	case config.ProviderTypeGemini:
+		return &geminiProvider{c: c}
+	case config.ProviderTypeAzureOpenAI:
+		return newAzureProvider(c)
+	case config.ProviderTypeScript:
+		return newScriptProvider(c)
+	default:
+		return &openAIProvider{c: c, chatPath: "/chat/completions"}
+	}
 }
 
 // NewClient creates a new API client
@@ -34,17 +86,33 @@ func NewClient(providerCfg *config.ProviderConfig, modelCfg *config.ModelConfig,
 	}
 
 	slog.Info("Creating API client",
-		slog.Duration("timeout", timeout))
-
-	return &Client{
+		slog.String("type", providerCfg.Type),
+		slog.Duration("timeout", timeout),
+		slog.Duration("connectTimeout", providerCfg.ConnectTimeout),
+		slog.Duration("firstTokenTimeout", providerCfg.FirstTokenTimeout),
+		slog.Duration("idleTimeout", providerCfg.IdleTimeout))
+
+	transport, wireType := newTransport(providerCfg)
+	c := &Client{
 		providerCfg:   providerCfg,
 		modelCfg:      modelCfg,
 		contextConfig: contextConfig,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: transport,
 		},
-		baseURL: strings.TrimSuffix(providerCfg.BaseURL, "/"),
+		streamClient: &http.Client{Transport: transport},
+	}
+	c.provider = newProvider(c, wireType)
+
+	baseURL := providerCfg.BaseURL
+	if baseURL == "" {
+		baseURL = c.provider.defaultBaseURL()
 	}
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+	c.limiter = rateLimiterFor(c.baseURL, providerCfg.RateLimit)
+
+	return c
 }
 
 // StreamEvent represents a streaming response event
@@ -53,77 +121,160 @@ type StreamEvent struct {
 	Error error
 }
 
+// DrainStream reads the rest of stream and returns the last usage it
+// carried. Consumers call it after the finish reason: OpenAI-compatible
+// servers send usage in a separate chunk after it, and the stream goroutine
+// only exits once everything has been read.
+func DrainStream(stream <-chan StreamEvent) *Usage {
+	var usage *Usage
+	for ev := range stream {
+		if ev.Data != nil && ev.Data.Usage != nil {
+			usage = ev.Data.Usage
+		}
+	}
+	return usage
+}
+
 // newRequest creates a new HTTP request with authentication
 func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
-	url := c.baseURL + path
+	return c.newRequestURL(ctx, method, c.baseURL+path, body)
+}
 
+// newRequestURL is newRequest for a URL outside the provider's base URL.
+func (c *Client) newRequestURL(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, &SetupError{Err: fmt.Errorf("create request: %w", err)}
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	if c.providerCfg.APIKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.providerCfg.APIKey)
+	apiKey, err := c.apiKey(ctx)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAuth(req, apiKey)
 
 	return req, nil
 }
 
-// StreamChatCompletionWithTools sends a streaming request with tool support
-func (c *Client) StreamChatCompletionWithTools(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
-	req := &ChatCompletionRequest{
-		Model:             c.modelCfg.Model,
-		Messages:          messages,
-		Temperature:       c.modelCfg.Temperature,
-		TopP:              c.modelCfg.TopP,
-		MinP:              c.modelCfg.MinP,
-		TopK:              c.modelCfg.TopK,
-		FrequencyPenalty:  c.modelCfg.FrequencyPenalty,
-		PresencePenalty:   c.modelCfg.PresencePenalty,
-		Stop:              c.modelCfg.Stop,
-		Seed:              c.modelCfg.Seed,
-		ParallelToolCalls: c.modelCfg.ParallelToolCalls,
-		ReasoningEffort:   c.modelCfg.ReasoningEffort,
-		MaxTokens:         c.contextConfig.MaxTokens,
-		Tools:             tools,
-		Stream:            true,
-		StreamOptions: &StreamOptions{
-			IncludeUsage: true,
-		},
-	}
-	if c.modelCfg.ResponseFormat != "" {
-		req.ResponseFormat = &ResponseFormat{Type: c.modelCfg.ResponseFormat}
-	}
-
-	slog.Info("Sending streaming request",
-		slog.String("model", req.Model),
-		slog.Int("messages", len(req.Messages)),
-		slog.Int("tools", len(req.Tools)))
+// postJSON marshals payload, POSTs it to path and returns the response when
+// the provider answered 200 OK. Any other status is converted to an error via
+// handleError. Retryable failures (see config.RetryConfig) are retried with
+// exponential backoff, honouring Retry-After; callers can observe retries via
+// WithRetryNotifier. The caller owns the returned response body.
+func (c *Client) postJSON(ctx context.Context, path string, payload any) (*http.Response, error) {
+	return c.postWithRetry(ctx, path, payload, c.post)
+}
 
-	slog.Debug("Starting streaming chat completion", "model", req.Model, "messages", len(req.Messages))
+// postStream is postJSON for streaming responses: instead of the provider
+// timeout it applies first_token_timeout until the body starts and
+// idle_timeout between reads after that, failing with a TimeoutError.
+func (c *Client) postStream(ctx context.Context, path string, payload any) (*http.Response, error) {
+	return c.postWithRetry(ctx, path, payload, c.postStreamOnce)
+}
 
-	body, err := json.Marshal(req)
+func (c *Client) postWithRetry(ctx context.Context, path string, payload any,
+	attemptPost func(ctx context.Context, path string, body []byte) (*http.Response, error)) (*http.Response, error) {
+	body, err := json.Marshal(payload)
 	if err != nil {
-		slog.Error("Failed to marshal streaming request", "error", err)
-		return nil, fmt.Errorf("marshal request: %w", err)
+		slog.Error("Failed to marshal request", "error", err)
+		return nil, &SetupError{Err: fmt.Errorf("marshal request: %w", err)}
 	}
 	// Communication log (debug): outgoing request payload.
 	// Keep this bounded to avoid exploding log size on large conversations.
 	slog.Debug("API request payload",
-		slog.String("path", "/chat/completions"),
+		slog.String("path", path),
 		slog.Int("bytes", len(body)),
 		slog.String("json", truncateForLog(string(body), 4000)))
 
-	httpReq, err := c.newRequest(ctx, "POST", "/chat/completions", bytes.NewReader(body))
+	policy := c.providerCfg.Retry
+	maxAttempts := max(policy.MaxAttempts, 1)
+	post := c.withRateLimit(attemptPost)
+	for attempt := 1; ; attempt++ {
+		resp, err := post(ctx, path, body)
+		if err != nil && c.refreshAPIKey(err) {
+			resp, err = post(ctx, path, body)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil || !isRetryable(policy, err) {
+			return nil, err
+		}
+		if attempt >= maxAttempts {
+			if maxAttempts == 1 {
+				return nil, err
+			}
+			return nil, &RetryExhaustedError{Attempts: attempt, Err: err}
+		}
+
+		info := RetryInfo{
+			Attempt:     attempt + 1,
+			MaxAttempts: maxAttempts,
+			Delay:       retryDelay(policy, attempt+1, err),
+			Err:         err,
+		}
+		slog.Warn("Retrying API request",
+			slog.String("path", path),
+			slog.Int("attempt", info.Attempt),
+			slog.Int("maxAttempts", info.MaxAttempts),
+			slog.Duration("delay", info.Delay),
+			slog.Any("error", err))
+		notifyRetry(ctx, info)
+
+		timer := time.NewTimer(info.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// withRateLimit makes a single attempt wait for the provider's rate limit
+// and hold its slot until the response body is closed.
+func (c *Client) withRateLimit(attemptPost func(ctx context.Context, path string, body []byte) (*http.Response, error)) func(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	if c.limiter == nil {
+		return attemptPost
+	}
+	return func(ctx context.Context, path string, body []byte) (*http.Response, error) {
+		// The request body is JSON, so bytes/4 overestimates the prompt
+		// a little, which errs on the safe side.
+		release, err := c.limiter.acquire(ctx, len(body)/4)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := attemptPost(ctx, path, body)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, release: release}
+		return resp, nil
+	}
+}
+
+// post sends a single POST attempt.
+func (c *Client) post(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	resp, err := c.send(ctx, c.httpClient, path, body)
+	if err != nil {
+		return nil, timeoutCause(ctx, err, c.providerCfg.ConnectTimeout)
+	}
+	return resp, nil
+}
+
+// send POSTs body with client and converts a non-200 status into an error.
+func (c *Client) send(ctx context.Context, client *http.Client, path string, body []byte) (*http.Response, error) {
+	httpReq, err := c.newRequest(ctx, "POST", path, bytes.NewReader(body))
 	if err != nil {
 		return nil, err
 	}
 
-	slog.Debug("Sending streaming API request", "url", httpReq.URL.String())
-	resp, err := c.httpClient.Do(httpReq)
+	slog.Debug("Sending API request", "url", httpReq.URL.String())
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		slog.Error("Failed to send streaming request",
+		slog.Error("Failed to send request",
 			slog.Any("error", err))
 		return nil, fmt.Errorf("send request: %w", err)
 	}
@@ -133,95 +284,55 @@ func (c *Client) StreamChatCompletionWithTools(ctx context.Context, messages []M
 		slog.String("contentType", resp.Header.Get("Content-Type")),
 		slog.String("requestID", firstNonEmpty(
 			resp.Header.Get("x-request-id"),
+			resp.Header.Get("request-id"),
 			resp.Header.Get("x-amzn-requestid"),
 			resp.Header.Get("cf-ray"),
 		)),
 		slog.String("retryAfter", resp.Header.Get("Retry-After")))
 
 	if resp.StatusCode != http.StatusOK {
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				slog.Warn("Failed to close response body",
-					slog.Any("error", err))
-			}
-		}()
-		slog.Error("Streaming API returned error", "status", resp.StatusCode)
+		defer closeBody(resp)
+		slog.Error("API returned error", "status", resp.StatusCode)
 		return nil, c.handleError(resp)
 	}
+	return resp, nil
+}
 
-	eventChan := make(chan StreamEvent)
-
-	go func() {
-		defer close(eventChan)
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				slog.Warn("Failed to close response body",
-					slog.Any("error", err))
-			}
-		}()
-
-		reader := bufio.NewReader(resp.Body)
-
-		for {
-			line, err := reader.ReadString('\n')
-			if err != nil {
-				if err != io.EOF {
-					eventChan <- StreamEvent{Error: err}
-				}
-				return
-			}
-
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			if strings.HasPrefix(line, "data: ") {
-				slog.Debug("API stream line", slog.String("line", truncateForLog(line, 2000)))
-				data := strings.TrimPrefix(line, "data: ")
-				if data == "[DONE]" {
-					slog.Debug("Stream completed")
-					return
-				}
-
-				var chunk StreamResponse
-				if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-					slog.Warn("Failed to parse streaming chunk", "error", err, "data", data)
-					eventChan <- StreamEvent{Error: fmt.Errorf("parse chunk: %w", err)}
-					continue
-				}
-
-				// Log the raw chunk for debugging
-				if len(chunk.Choices) > 0 {
-					slog.Debug("Raw stream chunk",
-						slog.String("content", chunk.Choices[0].Delta.Content),
-						slog.Int("toolCalls", len(chunk.Choices[0].Delta.ToolCalls)),
-						slog.String("finishReason", chunk.Choices[0].FinishReason))
-				}
-
-				if len(chunk.Choices) > 0 {
-					if chunk.Choices[0].Delta.Content != "" {
-						slog.Debug("Received streaming content", "length", len(chunk.Choices[0].Delta.Content))
-					}
-					if len(chunk.Choices[0].Delta.ToolCalls) > 0 {
-						slog.Debug("Received streaming tool calls", "count", len(chunk.Choices[0].Delta.ToolCalls))
-					}
-				}
-				eventChan <- StreamEvent{Data: &chunk}
-			}
+// StreamChatCompletionWithTools sends a streaming request with tool support
+func (c *Client) StreamChatCompletionWithTools(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
+	slog.Info("Sending streaming request",
+		slog.String("type", c.providerCfg.Type),
+		slog.String("model", c.modelCfg.Model),
+		slog.Int("messages", len(messages)),
+		slog.Int("tools", len(tools)))
+	return withFallback(ctx, c, func(cl *Client) (<-chan StreamEvent, error) {
+		stream, err := cl.provider.streamChat(ctx, cl.visibleMessages(messages), tools)
+		if err != nil {
+			return nil, err
 		}
-	}()
-
-	return eventChan, nil
+		return cl.observeUsage(stream), nil
+	})
 }
 
 // Summarize sends a non-streaming chat completion to summarize the given
 // messages. It appends a summarization instruction to the message list and
 // returns the model's text response.
 func (c *Client) Summarize(ctx context.Context, messages []Message) (string, error) {
-	summarizeInstruction := Message{
-		Role: "user",
-		Content: `Create a concise but comprehensive summary of the conversation above.
+	return withFallback(ctx, c, func(cl *Client) (string, error) {
+		summary, usage, err := cl.provider.summarize(ctx, cl.visibleMessages(messages))
+		if err != nil {
+			return "", err
+		}
+		cl.reportUsage(usage)
+		return summary, nil
+	})
+}
+
+// summarizeInstruction is appended to the conversation by Summarize to ask the
+// model for a compaction summary. It is shared by all provider dialects.
+var summarizeInstruction = Message{
+	Role: "user",
+	Content: `Create a concise but comprehensive summary of the conversation above.
 You MUST preserve the following (in order of priority):
 1. Files modified or created, with exact paths and the nature of changes
 2. Unresolved bugs, errors, or failing tests — include exact error messages
@@ -237,78 +348,81 @@ You may aggressively compress or omit:
 
 Write the summary as structured notes (not prose) to maximize information density.
 Use file paths, function names, and concrete details rather than vague descriptions.`,
-	}
+}
 
-	req := &ChatCompletionRequest{
-		Model:            c.modelCfg.Model,
-		Messages:         append(messages, summarizeInstruction),
-		Temperature:      c.modelCfg.Temperature,
-		TopP:             c.modelCfg.TopP,
-		MinP:             c.modelCfg.MinP,
-		TopK:             c.modelCfg.TopK,
-		FrequencyPenalty: c.modelCfg.FrequencyPenalty,
-		PresencePenalty:  c.modelCfg.PresencePenalty,
-		Stop:             c.modelCfg.Stop,
-		Seed:             c.modelCfg.Seed,
-		ReasoningEffort:  c.modelCfg.ReasoningEffort,
-	}
-	if c.modelCfg.ResponseFormat != "" {
-		req.ResponseFormat = &ResponseFormat{Type: c.modelCfg.ResponseFormat}
-	}
+// handleError converts a non-200 response into a *StatusError.
+func (c *Client) handleError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	slog.Debug("API error response body", slog.String("body", truncateForLog(string(body), 4000)))
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("marshal summarize request: %w", err)
+	statusErr := &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 	}
-
-	httpReq, err := c.newRequest(ctx, "POST", "/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return "", err
+	var errResp ErrorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		statusErr.Message = fmt.Sprintf("API error %d: %s", resp.StatusCode, string(body))
+	} else {
+		statusErr.Message = fmt.Sprintf("API error: %s (type: %s, code: %v)",
+			errResp.Error.Message, errResp.Error.Type, errResp.Error.Code)
 	}
+	return statusErr
+}
 
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return "", fmt.Errorf("summarize request: %w", err)
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			slog.Warn("Failed to close summarize response body", slog.Any("error", err))
+// readSSEData reads a server-sent event stream and calls fn with the payload
+// of every "data:" line. Other fields ("event:", comments) are ignored since
+// all supported providers repeat the event type inside the JSON payload.
+// Reading stops when fn returns false or the stream ends; io.EOF is not
+// reported as an error.
+func readSSEData(r io.Reader, fn func(data string) bool) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return err
 		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", c.handleError(resp)
-	}
 
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read summarize response: %w", err)
-	}
-
-	var completion ChatCompletionResponse
-	if err := json.Unmarshal(respBody, &completion); err != nil {
-		return "", fmt.Errorf("parse summarize response: %w", err)
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "data:") {
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			slog.Debug("API stream line", slog.String("line", truncateForLog(data, 2000)))
+			if !fn(data) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
 	}
+}
 
-	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
-		return "", fmt.Errorf("empty summarize response")
-	}
+// streamSSE runs readSSEData over resp in a goroutine and returns the event
+// channel. handle converts each payload into zero or more events by calling
+// emit; it returns false to stop reading early.
+func streamSSE(resp *http.Response, handle func(data string, emit func(StreamEvent)) bool) <-chan StreamEvent {
+	eventChan := make(chan StreamEvent)
+	go func() {
+		defer close(eventChan)
+		defer closeBody(resp)
 
-	return completion.Choices[0].Message.Content, nil
+		emit := func(ev StreamEvent) { eventChan <- ev }
+		if err := readSSEData(resp.Body, func(data string) bool {
+			return handle(data, emit)
+		}); err != nil {
+			eventChan <- StreamEvent{Error: err}
+		}
+	}()
+	return eventChan
 }
 
-// handleError processes API error responses
-func (c *Client) handleError(resp *http.Response) error {
-	body, _ := io.ReadAll(resp.Body)
-	slog.Debug("API error response body", slog.String("body", truncateForLog(string(body), 4000)))
-
-	var errResp ErrorResponse
-	if err := json.Unmarshal(body, &errResp); err != nil {
-		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+func closeBody(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		slog.Warn("Failed to close response body",
+			slog.Any("error", err))
 	}
-
-	return fmt.Errorf("API error: %s (type: %s, code: %s)",
-		errResp.Error.Message, errResp.Error.Type, errResp.Error.Code)
 }
 
 func truncateForLog(s string, max int) string {
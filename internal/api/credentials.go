@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiKeyCommandTimeout bounds one run of api_key_command, which may prompt
+// for a passphrase through an agent (pass, gpg, op).
+const apiKeyCommandTimeout = 30 * time.Second
+
+// commandKey is the cached output of one api_key_command. Entries are shared
+// by every client with the same command, so the main client, fallbacks and
+// subagents do not each run it.
+type commandKey struct {
+	mu      sync.Mutex
+	key     string
+	fetched time.Time
+}
+
+var (
+	commandKeysMu sync.Mutex
+	commandKeys   = make(map[string]*commandKey)
+	// runKeyCommand is replaced in tests.
+	runKeyCommand = runShellForKey
+	keyNow        = time.Now
+)
+
+func commandKeyFor(command string) *commandKey {
+	commandKeysMu.Lock()
+	defer commandKeysMu.Unlock()
+	ck, ok := commandKeys[command]
+	if !ok {
+		ck = &commandKey{}
+		commandKeys[command] = ck
+	}
+	return ck
+}
+
+// apiKey returns the key to authenticate with: api_key (or the variable named
+// by api_key_env), else the cached output of api_key_command. Failures are
+// SetupErrors, so a missing key is neither retried nor handed to a fallback.
+func (c *Client) apiKey(ctx context.Context) (string, error) {
+	cfg := c.providerCfg
+	switch {
+	case cfg.APIKey != "":
+		return cfg.APIKey, nil
+	case cfg.APIKeyCommand != "":
+		key, err := commandKeyFor(cfg.APIKeyCommand).get(ctx, cfg.APIKeyCommand, cfg.APIKeyCommandTTL)
+		if err != nil {
+			return "", &SetupError{Err: err}
+		}
+		return key, nil
+	case cfg.APIKeyEnv != "":
+		return "", &SetupError{Err: fmt.Errorf("environment variable %s (api_key_env) is not set", cfg.APIKeyEnv)}
+	default:
+		return "", nil
+	}
+}
+
+// refreshAPIKey forgets the cached api_key_command output after the provider
+// rejected it, and reports whether a retry could use a different key.
+func (c *Client) refreshAPIKey(err error) bool {
+	var se *StatusError
+	if c.providerCfg.APIKey != "" || c.providerCfg.APIKeyCommand == "" ||
+		!errors.As(err, &se) || se.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	slog.Info("API key rejected, running api_key_command again")
+	commandKeyFor(c.providerCfg.APIKeyCommand).invalidate()
+	return true
+}
+
+func (ck *commandKey) get(ctx context.Context, command string, ttl time.Duration) (string, error) {
+	ck.mu.Lock()
+	defer ck.mu.Unlock()
+	if ck.key != "" && (ttl <= 0 || keyNow().Sub(ck.fetched) < ttl) {
+		return ck.key, nil
+	}
+	key, err := runKeyCommand(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	ck.key, ck.fetched = key, keyNow()
+	return key, nil
+}
+
+func (ck *commandKey) invalidate() {
+	ck.mu.Lock()
+	defer ck.mu.Unlock()
+	ck.key = ""
+}
+
+// runShellForKey runs command with sh and returns the first line of its
+// output. The output is never logged or included in errors.
+func runShellForKey(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, apiKeyCommandTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("api_key_command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("api_key_command failed: %w", err)
+	}
+	key, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("api_key_command printed no key")
+	}
+	return key, nil
+}
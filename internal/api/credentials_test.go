@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestAPIKeyCommandIsCachedAndRefreshedOn401(t *testing.T) {
+	var runs atomic.Int32
+	runKeyCommand = func(ctx context.Context, command string) (string, error) {
+		return "key-" + strconv.Itoa(int(runs.Add(1))), nil
+	}
+	now := time.Now()
+	keyNow = func() time.Time { return now }
+	t.Cleanup(func() {
+		runKeyCommand = runShellForKey
+		keyNow = time.Now
+	})
+
+	var valid atomic.Value
+	valid.Store("key-1")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error":{"message":"bad key","type":"auth"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		&config.ProviderConfig{
+			BaseURL:          server.URL,
+			APIKeyCommand:    "test-key-command " + t.Name(),
+			APIKeyCommandTTL: time.Hour,
+			Timeout:          5 * time.Second,
+			Retry:            config.RetryConfig{MaxAttempts: 1},
+		},
+		&config.ModelConfig{Model: "test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+	summarize := func() {
+		t.Helper()
+		if _, err := client.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err != nil {
+			t.Fatalf("Summarize: %v", err)
+		}
+	}
+
+	summarize()
+	summarize()
+	if runs.Load() != 1 {
+		t.Fatalf("command should run once while cached, ran %d times", runs.Load())
+	}
+
+	// The key is rotated: the 401 makes the client run the command again.
+	valid.Store("key-2")
+	summarize()
+	if runs.Load() != 2 {
+		t.Fatalf("command should run again after a 401, ran %d times", runs.Load())
+	}
+
+	// The cached key expires after the TTL.
+	now = now.Add(2 * time.Hour)
+	valid.Store("key-3")
+	summarize()
+	if runs.Load() != 3 {
+		t.Fatalf("command should run again after the TTL, ran %d times", runs.Load())
+	}
+}
+
+func TestRunShellForKey(t *testing.T) {
+	key, err := runShellForKey(context.Background(), "printf 'sk-secret\\nuser: me\\n'")
+	if err != nil || key != "sk-secret" {
+		t.Fatalf("runShellForKey = %q, %v", key, err)
+	}
+	if _, err := runShellForKey(context.Background(), "true"); err == nil {
+		t.Fatal("expected an error for a command printing nothing")
+	}
+}
+
+func TestAPIKeyEnvUnset(t *testing.T) {
+	client := newRetryTestClient("http://127.0.0.1:0", fastRetry)
+	client.providerCfg.APIKeyEnv = "ASHRON_UNSET_KEY"
+	var retries int
+	ctx := WithRetryNotifier(context.Background(), func(RetryInfo) { retries++ })
+	_, err := client.Summarize(ctx, []Message{NewUserMessage("hi")})
+	var setupErr *SetupError
+	if !errors.As(err, &setupErr) {
+		t.Fatalf("expected a SetupError when api_key_env is not set, got %v", err)
+	}
+	if retries != 0 {
+		t.Fatalf("a missing key should not be retried, got %d retries", retries)
+	}
+}
+
+func TestFailingAPIKeyCommandRunsOnce(t *testing.T) {
+	var runs atomic.Int32
+	runKeyCommand = func(ctx context.Context, command string) (string, error) {
+		runs.Add(1)
+		return "", errors.New("api_key_command failed: exit status 1")
+	}
+	t.Cleanup(func() { runKeyCommand = runShellForKey })
+
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("backup should not be called when the key cannot be fetched")
+	}))
+	defer backup.Close()
+
+	cfg := newFallbackTestConfig("http://127.0.0.1:1", backup.URL)
+	primary := cfg.Providers["primary"]
+	primary.APIKeyCommand = "test-key-command " + t.Name()
+	primary.Retry = fastRetry
+	cfg.Providers["primary"] = primary
+	client, err := NewClientForModel(cfg, "primary", "main", &config.ContextConfig{})
+	if err != nil {
+		t.Fatalf("NewClientForModel: %v", err)
+	}
+	if _, err := client.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err == nil {
+		t.Fatal("expected error")
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("api_key_command should run once, ran %d times", runs.Load())
+	}
+}
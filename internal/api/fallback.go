@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// FallbackInfo describes a switch to the next model in a fallback chain.
+type FallbackInfo struct {
+	From string // "provider/model" that failed
+	To   string // "provider/model" tried next
+	Err  error  // the error that caused the switch
+}
+
+func (f FallbackInfo) String() string {
+	return fmt.Sprintf("%s failed, falling back to %s", f.From, f.To)
+}
+
+type fallbackNotifierKey struct{}
+
+// WithFallbackNotifier returns a context that makes the client call fn before
+// switching to a fallback model. fn is called from the goroutine issuing the
+// request.
+func WithFallbackNotifier(ctx context.Context, fn func(FallbackInfo)) context.Context {
+	return context.WithValue(ctx, fallbackNotifierKey{}, fn)
+}
+
+func notifyFallback(ctx context.Context, info FallbackInfo) {
+	if fn, ok := ctx.Value(fallbackNotifierKey{}).(func(FallbackInfo)); ok && fn != nil {
+		fn(info)
+	}
+}
+
+// NewClientForModel creates a client for provName/modelName together with the
+// fallback chain configured for it (see config.Config.FallbackChain).
+// Fallback models use their own context override when they have one, and
+// contextConfig otherwise.
+func NewClientForModel(cfg *config.Config, provName, modelName string, contextConfig *config.ContextConfig) (*Client, error) {
+	primary := config.ModelRef{Provider: provName, Model: modelName}
+	provCfg, modelCfg, err := cfg.LookupModel(primary)
+	if err != nil {
+		return nil, err
+	}
+	c := NewClient(provCfg, modelCfg, contextConfig)
+	c.name = primary.String()
+
+	for _, ref := range cfg.FallbackChain(provName, modelName) {
+		fbProv, fbModel, err := cfg.LookupModel(ref)
+		if err != nil {
+			return nil, fmt.Errorf("fallback %s: %w", ref, err)
+		}
+		fbContext := contextConfig
+		if fbModel.Context != nil {
+			fbContext = fbModel.Context
+		}
+		fb := NewClient(fbProv, fbModel, fbContext)
+		fb.name = ref.String()
+		c.fallbacks = append(c.fallbacks, fb)
+	}
+	if len(c.fallbacks) > 0 {
+		slog.Info("Configured fallback chain", slog.String("primary", c.name), slog.Int("fallbacks", len(c.fallbacks)))
+	}
+	return c, nil
+}
+
+// Name returns the "provider/model" label of the client, or the model name
+// when the client was created without NewClientForModel.
+func (c *Client) Name() string {
+	if c.name != "" {
+		return c.name
+	}
+	return c.modelCfg.Model
+}
+
+// withFallback runs call against c and, while it fails with an error that c's
+// retry policy considers retryable, against each fallback client in turn.
+func withFallback[T any](ctx context.Context, c *Client, call func(*Client) (T, error)) (T, error) {
+	res, err := call(c)
+	current := c
+	for _, fb := range c.fallbacks {
+		if err == nil || ctx.Err() != nil || !isRetryable(current.providerCfg.Retry, err) {
+			break
+		}
+		info := FallbackInfo{From: current.Name(), To: fb.Name(), Err: err}
+		slog.Warn("Falling back to next model",
+			slog.String("from", info.From),
+			slog.String("to", info.To),
+			slog.Any("error", err))
+		notifyFallback(ctx, info)
+		current = fb
+		res, err = call(fb)
+	}
+	return res, err
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newFallbackTestConfig(primaryURL, backupURL string) *config.Config {
+	noRetry := config.RetryConfig{MaxAttempts: 1, RetryOnStatus: []int{503}}
+	return &config.Config{
+		Default: config.DefaultConfig{
+			Provider: "primary",
+			Model:    "main",
+			Fallback: []config.ModelRef{{Provider: "backup", Model: "spare"}},
+		},
+		Providers: map[string]config.ProviderConfig{
+			"primary": {BaseURL: primaryURL, Retry: noRetry, Models: map[string]config.ModelConfig{"main": {Model: "main-model"}}},
+			"backup":  {BaseURL: backupURL, Retry: noRetry, Models: map[string]config.ModelConfig{"spare": {Model: "spare-model"}}},
+		},
+	}
+}
+
+func TestClientFallsBackOnRetryableError(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"from backup"}}]}`)
+	}))
+	defer backup.Close()
+
+	cfg := newFallbackTestConfig(primary.URL, backup.URL)
+	client, err := NewClientForModel(cfg, "primary", "main", &config.ContextConfig{})
+	if err != nil {
+		t.Fatalf("NewClientForModel: %v", err)
+	}
+	if client.Name() != "primary/main" {
+		t.Fatalf("unexpected client name: %q", client.Name())
+	}
+
+	var infos []FallbackInfo
+	ctx := WithFallbackNotifier(context.Background(), func(info FallbackInfo) {
+		infos = append(infos, info)
+	})
+	summary, err := client.Summarize(ctx, []Message{NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "from backup" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+	if len(infos) != 1 || infos[0].From != "primary/main" || infos[0].To != "backup/spare" {
+		t.Fatalf("unexpected fallback notifications: %+v", infos)
+	}
+}
+
+func TestClientDoesNotFallBackOnClientError(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("backup should not be called for a 400")
+	}))
+	defer backup.Close()
+
+	cfg := newFallbackTestConfig(primary.URL, backup.URL)
+	client, err := NewClientForModel(cfg, "primary", "main", &config.ContextConfig{})
+	if err != nil {
+		t.Fatalf("NewClientForModel: %v", err)
+	}
+	if _, err := client.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestClientDoesNotFallBackOnSetupError(t *testing.T) {
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("backup should not be called when the primary is misconfigured")
+	}))
+	defer backup.Close()
+
+	cfg := newFallbackTestConfig("http://127.0.0.1:1", backup.URL)
+	primary := cfg.Providers["primary"]
+	primary.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+	cfg.Providers["primary"] = primary
+	client, err := NewClientForModel(cfg, "primary", "main", &config.ContextConfig{})
+	if err != nil {
+		t.Fatalf("NewClientForModel: %v", err)
+	}
+	if _, err := client.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err == nil {
+		t.Fatal("expected error")
+	}
+}
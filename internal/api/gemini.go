@@ -0,0 +1,423 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// geminiDefaultBaseURL is used when a gemini provider has no base_url.
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+// geminiRequest is the request body for generateContent and
+// streamGenerateContent.
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiPart covers the text, inlineData, functionCall and functionResponse
+// part types.
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"` // Text is a thought summary
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiInlineData struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// geminiParts converts the text and image parts of a message. Gemini only
+// accepts inline image data, so images given by URL are described in text.
+func geminiParts(msg Message) []geminiPart {
+	if len(msg.Parts) == 0 {
+		if msg.Content == "" {
+			return nil
+		}
+		return []geminiPart{{Text: msg.Content}}
+	}
+	var parts []geminiPart
+	for _, p := range msg.Parts {
+		switch {
+		case p.Type == ContentPartText && p.Text != "":
+			parts = append(parts, geminiPart{Text: p.Text})
+		case p.Type == ContentPartImage && p.ImageURL != nil:
+			if mime, data, ok := p.ImageURL.DataURL(); ok {
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{MIMEType: mime, Data: data}})
+			} else {
+				parts = append(parts, geminiPart{Text: "[image: " + p.ImageURL.URL + "]"})
+			}
+		}
+	}
+	return parts
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// geminiFunctionDeclaration uses parametersJsonSchema rather than the
+// OpenAPI-subset "parameters" field, which rejects free-form object
+// properties such as mcp_call's "arguments".
+type geminiFunctionDeclaration struct {
+	Name                 string         `json:"name"`
+	Description          string         `json:"description,omitempty"`
+	ParametersJSONSchema map[string]any `json:"parametersJsonSchema,omitempty"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float32  `json:"temperature,omitempty"`
+	TopP             float32  `json:"topP,omitempty"`
+	TopK             int      `json:"topK,omitempty"`
+	FrequencyPenalty float32  `json:"frequencyPenalty,omitempty"`
+	PresencePenalty  float32  `json:"presencePenalty,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	Seed             *int     `json:"seed,omitempty"`
+	MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+	ResponseMIMEType string   `json:"responseMimeType,omitempty"`
+	// ResponseJSONSchema constrains JSON output (responseMimeType must be
+	// application/json).
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+// geminiResponse is a generateContent response, and also the payload of each
+// streamGenerateContent SSE event.
+type geminiResponse struct {
+	Candidates    []geminiCandidate    `json:"candidates"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion"`
+	Error         *APIError            `json:"error,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+}
+
+// geminiProvider speaks the native Google Gemini generateContent API.
+type geminiProvider struct {
+	c *Client
+}
+
+func (p *geminiProvider) defaultBaseURL() string {
+	return geminiDefaultBaseURL
+}
+
+func (p *geminiProvider) setAuth(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("x-goog-api-key", apiKey)
+	}
+}
+
+// modelPath returns the request path for a model method such as
+// "generateContent".
+func (p *geminiProvider) modelPath(method string) string {
+	model := strings.TrimPrefix(p.c.modelCfg.Model, "models/")
+	return "/models/" + url.PathEscape(model) + ":" + method
+}
+
+// buildRequest maps the OpenAI-shaped conversation onto a generateContent request.
+func (p *geminiProvider) buildRequest(messages []Message, tools []Tool, format *config.ResponseFormat) *geminiRequest {
+	c := p.c
+	system, contents := toGeminiContents(messages)
+	req := &geminiRequest{
+		Contents: contents,
+		GenerationConfig: &geminiGenerationConfig{
+			Temperature:      c.modelCfg.Temperature,
+			TopP:             c.modelCfg.TopP,
+			TopK:             c.modelCfg.TopK,
+			FrequencyPenalty: c.modelCfg.FrequencyPenalty,
+			PresencePenalty:  c.modelCfg.PresencePenalty,
+			StopSequences:    c.modelCfg.Stop,
+			Seed:             c.modelCfg.Seed,
+			MaxOutputTokens:  c.modelCfg.MaxOutputTokens,
+		},
+	}
+	if format != nil {
+		switch format.Type {
+		case config.ResponseFormatJSONObject:
+			req.GenerationConfig.ResponseMIMEType = "application/json"
+		case config.ResponseFormatJSONSchema:
+			// Constrained decoding would also apply to the turns that call
+			// tools, so with tools the schema is only requested in the prompt.
+			if len(tools) == 0 {
+				req.GenerationConfig.ResponseMIMEType = "application/json"
+				req.GenerationConfig.ResponseJSONSchema = format.Schema
+			} else {
+				system = strings.TrimSpace(system + "\n\n" + schemaInstruction(format))
+			}
+		}
+	}
+	if system != "" {
+		req.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:                 t.Function.Name,
+				Description:          t.Function.Description,
+				ParametersJSONSchema: geminiSchema(t.Function.Parameters),
+			})
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+	return req
+}
+
+// geminiSchema converts tool parameters to a JSON Schema object. Empty
+// "required" lists are dropped since Gemini rejects a null value there.
+func geminiSchema(params FunctionParameters) map[string]any {
+	if params.Type == "" && len(params.Properties) == 0 {
+		return nil
+	}
+	typ := params.Type
+	if typ == "" {
+		typ = "object"
+	}
+	properties := make(map[string]any, len(params.Properties))
+	for name, prop := range params.Properties {
+		schema := map[string]any{"type": prop.Type}
+		if prop.Description != "" {
+			schema["description"] = prop.Description
+		}
+		properties[name] = schema
+	}
+	schema := map[string]any{"type": typ, "properties": properties}
+	if len(params.Required) > 0 {
+		schema["required"] = params.Required
+	}
+	return schema
+}
+
+// toGeminiContents converts chat messages to the generateContent layout.
+//
+// Leading system messages become the system instruction and later ones are
+// sent as user text, like the Anthropic dialect. Gemini matches function
+// responses to calls by name rather than ID, so tool messages look up the
+// function name from the assistant message that issued the call. Consecutive
+// turns with the same role are merged.
+func toGeminiContents(messages []Message) (string, []geminiContent) {
+	var systemParts []string
+	i := 0
+	for i < len(messages) && messages[i].Role == "system" {
+		if messages[i].Content != "" {
+			systemParts = append(systemParts, messages[i].Content)
+		}
+		i++
+	}
+
+	var out []geminiContent
+	appendParts := func(role string, parts ...geminiPart) {
+		if len(parts) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Parts = append(out[n-1].Parts, parts...)
+			return
+		}
+		out = append(out, geminiContent{Role: role, Parts: parts})
+	}
+
+	callNames := make(map[string]string) // tool call ID -> function name
+	for _, msg := range moveToolImages(messages[i:]) {
+		switch msg.Role {
+		case "system", "user":
+			appendParts("user", geminiParts(msg)...)
+		case "assistant":
+			var parts []geminiPart
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: tc.Function.Name,
+					Args: args,
+				}})
+			}
+			appendParts("model", parts...)
+		case "tool":
+			appendParts("user", geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     callNames[msg.ToolCallID],
+				Response: map[string]any{"content": msg.Content},
+			}})
+		}
+	}
+
+	return strings.Join(systemParts, "\n\n"), out
+}
+
+// geminiFinishReason maps a Gemini finishReason onto an OpenAI finish_reason.
+// Gemini reports STOP even when the turn ended with function calls, so
+// sawToolCall upgrades it to "tool_calls".
+func geminiFinishReason(reason string, sawToolCall bool) string {
+	switch reason {
+	case "":
+		return ""
+	case "MAX_TOKENS":
+		return "length"
+	case "STOP":
+		if sawToolCall {
+			return "tool_calls"
+		}
+		return "stop"
+	default:
+		return "stop"
+	}
+}
+
+// newGeminiToolCallID generates an ID for a function call. Gemini does not
+// assign call IDs, but the rest of ashron pairs tool results with calls by ID.
+func newGeminiToolCallID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "call_" + hex.EncodeToString(b[:])
+}
+
+func geminiUsage(meta *geminiUsageMetadata) *Usage {
+	return &Usage{
+		PromptTokens:        meta.PromptTokenCount,
+		CompletionTokens:    meta.CandidatesTokenCount,
+		TotalTokens:         meta.TotalTokenCount,
+		PromptTokensDetails: cachedDetails(meta.CachedContentTokenCount),
+	}
+}
+
+// streamChat sends a streamGenerateContent request and translates each SSE
+// event into an OpenAI-style StreamResponse chunk. Function calls arrive
+// complete, so each one is emitted as a single tool call delta.
+func (p *geminiProvider) streamChat(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
+	req := p.buildRequest(messages, tools, p.c.modelCfg.ResponseFormat)
+
+	resp, err := p.c.postStream(ctx, p.modelPath("streamGenerateContent")+"?alt=sse", req)
+	if err != nil {
+		return nil, err
+	}
+
+	toolCalls := 0
+	return streamSSE(resp, func(data string, emit func(StreamEvent)) bool {
+		var ev geminiResponse
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			slog.Warn("Failed to parse streaming event", "error", err, "data", data)
+			emit(StreamEvent{Error: fmt.Errorf("parse chunk: %w", err)})
+			return true
+		}
+		if ev.Error != nil {
+			emit(StreamEvent{Error: fmt.Errorf("API error: %s (code: %v)", ev.Error.Message, ev.Error.Code)})
+			return false
+		}
+
+		chunk := StreamResponse{Model: ev.ModelVersion}
+		if ev.UsageMetadata != nil {
+			chunk.Usage = geminiUsage(ev.UsageMetadata)
+		}
+		if len(ev.Candidates) > 0 {
+			cand := ev.Candidates[0]
+			var delta Message
+			for _, part := range cand.Content.Parts {
+				if part.FunctionCall != nil {
+					args := string(part.FunctionCall.Args)
+					if args == "" {
+						args = "{}"
+					}
+					delta.ToolCalls = append(delta.ToolCalls, ToolCall{
+						Index: toolCalls,
+						ID:    newGeminiToolCallID(),
+						Type:  "function",
+						Function: FunctionCall{
+							Name:      part.FunctionCall.Name,
+							Arguments: args,
+						},
+					})
+					toolCalls++
+					continue
+				}
+				if part.Thought {
+					delta.ReasoningContent += part.Text
+					continue
+				}
+				delta.Content += part.Text
+			}
+			chunk.Choices = []Choice{{
+				Delta:        delta,
+				FinishReason: geminiFinishReason(cand.FinishReason, toolCalls > 0),
+			}}
+		}
+
+		emit(StreamEvent{Data: &chunk})
+		return true
+	}), nil
+}
+
+func (p *geminiProvider) summarize(ctx context.Context, messages []Message) (string, *Usage, error) {
+	req := p.buildRequest(append(messages, summarizeInstruction), nil, summaryFormat(p.c.modelCfg.ResponseFormat))
+
+	resp, err := p.c.postJSON(ctx, p.modelPath("generateContent"), req)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarize request: %w", err)
+	}
+	defer closeBody(resp)
+
+	var completion geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", nil, fmt.Errorf("parse summarize response: %w", err)
+	}
+
+	var sb strings.Builder
+	if len(completion.Candidates) > 0 {
+		for _, part := range completion.Candidates[0].Content.Parts {
+			if !part.Thought {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "", nil, fmt.Errorf("empty summarize response")
+	}
+	var usage *Usage
+	if completion.UsageMetadata != nil {
+		usage = geminiUsage(completion.UsageMetadata)
+	}
+	return sb.String(), usage, nil
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newGeminiTestClient(baseURL string) *Client {
+	return NewClient(
+		&config.ProviderConfig{
+			Type:    config.ProviderTypeGemini,
+			BaseURL: baseURL,
+			APIKey:  "test-key",
+			Timeout: 5 * time.Second,
+		},
+		&config.ModelConfig{Model: "gemini-test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+}
+
+func TestToGeminiContents(t *testing.T) {
+	system, contents := toGeminiContents([]Message{
+		NewSystemMessage("base prompt"),
+		NewUserMessage("read it"),
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"a"}`}},
+		}},
+		NewToolMessage("call_1", "content-a"),
+		NewUserMessage("thanks"),
+	})
+
+	if system != "base prompt" {
+		t.Fatalf("unexpected system instruction: %q", system)
+	}
+	if len(contents) != 3 {
+		t.Fatalf("expected 3 contents, got %d: %+v", len(contents), contents)
+	}
+	if contents[0].Role != "user" || contents[1].Role != "model" || contents[2].Role != "user" {
+		t.Fatalf("unexpected roles: %s %s %s", contents[0].Role, contents[1].Role, contents[2].Role)
+	}
+
+	call := contents[1].Parts[0].FunctionCall
+	if call == nil || call.Name != "read_file" || string(call.Args) != `{"path":"a"}` {
+		t.Fatalf("unexpected function call: %+v", call)
+	}
+
+	last := contents[2].Parts
+	if len(last) != 2 || last[0].FunctionResponse == nil || last[1].Text != "thanks" {
+		t.Fatalf("unexpected last turn: %+v", last)
+	}
+	if last[0].FunctionResponse.Name != "read_file" || last[0].FunctionResponse.Response["content"] != "content-a" {
+		t.Fatalf("unexpected function response: %+v", last[0].FunctionResponse)
+	}
+}
+
+func TestGeminiStreamTranslatesEvents(t *testing.T) {
+	var gotReq geminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			http.Error(w, "missing api key", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Need the file.","thought":true},{"text":"Let me "}]}}],"modelVersion":"gemini-test"}`,
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"look."},{"functionCall":{"name":"read_file","args":{"path":"a.txt"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":21,"candidatesTokenCount":9,"totalTokenCount":30},"modelVersion":"gemini-test"}`,
+		}
+		for _, ev := range events {
+			_, _ = fmt.Fprintf(w, "data: %s\r\n\r\n", ev)
+		}
+	}))
+	defer server.Close()
+
+	client := newGeminiTestClient(server.URL)
+	tools := []Tool{{Type: "function", Function: FunctionDef{
+		Name:       "read_file",
+		Parameters: FunctionParameters{Type: "object", Properties: map[string]FunctionProperty{"path": {Type: "string"}}},
+	}}}
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{
+		NewSystemMessage("sys"),
+		NewUserMessage("hi"),
+	}, tools)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+
+	var content, reasoning strings.Builder
+	var calls []ToolCall
+	var finish string
+	var usage *Usage
+	for ev := range stream {
+		if ev.Error != nil {
+			t.Fatalf("stream error: %v", ev.Error)
+		}
+		if ev.Data.Usage != nil {
+			usage = ev.Data.Usage
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+			reasoning.WriteString(ch.Delta.ReasoningContent)
+			calls = append(calls, ch.Delta.ToolCalls...)
+			if ch.FinishReason != "" {
+				finish = ch.FinishReason
+			}
+		}
+	}
+
+	if gotReq.SystemInstruction == nil || gotReq.SystemInstruction.Parts[0].Text != "sys" {
+		t.Fatalf("unexpected system instruction: %+v", gotReq.SystemInstruction)
+	}
+	if len(gotReq.Tools) != 1 || len(gotReq.Tools[0].FunctionDeclarations) != 1 {
+		t.Fatalf("unexpected tools in request: %+v", gotReq.Tools)
+	}
+	if _, ok := gotReq.Tools[0].FunctionDeclarations[0].ParametersJSONSchema["required"]; ok {
+		t.Fatalf("empty required list should be omitted: %+v", gotReq.Tools[0].FunctionDeclarations[0])
+	}
+	if content.String() != "Let me look." || reasoning.String() != "Need the file." {
+		t.Fatalf("unexpected content: %q (reasoning %q)", content.String(), reasoning.String())
+	}
+	if len(calls) != 1 || calls[0].ID == "" || calls[0].Function.Name != "read_file" || calls[0].Function.Arguments != `{"path":"a.txt"}` {
+		t.Fatalf("unexpected tool calls: %+v", calls)
+	}
+	if finish != "tool_calls" {
+		t.Fatalf("finish reason = %q, want tool_calls", finish)
+	}
+	if usage == nil || usage.PromptTokens != 21 || usage.CompletionTokens != 9 || usage.TotalTokens != 30 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestGeminiSummarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"- summary"}]},"finishReason":"STOP"}]}`)
+	}))
+	defer server.Close()
+
+	client := newGeminiTestClient(server.URL)
+	summary, err := client.Summarize(context.Background(), []Message{NewUserMessage("hello")})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "- summary" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+}
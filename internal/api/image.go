@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+)
+
+// NewImagePart returns an image part embedding data as a data URL.
+func NewImagePart(mimeType string, data []byte) ContentPart {
+	return ContentPart{
+		Type:     ContentPartImage,
+		ImageURL: &ImageURL{URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)},
+	}
+}
+
+// DataURL splits a base64 data URL into its MIME type and base64 payload.
+// ok is false for other URLs.
+func (u *ImageURL) DataURL() (mimeType, data string, ok bool) {
+	rest, found := strings.CutPrefix(u.URL, "data:")
+	if !found {
+		return "", "", false
+	}
+	mimeType, data, found = strings.Cut(rest, ";base64,")
+	return mimeType, data, found
+}
+
+// moveToolImages returns messages with the images of tool messages moved
+// into a user message after each run of tool results, for APIs whose tool
+// results may only contain text (chat completions, Responses).
+func moveToolImages(messages []Message) []Message {
+	var (
+		out    []Message
+		images []ContentPart
+	)
+	for i, msg := range messages {
+		if msg.Role == "tool" && len(msg.Parts) > 0 {
+			if out == nil {
+				out = slices.Clone(messages[:i])
+			}
+			images = append(images, msg.Images()...)
+			msg.Parts = nil
+		}
+		if out != nil {
+			out = append(out, msg)
+		}
+		if len(images) > 0 && (i+1 == len(messages) || messages[i+1].Role != "tool") {
+			note := Message{Role: "user", Content: "Images returned by the tool calls above:"}
+			note.AttachImages(images...)
+			out = append(out, note)
+			images = nil
+		}
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
+
+// SupportsVision reports whether the client's model accepts image input.
+func (c *Client) SupportsVision() bool {
+	return c.modelCfg.Vision
+}
+
+// EchoesReasoning reports whether the client's model needs its reasoning
+// content sent back on later turns, so callers know to keep it in history.
+func (c *Client) EchoesReasoning() bool {
+	return c.modelCfg.EchoReasoning
+}
+
+// visibleMessages returns messages without their images when the model
+// does not accept them, which happens when falling back from a vision model.
+func (c *Client) visibleMessages(messages []Message) []Message {
+	if c.SupportsVision() {
+		return messages
+	}
+	var out []Message
+	for i, msg := range messages {
+		if len(msg.Parts) == 0 {
+			if out != nil {
+				out = append(out, msg)
+			}
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(messages[:i])
+		}
+		msg.Parts = nil
+		out = append(out, msg)
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
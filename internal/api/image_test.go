@@ -0,0 +1,166 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestMessageJSONRoundTripsImageParts(t *testing.T) {
+	msg := NewUserMessage("what is this?")
+	msg.AttachImages(NewImagePart("image/png", []byte("png")))
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"role":"user","content":[{"type":"text","text":"what is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}]}`
+	if string(data) != want {
+		t.Fatalf("marshal:\n got %s\nwant %s", data, want)
+	}
+
+	var got Message
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Content != "what is this?" || len(got.Parts) != 2 || len(got.Images()) != 1 {
+		t.Fatalf("unmarshal: %+v", got)
+	}
+	if mime, payload, ok := got.Images()[0].ImageURL.DataURL(); !ok || mime != "image/png" || payload != "cG5n" {
+		t.Fatalf("DataURL() = %q, %q, %v", mime, payload, ok)
+	}
+}
+
+func TestMessageUnmarshalStringAndNullContent(t *testing.T) {
+	var msg Message
+	if err := json.Unmarshal([]byte(`{"role":"user","content":"hi"}`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "hi" || msg.Parts != nil {
+		t.Fatalf("string content: %+v", msg)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]}`), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "" || len(msg.ToolCalls) != 1 {
+		t.Fatalf("null content: %+v", msg)
+	}
+}
+
+func TestMoveToolImages(t *testing.T) {
+	image := NewImagePart("image/png", []byte("png"))
+	tool1 := NewToolMessage("c1", "Image file a.png")
+	tool1.AttachImages(image)
+	messages := []Message{
+		NewUserMessage("look"),
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1"}, {ID: "c2"}}},
+		tool1,
+		NewToolMessage("c2", "text"),
+		NewUserMessage("thanks"),
+	}
+
+	moved := moveToolImages(messages)
+	if len(moved) != 6 {
+		t.Fatalf("expected an extra user message, got %d messages", len(moved))
+	}
+	if moved[2].Parts != nil || moved[2].Content != "Image file a.png" {
+		t.Fatalf("tool message should keep only text: %+v", moved[2])
+	}
+	if moved[4].Role != "user" || len(moved[4].Images()) != 1 {
+		t.Fatalf("images should follow the tool messages: %+v", moved[4])
+	}
+	if len(messages[2].Parts) == 0 {
+		t.Fatal("original messages must not be modified")
+	}
+
+	data, err := json.Marshal(wireMessages(messages, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), `"image_url"`) != 2 { // part type and field
+		t.Fatalf("expected one image in the wire messages: %s", data)
+	}
+}
+
+func TestVisibleMessagesDropsImagesWithoutVision(t *testing.T) {
+	msg := NewUserMessage("look")
+	msg.AttachImages(NewImagePart("image/png", []byte("png")))
+	messages := []Message{msg}
+
+	c := &Client{modelCfg: &config.ModelConfig{}}
+	if got := c.visibleMessages(messages); got[0].Parts != nil || got[0].Content != "look" {
+		t.Fatalf("images should be dropped: %+v", got[0])
+	}
+	c.modelCfg.Vision = true
+	if got := c.visibleMessages(messages); len(got[0].Images()) != 1 {
+		t.Fatalf("images should be kept: %+v", got[0])
+	}
+}
+
+func TestToAnthropicMessagesImages(t *testing.T) {
+	user := NewUserMessage("look")
+	user.AttachImages(NewImagePart("image/png", []byte("png")), ContentPart{Type: ContentPartImage, ImageURL: &ImageURL{URL: "https://example.com/a.png"}})
+	tool := NewToolMessage("toolu_1", "Image file b.png")
+	tool.AttachImages(NewImagePart("image/jpeg", []byte("jpg")))
+
+	_, msgs := toAnthropicMessages([]Message{
+		user,
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_1", Function: FunctionCall{Name: "read_file", Arguments: "{}"}}}},
+		tool,
+	})
+
+	blocks := msgs[0].Content
+	if len(blocks) != 3 || blocks[1].Type != "image" || blocks[2].Type != "image" {
+		t.Fatalf("unexpected user blocks: %+v", blocks)
+	}
+	if s := blocks[1].Source; s.Type != "base64" || s.MediaType != "image/png" || s.Data != "cG5n" {
+		t.Fatalf("unexpected base64 source: %+v", s)
+	}
+	if s := blocks[2].Source; s.Type != "url" || s.URL != "https://example.com/a.png" {
+		t.Fatalf("unexpected url source: %+v", s)
+	}
+
+	content, ok := msgs[2].Content[0].Content.([]anthropicContentBlock)
+	if !ok || len(content) != 2 || content[0].Text != "Image file b.png" || content[1].Source.MediaType != "image/jpeg" {
+		t.Fatalf("unexpected tool_result content: %+v", msgs[2].Content[0].Content)
+	}
+}
+
+func TestToGeminiContentsImages(t *testing.T) {
+	tool := NewToolMessage("call_1", "Image file a.png")
+	tool.AttachImages(NewImagePart("image/png", []byte("png")))
+
+	_, contents := toGeminiContents([]Message{
+		NewUserMessage("look"),
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Function: FunctionCall{Name: "read_file", Arguments: "{}"}}}},
+		tool,
+	})
+
+	last := contents[len(contents)-1]
+	if last.Role != "user" || len(last.Parts) != 3 {
+		t.Fatalf("unexpected last turn: %+v", last)
+	}
+	if last.Parts[0].FunctionResponse == nil || last.Parts[2].InlineData == nil {
+		t.Fatalf("expected function response then image: %+v", last.Parts)
+	}
+	if d := last.Parts[2].InlineData; d.MIMEType != "image/png" || d.Data != "cG5n" {
+		t.Fatalf("unexpected inline data: %+v", d)
+	}
+}
+
+func TestToResponsesInputImages(t *testing.T) {
+	user := NewUserMessage("look")
+	user.AttachImages(NewImagePart("image/png", []byte("png")))
+
+	input := toResponsesInput([]Message{user})
+	msg, ok := input[0].(responsesInputMessage)
+	if !ok {
+		t.Fatalf("unexpected input item: %#v", input[0])
+	}
+	parts, ok := msg.Content.([]responsesContentPart)
+	if !ok || len(parts) != 2 || parts[0].Type != "input_text" || parts[1].Type != "input_image" || parts[1].ImageURL != "data:image/png;base64,cG5n" {
+		t.Fatalf("unexpected content: %#v", msg.Content)
+	}
+}
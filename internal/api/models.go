@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ListModels asks the provider which models it serves and returns their IDs,
+// sorted and without duplicates.
+func (c *Client) ListModels(ctx context.Context) ([]string, error) {
+	ids, err := c.provider.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// getJSON GETs url with the provider's authentication and decodes the JSON
+// response into out.
+func (c *Client) getJSON(ctx context.Context, url string, out any) error {
+	req, err := c.newRequestURL(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return timeoutCause(ctx, fmt.Errorf("send request: %w", err), c.providerCfg.ConnectTimeout)
+	}
+	defer closeBody(resp)
+	if resp.StatusCode != http.StatusOK {
+		return c.handleError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// modelList is the {"data": [{"id": ...}]} shape of OpenAI's and Anthropic's
+// GET /models.
+type modelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+func (l *modelList) ids() []string {
+	ids := make([]string, 0, len(l.Data))
+	for _, m := range l.Data {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// listModels calls GET /models, falling back to Ollama's native /api/tags for
+// servers that do not implement it.
+func (p *openAIProvider) listModels(ctx context.Context) ([]string, error) {
+	var list modelList
+	err := p.c.getJSON(ctx, p.c.baseURL+"/models", &list)
+	if err == nil {
+		return list.ids(), nil
+	}
+	root := strings.TrimSuffix(p.c.baseURL, "/v1")
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if ollamaErr := p.c.getJSON(ctx, root+"/api/tags", &tags); ollamaErr != nil {
+		slog.Debug("Ollama model listing failed", slog.Any("error", ollamaErr))
+		return nil, err
+	}
+	ids := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		ids = append(ids, m.Name)
+	}
+	return ids, nil
+}
+
+func (p *responsesProvider) listModels(ctx context.Context) ([]string, error) {
+	var list modelList
+	if err := p.c.getJSON(ctx, p.c.baseURL+"/models", &list); err != nil {
+		return nil, err
+	}
+	return list.ids(), nil
+}
+
+func (p *anthropicProvider) listModels(ctx context.Context) ([]string, error) {
+	var list modelList
+	if err := p.c.getJSON(ctx, p.c.baseURL+"/models?limit=1000", &list); err != nil {
+		return nil, err
+	}
+	return list.ids(), nil
+}
+
+// listModels returns the models that support generateContent; embedding and
+// other models are left out.
+func (p *geminiProvider) listModels(ctx context.Context) ([]string, error) {
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := p.c.getJSON(ctx, p.c.baseURL+"/models?pageSize=1000", &list); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range list.Models {
+		if slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+			ids = append(ids, strings.TrimPrefix(m.Name, "models/"))
+		}
+	}
+	return ids, nil
+}
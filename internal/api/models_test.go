@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newModelsTestClient(typ, baseURL string) *Client {
+	return NewClient(&config.ProviderConfig{Type: typ, BaseURL: baseURL, APIKey: "sk-test"},
+		&config.ModelConfig{}, &config.ContextConfig{MaxTokens: 4096})
+}
+
+func TestListModelsOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"gpt-b"},{"id":"gpt-a"},{"id":"gpt-b"}]}`))
+	}))
+	defer server.Close()
+
+	ids, err := newModelsTestClient(config.ProviderTypeOpenAICompat, server.URL+"/v1").ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if !slices.Equal(ids, []string{"gpt-a", "gpt-b"}) {
+		t.Fatalf("ids = %v", ids)
+	}
+}
+
+func TestListModelsFallsBackToOllamaTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"models":[{"name":"llama3:latest"},{"name":"qwen3:8b"}]}`))
+	}))
+	defer server.Close()
+
+	ids, err := newModelsTestClient(config.ProviderTypeOpenAICompat, server.URL+"/v1").ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if !slices.Equal(ids, []string{"llama3:latest", "qwen3:8b"}) {
+		t.Fatalf("ids = %v", ids)
+	}
+}
+
+func TestListModelsGeminiKeepsGenerativeModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"models":[
+			{"name":"models/gemini-2.5-flash","supportedGenerationMethods":["generateContent","countTokens"]},
+			{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}]}`))
+	}))
+	defer server.Close()
+
+	ids, err := newModelsTestClient(config.ProviderTypeGemini, server.URL).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if !slices.Equal(ids, []string{"gemini-2.5-flash"}) {
+		t.Fatalf("ids = %v", ids)
+	}
+}
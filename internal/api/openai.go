@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// openAIProvider speaks the OpenAI-compatible /chat/completions dialect used
+// by OpenAI, Ollama, vLLM, llama.cpp and most gateways.
+type openAIProvider struct {
+	c *Client
+	// chatPath is the chat completions endpoint relative to the base URL.
+	chatPath string
+}
+
+func (p *openAIProvider) defaultBaseURL() string {
+	return "https://api.openai.com/v1"
+}
+
+func (p *openAIProvider) setAuth(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+func (p *openAIProvider) streamChat(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
+	c := p.c
+	req := &ChatCompletionRequest{
+		Model:             c.modelCfg.Model,
+		Messages:          wireMessages(messages, c.modelCfg.EchoReasoning),
+		Temperature:       c.modelCfg.Temperature,
+		TopP:              c.modelCfg.TopP,
+		MinP:              c.modelCfg.MinP,
+		TopK:              c.modelCfg.TopK,
+		FrequencyPenalty:  c.modelCfg.FrequencyPenalty,
+		PresencePenalty:   c.modelCfg.PresencePenalty,
+		Stop:              c.modelCfg.Stop,
+		Seed:              c.modelCfg.Seed,
+		ParallelToolCalls: c.modelCfg.ParallelToolCalls,
+		ReasoningEffort:   c.modelCfg.ReasoningEffort,
+		MaxTokens:         c.contextConfig.MaxTokens,
+		Tools:             tools,
+		Stream:            true,
+		StreamOptions: &StreamOptions{
+			IncludeUsage: true,
+		},
+		ResponseFormat: newResponseFormat(c.modelCfg.ResponseFormat),
+	}
+
+	slog.Debug("Starting streaming chat completion", "model", req.Model, "messages", len(req.Messages))
+
+	resp, err := c.postStream(ctx, p.chatPath, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return streamSSE(resp, func(data string, emit func(StreamEvent)) bool {
+		if data == "[DONE]" {
+			slog.Debug("Stream completed")
+			return false
+		}
+
+		var chunk StreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			slog.Warn("Failed to parse streaming chunk", "error", err, "data", data)
+			emit(StreamEvent{Error: fmt.Errorf("parse chunk: %w", err)})
+			return true
+		}
+
+		// Log the raw chunk for debugging
+		if len(chunk.Choices) > 0 {
+			slog.Debug("Raw stream chunk",
+				slog.String("content", chunk.Choices[0].Delta.Content),
+				slog.Int("toolCalls", len(chunk.Choices[0].Delta.ToolCalls)),
+				slog.String("finishReason", chunk.Choices[0].FinishReason))
+		}
+
+		emit(StreamEvent{Data: &chunk})
+		return true
+	}), nil
+}
+
+func (p *openAIProvider) summarize(ctx context.Context, messages []Message) (string, *Usage, error) {
+	c := p.c
+	req := &ChatCompletionRequest{
+		Model:            c.modelCfg.Model,
+		Messages:         append(wireMessages(messages, c.modelCfg.EchoReasoning), summarizeInstruction),
+		Temperature:      c.modelCfg.Temperature,
+		TopP:             c.modelCfg.TopP,
+		MinP:             c.modelCfg.MinP,
+		TopK:             c.modelCfg.TopK,
+		FrequencyPenalty: c.modelCfg.FrequencyPenalty,
+		PresencePenalty:  c.modelCfg.PresencePenalty,
+		Stop:             c.modelCfg.Stop,
+		Seed:             c.modelCfg.Seed,
+		ReasoningEffort:  c.modelCfg.ReasoningEffort,
+		ResponseFormat:   newResponseFormat(summaryFormat(c.modelCfg.ResponseFormat)),
+	}
+
+	resp, err := c.postJSON(ctx, p.chatPath, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarize request: %w", err)
+	}
+	defer closeBody(resp)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("read summarize response: %w", err)
+	}
+
+	var completion ChatCompletionResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return "", nil, fmt.Errorf("parse summarize response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 || completion.Choices[0].Message.Content == "" {
+		return "", nil, fmt.Errorf("empty summarize response")
+	}
+
+	return completion.Choices[0].Message.Content, &completion.Usage, nil
+}
+
+// wireMessages returns messages in the form chat completions servers
+// accept: session-only fields (Responses API reasoning items, the answering
+// model) are removed so strict servers do not reject them, and images
+// returned by tools are moved into a user message since tool messages may
+// only contain text. reasoning_content is kept only with echoReasoning;
+// servers such as DeepSeek reject it otherwise.
+func wireMessages(messages []Message, echoReasoning bool) []Message {
+	messages = moveToolImages(messages)
+	var out []Message
+	for i, msg := range messages {
+		if len(msg.ReasoningItems) == 0 && msg.Model == "" && (echoReasoning || msg.ReasoningContent == "") {
+			continue
+		}
+		if out == nil {
+			out = slices.Clone(messages)
+		}
+		out[i].ReasoningItems = nil
+		out[i].Model = ""
+		if !echoReasoning {
+			out[i].ReasoningContent = ""
+		}
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestOpenAIStreamSeparatesReasoningContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant","reasoning_content":"Think "}}]}`,
+			`{"choices":[{"index":0,"delta":{"reasoning":"harder."}}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"Done."},"finish_reason":"stop"}]}`,
+		}
+		for _, c := range chunks {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	stream, err := newRetryTestClient(server.URL, config.RetryConfig{}).StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+	var content, reasoning strings.Builder
+	for ev := range stream {
+		if ev.Error != nil {
+			t.Fatalf("stream error: %v", ev.Error)
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+			reasoning.WriteString(ch.Delta.ReasoningContent)
+		}
+	}
+	if content.String() != "Done." || reasoning.String() != "Think harder." {
+		t.Fatalf("content = %q, reasoning = %q", content.String(), reasoning.String())
+	}
+}
+
+func TestWireMessagesEchoesReasoningOnlyWhenConfigured(t *testing.T) {
+	messages := []Message{
+		NewUserMessage("hi"),
+		{Role: "assistant", Content: "ok", ReasoningContent: "because"},
+	}
+
+	data, err := json.Marshal(wireMessages(messages, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "reasoning_content") {
+		t.Fatalf("reasoning_content should be stripped: %s", data)
+	}
+	if messages[1].ReasoningContent != "because" {
+		t.Fatal("original messages must not be modified")
+	}
+
+	data, err = json.Marshal(wireMessages(messages, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"reasoning_content":"because"`) {
+		t.Fatalf("reasoning_content should be echoed: %s", data)
+	}
+}
@@ -0,0 +1,270 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// QueueInfo describes a request waiting for the provider's rate limit.
+type QueueInfo struct {
+	Waiting bool
+	Ahead   int    // requests queued before this one
+	Reason  string // the limit holding up the queue
+}
+
+// String renders the info as a short status line, e.g.
+// "Queued: max_concurrent (2 ahead)".
+func (q QueueInfo) String() string {
+	if q.Ahead == 0 {
+		return "Queued: " + q.Reason
+	}
+	return fmt.Sprintf("Queued: %s (%d ahead)", q.Reason, q.Ahead)
+}
+
+type queueNotifierKey struct{}
+
+// WithQueueNotifier returns a context that makes the client call fn while a
+// request waits for the provider's rate limit: when it starts waiting, when
+// its position or the blocking limit changes, and with a zero QueueInfo once
+// it is sent. fn is called from the goroutine issuing the request.
+func WithQueueNotifier(ctx context.Context, fn func(QueueInfo)) context.Context {
+	return context.WithValue(ctx, queueNotifierKey{}, fn)
+}
+
+func notifyQueue(ctx context.Context, info QueueInfo) {
+	if fn, ok := ctx.Value(queueNotifierKey{}).(func(QueueInfo)); ok && fn != nil {
+		fn(info)
+	}
+}
+
+var (
+	rateLimitersMu sync.Mutex
+	rateLimiters   = make(map[string]*rateLimiter)
+)
+
+// rateLimiterFor returns the limiter shared by every client talking to
+// baseURL with the same limits, or nil when limits are all zero.
+func rateLimiterFor(baseURL string, limits config.RateLimitConfig) *rateLimiter {
+	if limits == (config.RateLimitConfig{}) {
+		return nil
+	}
+	key := fmt.Sprintf("%s|%d|%d|%d", baseURL, limits.MaxConcurrent, limits.RequestsPerMinute, limits.TokensPerMinute)
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+	l, ok := rateLimiters[key]
+	if !ok {
+		l = newRateLimiter(limits, time.Minute)
+		rateLimiters[key] = l
+	}
+	return l
+}
+
+// rateLimiter admits requests in arrival order while they fit the limits.
+// The per-minute limits use a sliding window.
+type rateLimiter struct {
+	limits config.RateLimitConfig
+	window time.Duration
+
+	mu     sync.Mutex
+	queue  []*limitWaiter
+	active int
+	starts []time.Time  // request starts within the window
+	spent  []tokenSpend // token charges within the window
+	timer  *time.Timer
+}
+
+type tokenSpend struct {
+	at     time.Time
+	tokens int
+}
+
+type limitWaiter struct {
+	tokens  int
+	ready   chan struct{}
+	changed chan struct{}
+	granted bool
+}
+
+func newRateLimiter(limits config.RateLimitConfig, window time.Duration) *rateLimiter {
+	return &rateLimiter{limits: limits, window: window}
+}
+
+// acquire waits for the request's turn and returns the function that ends
+// it. tokens is the request's estimated prompt size.
+func (l *rateLimiter) acquire(ctx context.Context, tokens int) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	w := &limitWaiter{tokens: tokens, ready: make(chan struct{}), changed: make(chan struct{}, 1)}
+	l.mu.Lock()
+	l.queue = append(l.queue, w)
+	l.dispatchLocked()
+	l.mu.Unlock()
+
+	var last QueueInfo
+	for {
+		select {
+		case <-w.ready:
+			if last.Waiting {
+				notifyQueue(ctx, QueueInfo{})
+			}
+			return sync.OnceFunc(l.release), nil
+		case <-ctx.Done():
+			l.mu.Lock()
+			if w.granted {
+				l.mu.Unlock()
+				l.release()
+			} else {
+				l.removeLocked(w)
+				l.dispatchLocked()
+				l.mu.Unlock()
+			}
+			return nil, ctx.Err()
+		default:
+		}
+
+		if info := l.status(w); info.Waiting && info != last {
+			notifyQueue(ctx, info)
+			last = info
+		}
+		select {
+		case <-w.ready:
+		case <-w.changed:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	l.dispatchLocked()
+}
+
+// charge counts tokens reported after a request against tokens_per_minute.
+func (l *rateLimiter) charge(tokens int) {
+	if l == nil || l.limits.TokensPerMinute == 0 || tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.spent = append(l.spent, tokenSpend{at: time.Now(), tokens: tokens})
+}
+
+// tracksTokens reports whether reported usage has to be charged.
+func (l *rateLimiter) tracksTokens() bool {
+	return l != nil && l.limits.TokensPerMinute > 0
+}
+
+// status describes w's place in the queue.
+func (l *rateLimiter) status(w *limitWaiter) QueueInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, q := range l.queue {
+		if q == w {
+			reason, _ := l.blockedLocked(l.queue[0].tokens, time.Now())
+			return QueueInfo{Waiting: true, Ahead: i, Reason: reason}
+		}
+	}
+	return QueueInfo{}
+}
+
+// dispatchLocked admits queued requests from the front for as long as they
+// fit, and arranges to try again when the sliding window moves.
+func (l *rateLimiter) dispatchLocked() {
+	for len(l.queue) > 0 {
+		now := time.Now()
+		w := l.queue[0]
+		reason, wait := l.blockedLocked(w.tokens, now)
+		if reason != "" {
+			if wait > 0 {
+				if l.timer != nil {
+					l.timer.Stop()
+				}
+				l.timer = time.AfterFunc(wait, func() {
+					l.mu.Lock()
+					defer l.mu.Unlock()
+					l.dispatchLocked()
+				})
+			}
+			break
+		}
+		l.queue = l.queue[1:]
+		l.active++
+		l.starts = append(l.starts, now)
+		if l.limits.TokensPerMinute > 0 && w.tokens > 0 {
+			l.spent = append(l.spent, tokenSpend{at: now, tokens: w.tokens})
+		}
+		w.granted = true
+		close(w.ready)
+	}
+	for _, q := range l.queue {
+		select {
+		case q.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// blockedLocked returns the limit that keeps a request of the given size
+// from starting now, and how long until the window frees up for it (zero
+// when it has to wait for a running request instead).
+func (l *rateLimiter) blockedLocked(tokens int, now time.Time) (string, time.Duration) {
+	cutoff := now.Add(-l.window)
+	for len(l.starts) > 0 && !l.starts[0].After(cutoff) {
+		l.starts = l.starts[1:]
+	}
+	total := 0
+	for len(l.spent) > 0 && !l.spent[0].at.After(cutoff) {
+		l.spent = l.spent[1:]
+	}
+	for _, s := range l.spent {
+		total += s.tokens
+	}
+
+	lim := l.limits
+	if lim.MaxConcurrent > 0 && l.active >= lim.MaxConcurrent {
+		return "max_concurrent", 0
+	}
+	if lim.RequestsPerMinute > 0 && len(l.starts) >= lim.RequestsPerMinute {
+		return "requests_per_minute", l.starts[0].Add(l.window).Sub(now)
+	}
+	// A request larger than the whole budget still runs once the window
+	// is empty rather than waiting forever.
+	if lim.TokensPerMinute > 0 && total > 0 && total+tokens > lim.TokensPerMinute {
+		for _, s := range l.spent {
+			total -= s.tokens
+			if total == 0 || total+tokens <= lim.TokensPerMinute {
+				return "tokens_per_minute", s.at.Add(l.window).Sub(now)
+			}
+		}
+	}
+	return "", 0
+}
+
+func (l *rateLimiter) removeLocked(w *limitWaiter) {
+	for i, q := range l.queue {
+		if q == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// limitedBody ends the request's rate limit slot when the body is closed.
+type limitedBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *limitedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// queued waits until l has n requests waiting.
+func queued(t *testing.T, l *rateLimiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		got := len(l.queue)
+		l.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queue never reached %d requests", n)
+}
+
+func TestRateLimiterAdmitsInArrivalOrder(t *testing.T) {
+	l := newRateLimiter(config.RateLimitConfig{MaxConcurrent: 1}, time.Minute)
+	releaseFirst, err := l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var order []string
+	var infos []QueueInfo
+	var wg sync.WaitGroup
+	for i, name := range []string{"b", "c"} {
+		ctx := WithQueueNotifier(context.Background(), func(info QueueInfo) {
+			if name == "c" {
+				mu.Lock()
+				infos = append(infos, info)
+				mu.Unlock()
+			}
+		})
+		wg.Go(func() {
+			release, err := l.acquire(ctx, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			release()
+		})
+		queued(t, l, i+1)
+	}
+
+	releaseFirst()
+	wg.Wait()
+	if len(order) != 2 || order[0] != "b" || order[1] != "c" {
+		t.Fatalf("order = %v", order)
+	}
+	if len(infos) == 0 || infos[0] != (QueueInfo{Waiting: true, Ahead: 1, Reason: "max_concurrent"}) {
+		t.Fatalf("queue notifications = %+v", infos)
+	}
+	if last := infos[len(infos)-1]; last.Waiting {
+		t.Fatalf("last notification should clear the queue status: %+v", last)
+	}
+}
+
+func TestRateLimiterRequestsPerWindow(t *testing.T) {
+	window := 50 * time.Millisecond
+	l := newRateLimiter(config.RateLimitConfig{RequestsPerMinute: 2}, window)
+	start := time.Now()
+	for range 3 {
+		release, err := l.acquire(context.Background(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < window {
+		t.Fatalf("third request started after %v, want at least %v", elapsed, window)
+	}
+}
+
+func TestRateLimiterTokensPerWindow(t *testing.T) {
+	window := 50 * time.Millisecond
+	l := newRateLimiter(config.RateLimitConfig{TokensPerMinute: 100}, window)
+
+	// A request larger than the budget runs when nothing else has been spent.
+	release, err := l.acquire(context.Background(), 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+
+	start := time.Now()
+	release, err = l.acquire(context.Background(), 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if elapsed := time.Since(start); elapsed < window/2 {
+		t.Fatalf("request admitted after %v while the budget was spent", elapsed)
+	}
+}
+
+func TestRateLimiterCancelledWaiterLeavesQueue(t *testing.T) {
+	l := newRateLimiter(config.RateLimitConfig{MaxConcurrent: 1}, time.Minute)
+	releaseFirst, err := l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(ctx, 0)
+		errc <- err
+	}()
+	queued(t, l, 1)
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("err = %v", err)
+	}
+	queued(t, l, 0)
+
+	releaseFirst()
+	release, err := l.acquire(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestClientHoldsRateLimitSlotForWholeStream(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeChunk(w, "a")
+		time.Sleep(20 * time.Millisecond)
+		writeChunk(w, "b")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := newTimeoutTestClient(server.URL, config.ProviderConfig{RateLimit: config.RateLimitConfig{MaxConcurrent: 1}})
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if content, err := collectStream(t, stream); err != nil || content != "ab" {
+				t.Errorf("content = %q, err = %v", content, err)
+			}
+		})
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 1 {
+		t.Fatalf("peak concurrent requests = %d, want 1", got)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// ResponseFormat specifies the output format for the model response.
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is the schema of a "json_schema" response format.
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict *bool           `json:"strict,omitempty"`
+}
+
+// newResponseFormat converts the configured format for chat completions.
+func newResponseFormat(rf *config.ResponseFormat) *ResponseFormat {
+	if rf == nil || rf.Type == "" {
+		return nil
+	}
+	out := &ResponseFormat{Type: rf.Type}
+	if rf.Type == config.ResponseFormatJSONSchema {
+		out.JSONSchema = &JSONSchema{Name: rf.Name, Schema: rf.Schema, Strict: rf.Strict}
+	}
+	return out
+}
+
+// summaryFormat is the format used for Summarize: a schema made for
+// answers would force the summary into it, so only plain JSON is kept.
+func summaryFormat(rf *config.ResponseFormat) *config.ResponseFormat {
+	if rf != nil && rf.Type == config.ResponseFormatJSONSchema {
+		return nil
+	}
+	return rf
+}
+
+// responsesFormat is the text.format of a Responses API request, which
+// flattens the json_schema fields into the format object.
+type responsesFormat struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name,omitempty"`
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Strict *bool           `json:"strict,omitempty"`
+}
+
+func newResponsesFormat(rf *config.ResponseFormat) *responsesFormat {
+	if rf == nil || rf.Type == "" {
+		return nil
+	}
+	out := &responsesFormat{Type: rf.Type}
+	if rf.Type == config.ResponseFormatJSONSchema {
+		out.Name, out.Schema, out.Strict = rf.Name, rf.Schema, rf.Strict
+	}
+	return out
+}
+
+// schemaInstruction asks for schema-conforming output in the system prompt,
+// for providers without a native JSON schema mode.
+func schemaInstruction(rf *config.ResponseFormat) string {
+	if rf == nil {
+		return ""
+	}
+	switch rf.Type {
+	case config.ResponseFormatJSONObject:
+		return "Respond with a single JSON object and nothing else."
+	case config.ResponseFormatJSONSchema:
+		return "When you give your final answer, respond with only a JSON value, without Markdown fences, that conforms to this JSON Schema:\n" + string(rf.Schema)
+	}
+	return ""
+}
@@ -0,0 +1,409 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// responsesRequest is the request body for POST /responses.
+type responsesRequest struct {
+	Model             string              `json:"model"`
+	Input             []any               `json:"input"`
+	Tools             []responsesTool     `json:"tools,omitempty"`
+	Temperature       float32             `json:"temperature,omitempty"`
+	TopP              float32             `json:"top_p,omitempty"`
+	MaxOutputTokens   int                 `json:"max_output_tokens,omitempty"`
+	ParallelToolCalls *bool               `json:"parallel_tool_calls,omitempty"`
+	Reasoning         *responsesReasoning `json:"reasoning,omitempty"`
+	Text              *responsesText      `json:"text,omitempty"`
+	Include           []string            `json:"include,omitempty"`
+	Store             bool                `json:"store"`
+	Stream            bool                `json:"stream,omitempty"`
+}
+
+type responsesReasoning struct {
+	Effort string `json:"effort,omitempty"`
+	// Summary asks for reasoning summaries, streamed as
+	// response.reasoning_summary_text.delta events.
+	Summary string `json:"summary,omitempty"`
+}
+
+type responsesText struct {
+	Format *responsesFormat `json:"format"`
+}
+
+// responsesTool is a function tool. Unlike chat completions, the function
+// fields sit at the top level instead of under "function".
+type responsesTool struct {
+	Type        string             `json:"type"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Parameters  FunctionParameters `json:"parameters"`
+}
+
+// responsesInputMessage is an "easy input" message item. Content is a
+// string, or a []responsesContentPart for messages with images.
+type responsesInputMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type responsesContentPart struct {
+	Type     string `json:"type"` // "input_text" or "input_image"
+	Text     string `json:"text,omitempty"`
+	ImageURL string `json:"image_url,omitempty"`
+}
+
+// responsesContent converts a message's content for an input message.
+func responsesContent(msg Message) any {
+	if len(msg.Parts) == 0 {
+		return msg.Content
+	}
+	parts := make([]responsesContentPart, 0, len(msg.Parts))
+	for _, p := range msg.Parts {
+		switch {
+		case p.Type == ContentPartText:
+			parts = append(parts, responsesContentPart{Type: "input_text", Text: p.Text})
+		case p.Type == ContentPartImage && p.ImageURL != nil:
+			parts = append(parts, responsesContentPart{Type: "input_image", ImageURL: p.ImageURL.URL})
+		}
+	}
+	return parts
+}
+
+type responsesFunctionCall struct {
+	Type      string `json:"type"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type responsesFunctionCallOutput struct {
+	Type   string `json:"type"`
+	CallID string `json:"call_id"`
+	Output string `json:"output"`
+}
+
+// responsesResponse is the response object returned by POST /responses and
+// embedded in response.* stream events.
+type responsesResponse struct {
+	ID                string               `json:"id"`
+	Model             string               `json:"model"`
+	Status            string               `json:"status"`
+	Output            []responsesItem      `json:"output"`
+	Usage             *responsesUsage      `json:"usage,omitempty"`
+	Error             *APIError            `json:"error,omitempty"`
+	IncompleteDetails *responsesIncomplete `json:"incomplete_details,omitempty"`
+}
+
+type responsesIncomplete struct {
+	Reason string `json:"reason"`
+}
+
+// responsesItem is an output item. Only the fields ashron inspects are
+// decoded; reasoning items are kept as raw JSON elsewhere.
+type responsesItem struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Content   []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+type responsesUsage struct {
+	InputTokens        int `json:"input_tokens"`
+	OutputTokens       int `json:"output_tokens"`
+	TotalTokens        int `json:"total_tokens"`
+	InputTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+}
+
+func (u *responsesUsage) toUsage() *Usage {
+	return &Usage{
+		PromptTokens:        u.InputTokens,
+		CompletionTokens:    u.OutputTokens,
+		TotalTokens:         u.TotalTokens,
+		PromptTokensDetails: cachedDetails(u.InputTokensDetails.CachedTokens),
+	}
+}
+
+// responsesStreamEvent is the union of the SSE event payloads we care about.
+type responsesStreamEvent struct {
+	Type        string             `json:"type"`
+	OutputIndex int                `json:"output_index"`
+	Delta       string             `json:"delta"`
+	Item        json.RawMessage    `json:"item,omitempty"`
+	Response    *responsesResponse `json:"response,omitempty"`
+	Message     string             `json:"message"`
+	Code        string             `json:"code"`
+}
+
+// responsesProvider speaks the OpenAI Responses API (POST /responses).
+//
+// Requests are sent with store=false, so the server keeps no conversation
+// state. Reasoning items are requested with their encrypted content, attached
+// to the assistant message that produced them (Message.ReasoningItems) and
+// replayed in front of that message on later turns.
+type responsesProvider struct {
+	c *Client
+}
+
+func (p *responsesProvider) defaultBaseURL() string {
+	return "https://api.openai.com/v1"
+}
+
+func (p *responsesProvider) setAuth(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+}
+
+func (p *responsesProvider) buildRequest(messages []Message, tools []Tool, format *config.ResponseFormat) *responsesRequest {
+	c := p.c
+	req := &responsesRequest{
+		Model:             c.modelCfg.Model,
+		Input:             toResponsesInput(messages),
+		Temperature:       c.modelCfg.Temperature,
+		TopP:              c.modelCfg.TopP,
+		MaxOutputTokens:   c.modelCfg.MaxOutputTokens,
+		ParallelToolCalls: c.modelCfg.ParallelToolCalls,
+	}
+	if p.reasons() {
+		// Other models may reject these.
+		req.Include = []string{"reasoning.encrypted_content"}
+		req.Reasoning = &responsesReasoning{Effort: c.modelCfg.ReasoningEffort, Summary: "auto"}
+	}
+	if format := newResponsesFormat(format); format != nil {
+		req.Text = &responsesText{Format: format}
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, responsesTool{
+			Type:        "function",
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			Parameters:  t.Function.Parameters,
+		})
+	}
+	return req
+}
+
+// reasoningModelPrefixes name the OpenAI model families that reason even
+// without a reasoning_effort.
+var reasoningModelPrefixes = []string{"o1", "o3", "o4", "gpt-5", "codex-"}
+
+// reasons reports whether the model reasons: reasoning_effort is set or it
+// is a known reasoning model.
+func (p *responsesProvider) reasons() bool {
+	if p.c.modelCfg.ReasoningEffort != "" {
+		return true
+	}
+	model := strings.ToLower(p.c.modelCfg.Model)
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, prefix := range reasoningModelPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// toResponsesInput converts chat messages to Responses API input items.
+// An assistant message expands to its reasoning items, its text and one
+// function_call item per tool call, in that order; tool messages become
+// function_call_output items.
+func toResponsesInput(messages []Message) []any {
+	var input []any
+	for _, msg := range moveToolImages(messages) {
+		switch msg.Role {
+		case "assistant":
+			for _, item := range msg.ReasoningItems {
+				input = append(input, item)
+			}
+			if msg.Content != "" {
+				input = append(input, responsesInputMessage{Role: "assistant", Content: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				input = append(input, responsesFunctionCall{
+					Type:      "function_call",
+					CallID:    tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+				})
+			}
+		case "tool":
+			input = append(input, responsesFunctionCallOutput{
+				Type:   "function_call_output",
+				CallID: msg.ToolCallID,
+				Output: msg.Content,
+			})
+		default:
+			input = append(input, responsesInputMessage{Role: msg.Role, Content: responsesContent(msg)})
+		}
+	}
+	return input
+}
+
+// responsesFinishReason derives an OpenAI finish_reason from a completed
+// response. The Responses API has no finish reason of its own.
+func responsesFinishReason(resp *responsesResponse, sawToolCall bool) string {
+	if resp != nil && resp.IncompleteDetails != nil && resp.IncompleteDetails.Reason == "max_output_tokens" {
+		return "length"
+	}
+	if sawToolCall {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func responsesError(resp *responsesResponse, fallback string) error {
+	if resp != nil && resp.Error != nil {
+		return fmt.Errorf("API error: %s (code: %v)", resp.Error.Message, resp.Error.Code)
+	}
+	return fmt.Errorf("API error: %s", fallback)
+}
+
+// streamChat sends a streaming /responses request and translates the
+// response.* SSE events into OpenAI-style StreamResponse chunks. Completed
+// reasoning items are delivered in Delta.ReasoningItems.
+func (p *responsesProvider) streamChat(ctx context.Context, messages []Message, tools []Tool) (<-chan StreamEvent, error) {
+	req := p.buildRequest(messages, tools, p.c.modelCfg.ResponseFormat)
+	req.Stream = true
+
+	slog.Debug("Starting streaming response", "model", req.Model, "items", len(req.Input))
+
+	resp, err := p.c.postStream(ctx, "/responses", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		model     string
+		toolIndex = make(map[int]int) // output index -> tool call index
+	)
+
+	return streamSSE(resp, func(data string, emit func(StreamEvent)) bool {
+		var ev responsesStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			slog.Warn("Failed to parse streaming event", "error", err, "data", data)
+			emit(StreamEvent{Error: fmt.Errorf("parse chunk: %w", err)})
+			return true
+		}
+
+		chunk := StreamResponse{Model: model}
+		switch ev.Type {
+		case "response.created":
+			if ev.Response != nil {
+				model = ev.Response.Model
+			}
+			return true
+		case "response.output_text.delta":
+			if ev.Delta == "" {
+				return true
+			}
+			chunk.Choices = []Choice{{Delta: Message{Content: ev.Delta}}}
+		case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+			if ev.Delta == "" {
+				return true
+			}
+			chunk.Choices = []Choice{{Delta: Message{ReasoningContent: ev.Delta}}}
+		case "response.output_item.added":
+			var item responsesItem
+			if err := json.Unmarshal(ev.Item, &item); err != nil || item.Type != "function_call" {
+				return true
+			}
+			idx := len(toolIndex)
+			toolIndex[ev.OutputIndex] = idx
+			chunk.Choices = []Choice{{Delta: Message{ToolCalls: []ToolCall{{
+				Index:    idx,
+				ID:       item.CallID,
+				Type:     "function",
+				Function: FunctionCall{Name: item.Name},
+			}}}}}
+		case "response.function_call_arguments.delta":
+			idx, ok := toolIndex[ev.OutputIndex]
+			if !ok || ev.Delta == "" {
+				return true
+			}
+			chunk.Choices = []Choice{{Delta: Message{ToolCalls: []ToolCall{{
+				Index:    idx,
+				Function: FunctionCall{Arguments: ev.Delta},
+			}}}}}
+		case "response.output_item.done":
+			var item responsesItem
+			if err := json.Unmarshal(ev.Item, &item); err != nil || item.Type != "reasoning" {
+				return true
+			}
+			chunk.Choices = []Choice{{Delta: Message{ReasoningItems: []json.RawMessage{ev.Item}}}}
+		case "response.completed", "response.incomplete":
+			if ev.Response != nil && ev.Response.Usage != nil {
+				chunk.Usage = ev.Response.Usage.toUsage()
+			}
+			chunk.Choices = []Choice{{FinishReason: responsesFinishReason(ev.Response, len(toolIndex) > 0)}}
+			emit(StreamEvent{Data: &chunk})
+			slog.Debug("Stream completed")
+			return false
+		case "response.failed":
+			emit(StreamEvent{Error: responsesError(ev.Response, data)})
+			return false
+		case "error":
+			emit(StreamEvent{Error: fmt.Errorf("API error: %s (code: %s)", ev.Message, ev.Code)})
+			return false
+		default:
+			return true
+		}
+
+		emit(StreamEvent{Data: &chunk})
+		return true
+	}), nil
+}
+
+func (p *responsesProvider) summarize(ctx context.Context, messages []Message) (string, *Usage, error) {
+	req := p.buildRequest(append(messages, summarizeInstruction), nil, summaryFormat(p.c.modelCfg.ResponseFormat))
+
+	resp, err := p.c.postJSON(ctx, "/responses", req)
+	if err != nil {
+		return "", nil, fmt.Errorf("summarize request: %w", err)
+	}
+	defer closeBody(resp)
+
+	var completion responsesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", nil, fmt.Errorf("parse summarize response: %w", err)
+	}
+	if completion.Status == "failed" {
+		return "", nil, responsesError(&completion, "summarize failed")
+	}
+
+	var sb strings.Builder
+	for _, item := range completion.Output {
+		if item.Type != "message" {
+			continue
+		}
+		for _, part := range item.Content {
+			if part.Type == "output_text" {
+				sb.WriteString(part.Text)
+			}
+		}
+	}
+	if sb.Len() == 0 {
+		return "", nil, fmt.Errorf("empty summarize response")
+	}
+	var usage *Usage
+	if completion.Usage != nil {
+		usage = completion.Usage.toUsage()
+	}
+	return sb.String(), usage, nil
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newResponsesTestClient(baseURL string) *Client {
+	return NewClient(
+		&config.ProviderConfig{
+			Type:    config.ProviderTypeOpenAIResponses,
+			BaseURL: baseURL,
+			APIKey:  "test-key",
+			Timeout: 5 * time.Second,
+		},
+		&config.ModelConfig{Model: "o-test", ReasoningEffort: "high"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+}
+
+func TestToResponsesInputReplaysReasoningItems(t *testing.T) {
+	reasoning := json.RawMessage(`{"type":"reasoning","id":"rs_1","encrypted_content":"opaque","summary":[]}`)
+	input := toResponsesInput([]Message{
+		NewSystemMessage("sys"),
+		NewUserMessage("hi"),
+		{Role: "assistant", Content: "checking", ReasoningItems: []json.RawMessage{reasoning}, ToolCalls: []ToolCall{
+			{ID: "call_1", Type: "function", Function: FunctionCall{Name: "read_file", Arguments: `{"path":"a"}`}},
+		}},
+		NewToolMessage("call_1", "content-a"),
+	})
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatalf("marshal input: %v", err)
+	}
+	var items []map[string]any
+	if err := json.Unmarshal(data, &items); err != nil {
+		t.Fatalf("unmarshal input: %v", err)
+	}
+	if len(items) != 6 {
+		t.Fatalf("expected 6 input items, got %d: %s", len(items), data)
+	}
+	if items[0]["role"] != "system" || items[1]["role"] != "user" {
+		t.Fatalf("unexpected leading items: %s", data)
+	}
+	if items[2]["type"] != "reasoning" || items[2]["encrypted_content"] != "opaque" {
+		t.Fatalf("reasoning item should be replayed verbatim before the assistant turn: %s", data)
+	}
+	if items[3]["role"] != "assistant" || items[4]["type"] != "function_call" || items[4]["call_id"] != "call_1" {
+		t.Fatalf("unexpected assistant items: %s", data)
+	}
+	if items[5]["type"] != "function_call_output" || items[5]["output"] != "content-a" {
+		t.Fatalf("unexpected tool output item: %s", data)
+	}
+}
+
+func TestResponsesStreamTranslatesEvents(t *testing.T) {
+	var gotReq map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/responses" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			http.Error(w, "missing auth", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"response.created","response":{"id":"resp_1","model":"o-test","status":"in_progress","output":[]}}`,
+			`{"type":"response.output_item.added","output_index":0,"item":{"type":"reasoning","id":"rs_1","summary":[]}}`,
+			`{"type":"response.output_item.done","output_index":0,"item":{"type":"reasoning","id":"rs_1","encrypted_content":"opaque","summary":[]}}`,
+			`{"type":"response.output_text.delta","output_index":1,"delta":"Let me "}`,
+			`{"type":"response.output_text.delta","output_index":1,"delta":"look."}`,
+			`{"type":"response.output_item.added","output_index":2,"item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"read_file","arguments":""}}`,
+			`{"type":"response.function_call_arguments.delta","output_index":2,"delta":"{\"path\":"}`,
+			`{"type":"response.function_call_arguments.delta","output_index":2,"delta":"\"a.txt\"}"}`,
+			`{"type":"response.completed","response":{"id":"resp_1","model":"o-test","status":"completed","output":[],"usage":{"input_tokens":21,"output_tokens":9,"total_tokens":30}}}`,
+		}
+		for _, ev := range events {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(ev), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, ev)
+		}
+	}))
+	defer server.Close()
+
+	client := newResponsesTestClient(server.URL)
+	tools := []Tool{{Type: "function", Function: FunctionDef{
+		Name:       "read_file",
+		Parameters: FunctionParameters{Type: "object", Properties: map[string]FunctionProperty{"path": {Type: "string"}}},
+	}}}
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, tools)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+
+	var content, args strings.Builder
+	var reasoning []json.RawMessage
+	var toolID, toolName, finish string
+	var usage *Usage
+	for ev := range stream {
+		if ev.Error != nil {
+			t.Fatalf("stream error: %v", ev.Error)
+		}
+		if ev.Data.Usage != nil {
+			usage = ev.Data.Usage
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+			reasoning = append(reasoning, ch.Delta.ReasoningItems...)
+			for _, tc := range ch.Delta.ToolCalls {
+				if tc.ID != "" {
+					toolID, toolName = tc.ID, tc.Function.Name
+				}
+				args.WriteString(tc.Function.Arguments)
+			}
+			if ch.FinishReason != "" {
+				finish = ch.FinishReason
+			}
+		}
+	}
+
+	if gotReq["store"] != false || gotReq["stream"] != true {
+		t.Fatalf("unexpected request flags: %+v", gotReq)
+	}
+	if r, _ := gotReq["reasoning"].(map[string]any); r["effort"] != "high" {
+		t.Fatalf("reasoning effort not sent: %+v", gotReq["reasoning"])
+	}
+	if content.String() != "Let me look." {
+		t.Fatalf("unexpected content: %q", content.String())
+	}
+	if len(reasoning) != 1 || !strings.Contains(string(reasoning[0]), `"encrypted_content":"opaque"`) {
+		t.Fatalf("unexpected reasoning items: %s", reasoning)
+	}
+	if toolID != "call_1" || toolName != "read_file" || args.String() != `{"path":"a.txt"}` {
+		t.Fatalf("unexpected tool call: id=%q name=%q args=%q", toolID, toolName, args.String())
+	}
+	if finish != "tool_calls" {
+		t.Fatalf("finish reason = %q, want tool_calls", finish)
+	}
+	if usage == nil || usage.PromptTokens != 21 || usage.CompletionTokens != 9 || usage.TotalTokens != 30 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestResponsesRequestAsksForReasoningOnlyFromReasoningModels(t *testing.T) {
+	tests := []struct {
+		model, effort string
+		want          bool
+	}{
+		{"gpt-4.1", "", false},
+		{"gpt-4o-mini", "", false},
+		{"gpt-4.1", "low", true},
+		{"o4-mini", "", true},
+		{"openai/gpt-5", "", true},
+	}
+	for _, tt := range tests {
+		client := newResponsesTestClient("http://127.0.0.1:0")
+		client.modelCfg = &config.ModelConfig{Model: tt.model, ReasoningEffort: tt.effort}
+		req := client.provider.(*responsesProvider).buildRequest([]Message{NewUserMessage("hi")}, nil, nil)
+		if got := len(req.Include) > 0; got != tt.want {
+			t.Errorf("%s (effort %q): include = %v, want reasoning included %v", tt.model, tt.effort, req.Include, tt.want)
+		}
+		if got := req.Reasoning != nil && req.Reasoning.Summary == "auto" && req.Reasoning.Effort == tt.effort; got != tt.want {
+			t.Errorf("%s (effort %q): reasoning = %+v, want summaries requested %v", tt.model, tt.effort, req.Reasoning, tt.want)
+		}
+	}
+}
+
+func TestResponsesSummarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"id":"resp_1","status":"completed","output":[{"type":"reasoning","id":"rs_1"},{"type":"message","role":"assistant","content":[{"type":"output_text","text":"- summary"}]}]}`)
+	}))
+	defer server.Close()
+
+	client := newResponsesTestClient(server.URL)
+	summary, err := client.Summarize(context.Background(), []Message{NewUserMessage("hello")})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "- summary" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+}
+
+func TestWireMessagesStripsSessionFields(t *testing.T) {
+	messages := []Message{
+		NewUserMessage("hi"),
+		{Role: "assistant", Content: "ok", Model: "p/m", ReasoningItems: []json.RawMessage{json.RawMessage(`{}`)}},
+	}
+	stripped := wireMessages(messages, false)
+	if stripped[1].ReasoningItems != nil || stripped[1].Model != "" {
+		t.Fatalf("session-only fields should be stripped: %+v", stripped[1])
+	}
+	if messages[1].ReasoningItems == nil {
+		t.Fatal("original messages must not be modified")
+	}
+}
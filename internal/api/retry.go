@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/cassette"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// StatusError is returned when the provider answers with a non-200 status.
+// RetryAfter is the delay requested by the Retry-After header, or zero.
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// RetryInfo describes a retry that is about to happen.
+type RetryInfo struct {
+	Attempt     int // the attempt about to be made, starting at 2
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error // the error that caused the retry
+}
+
+// String renders the info as a short status line, e.g.
+// "Retrying in 4s (attempt 2/5)".
+func (r RetryInfo) String() string {
+	return fmt.Sprintf("Retrying in %s (attempt %d/%d)", r.Delay.Round(time.Second), r.Attempt, r.MaxAttempts)
+}
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier returns a context that makes the client call fn before
+// sleeping for a retry. fn is called from the goroutine issuing the request.
+func WithRetryNotifier(ctx context.Context, fn func(RetryInfo)) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, fn)
+}
+
+func notifyRetry(ctx context.Context, info RetryInfo) {
+	if fn, ok := ctx.Value(retryNotifierKey{}).(func(RetryInfo)); ok && fn != nil {
+		fn(info)
+	}
+}
+
+// RetryExhaustedError wraps the last error once every attempt has failed.
+type RetryExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("%v (gave up after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// SetupError reports a request that could not be made because of the
+// client's own setup: a missing API key, a failing api_key_command, a bad
+// ca_file or proxy, an unreadable cassette. Retrying or falling back to
+// another model cannot fix it, so it is never retried.
+type SetupError struct {
+	Err error
+}
+
+func (e *SetupError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SetupError) Unwrap() error {
+	return e.Err
+}
+
+// isRetryable reports whether err is worth another attempt under policy.
+// Besides the statuses listed in the policy, only transport failures are
+// retried: network errors (connection refused or reset, DNS failure),
+// timeouts and connections closed mid-response.
+func isRetryable(policy config.RetryConfig, err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return slices.Contains(policy.RetryOnStatus, se.StatusCode)
+	}
+	var setupErr *SetupError
+	if errors.As(err, &setupErr) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	// A request missing from a replay cassette will be missing next time too.
+	var nf *cassette.NotFoundError
+	if errors.As(err, &nf) {
+		return false
+	}
+	var te *TimeoutError
+	var netErr net.Error
+	return errors.As(err, &te) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryDelay returns how long to wait before the given attempt (2-based).
+// A Retry-After from the server wins over the computed backoff.
+func retryDelay(policy config.RetryConfig, attempt int, err error) time.Duration {
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		return se.RetryAfter
+	}
+
+	delay := policy.BaseDelay << min(attempt-2, 30)
+	if delay <= 0 || (policy.MaxDelay > 0 && delay > policy.MaxDelay) {
+		delay = policy.MaxDelay
+	}
+	if policy.Jitter > 0 {
+		spread := float64(delay) * policy.Jitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	return max(delay, 0)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as
+// an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(max(secs, 0)) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// SuggestNextAction returns a short hint telling the user what they can do
+// about err, or "" when there is nothing useful to suggest.
+func SuggestNextAction(err error) string {
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch {
+		case se.StatusCode == http.StatusTooManyRequests:
+			return "Rate limited by the provider. Wait a minute and retry, or switch to another model with /model."
+		case se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden:
+			return "Authentication failed. Check the API key for this provider (api_key, api_key_env or api_key_command) with /config."
+		case se.StatusCode >= 500:
+			return "The provider is having trouble. Retry later, or switch to another model with /model."
+		}
+		return ""
+	}
+	var te *TimeoutError
+	if errors.As(err, &te) {
+		if te.Phase == TimeoutConnect {
+			return fmt.Sprintf("Could not reach the provider. Check your network and base_url, raise %s, or switch to another model with /model.", te.ConfigKey())
+		}
+		return fmt.Sprintf("Retry (/retry) to resend from the last completed message, raise %s if the model is just slow, or switch to another model with /model.", te.ConfigKey())
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return "The request timed out. Retry, raise the provider timeout, or switch to another model with /model."
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return "Could not reach the provider. Check your network and base_url, or switch to another model with /model."
+	}
+	return ""
+}
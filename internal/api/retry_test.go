@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/cassette"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newRetryTestClient(baseURL string, retry config.RetryConfig) *Client {
+	return NewClient(
+		&config.ProviderConfig{
+			Type:    config.ProviderTypeOpenAICompat,
+			BaseURL: baseURL,
+			Timeout: 5 * time.Second,
+			Retry:   retry,
+		},
+		&config.ModelConfig{Model: "test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+}
+
+var fastRetry = config.RetryConfig{
+	MaxAttempts:   3,
+	BaseDelay:     time.Millisecond,
+	MaxDelay:      5 * time.Millisecond,
+	RetryOnStatus: []int{429, 503},
+}
+
+func TestPostJSONRetriesRetryableStatus(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"error":{"message":"slow down","type":"rate_limit"}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"- summary"}}]}`)
+	}))
+	defer server.Close()
+
+	var infos []RetryInfo
+	ctx := WithRetryNotifier(context.Background(), func(info RetryInfo) {
+		infos = append(infos, info)
+	})
+	summary, err := newRetryTestClient(server.URL, fastRetry).Summarize(ctx, []Message{NewUserMessage("hi")})
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if summary != "- summary" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+	if len(infos) != 2 || infos[0].Attempt != 2 || infos[1].Attempt != 3 || infos[1].MaxAttempts != 3 {
+		t.Fatalf("unexpected retry notifications: %+v", infos)
+	}
+	if !strings.HasPrefix(infos[0].String(), "Retrying in ") || !strings.HasSuffix(infos[0].String(), "(attempt 2/3)") {
+		t.Fatalf("unexpected retry status line: %q", infos[0].String())
+	}
+}
+
+func TestPostJSONGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := newRetryTestClient(server.URL, fastRetry).StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	var exhausted *RetryExhaustedError
+	if !errors.As(err, &exhausted) || exhausted.Attempts != 3 {
+		t.Fatalf("expected RetryExhaustedError after 3 attempts, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+	if SuggestNextAction(err) == "" {
+		t.Fatal("expected a next-action hint for a 503")
+	}
+}
+
+func TestPostJSONDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = io.WriteString(w, `{"error":{"message":"bad","type":"invalid_request_error"}}`)
+	}))
+	defer server.Close()
+
+	_, err := newRetryTestClient(server.URL, fastRetry).StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected StatusError 400, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single call, got %d", calls.Load())
+	}
+}
+
+func TestPostJSONRetriesConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	_, err := newRetryTestClient(url, fastRetry).Summarize(context.Background(), []Message{NewUserMessage("hi")})
+	var exhausted *RetryExhaustedError
+	if !errors.As(err, &exhausted) || exhausted.Attempts != 3 {
+		t.Fatalf("expected RetryExhaustedError after 3 attempts, got %v", err)
+	}
+}
+
+func TestPostJSONDoesNotRetrySetupErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		&config.ProviderConfig{
+			Type:    config.ProviderTypeOpenAICompat,
+			BaseURL: server.URL,
+			CAFile:  filepath.Join(t.TempDir(), "missing.pem"),
+			Retry:   fastRetry,
+		},
+		&config.ModelConfig{Model: "test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+	var retries int
+	ctx := WithRetryNotifier(context.Background(), func(RetryInfo) { retries++ })
+	_, err := client.Summarize(ctx, []Message{NewUserMessage("hi")})
+	var setupErr *SetupError
+	if !errors.As(err, &setupErr) || !strings.Contains(err.Error(), "ca_file") {
+		t.Fatalf("expected a SetupError about ca_file, got %v", err)
+	}
+	if retries != 0 || calls.Load() != 0 {
+		t.Fatalf("retries = %d, calls = %d, want none", retries, calls.Load())
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	refused := &url.Error{Op: "Post", URL: "http://127.0.0.1:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"retryable status", &StatusError{StatusCode: 503}, true},
+		{"other status", &StatusError{StatusCode: 400}, false},
+		{"connection refused", fmt.Errorf("send request: %w", refused), true},
+		{"timeout", &TimeoutError{Phase: TimeoutFirstToken, Limit: time.Second}, true},
+		{"unexpected EOF", fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"setup", &SetupError{Err: errors.New("environment variable X (api_key_env) is not set")}, false},
+		{"setup through transport", fmt.Errorf("send request: %w", &url.Error{Op: "Post", Err: &SetupError{Err: errors.New("read ca_file")}}), false},
+		{"missing from cassette", &url.Error{Op: "Post", Err: &cassette.NotFoundError{Method: "POST"}}, false},
+		{"cancelled", context.Canceled, false},
+		{"other", errors.New("decode response"), false},
+	} {
+		if got := isRetryable(fastRetry, tc.err); got != tc.want {
+			t.Errorf("%s: isRetryable = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := config.RetryConfig{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{2: time.Second, 3: 2 * time.Second, 4: 4 * time.Second, 5: 5 * time.Second, 80: 5 * time.Second} {
+		if got := retryDelay(policy, attempt, errors.New("x")); got != want {
+			t.Errorf("attempt %d: got %s want %s", attempt, got, want)
+		}
+	}
+	withRetryAfter := &StatusError{StatusCode: 429, RetryAfter: 42 * time.Second}
+	if got := retryDelay(policy, 2, withRetryAfter); got != 42*time.Second {
+		t.Errorf("Retry-After should win, got %s", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Fatalf("seconds: got %s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got <= 0 || got > time.Minute {
+		t.Fatalf("http date: got %s", got)
+	}
+	if got := parseRetryAfter("soon"); got != 0 {
+		t.Fatalf("garbage: got %s", got)
+	}
+}
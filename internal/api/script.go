@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// scriptFile is a script provider's file in YAML. A .jsonl script holds one
+// scriptTurn per line instead, without the other settings.
+type scriptFile struct {
+	// Summary answers summarization requests.
+	Summary string `yaml:"summary"`
+	// ChunkDelay pauses between streamed chunks, e.g. "30ms" for demos.
+	ChunkDelay string       `yaml:"chunk_delay"`
+	Turns      []scriptTurn `yaml:"turns"`
+}
+
+// scriptTurn is one scripted assistant response.
+type scriptTurn struct {
+	// Match restricts the turn to requests whose last user message contains
+	// it, or matches it as a regular expression when written as /regexp/.
+	Match     string           `yaml:"match" json:"match"`
+	Reasoning string           `yaml:"reasoning" json:"reasoning"`
+	Content   string           `yaml:"content" json:"content"`
+	ToolCalls []scriptToolCall `yaml:"tool_calls" json:"tool_calls"`
+	// Error fails the request with this message instead.
+	Error string `yaml:"error" json:"error"`
+
+	re *regexp.Regexp
+}
+
+type scriptToolCall struct {
+	Name string `yaml:"name" json:"name"`
+	// Arguments is a mapping, or a string of JSON.
+	Arguments any `yaml:"arguments" json:"arguments"`
+}
+
+// defaultScriptSummary answers summarization without a summary in the script.
+const defaultScriptSummary = "The conversation so far was scripted."
+
+// loadScript reads a script file.
+func loadScript(path string) (*scriptFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script scriptFile
+	if filepath.Ext(path) == ".jsonl" {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var turn scriptTurn
+			if err := json.Unmarshal(scanner.Bytes(), &turn); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			script.Turns = append(script.Turns, turn)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	for i := range script.Turns {
+		turn := &script.Turns[i]
+		if pattern, ok := strings.CutPrefix(turn.Match, "/"); ok && len(pattern) > 0 && strings.HasSuffix(pattern, "/") {
+			if turn.re, err = regexp.Compile(strings.TrimSuffix(pattern, "/")); err != nil {
+				return nil, fmt.Errorf("%s: turn %d: match: %w", path, i+1, err)
+			}
+		}
+		for _, tc := range turn.ToolCalls {
+			if tc.Name == "" {
+				return nil, fmt.Errorf("%s: turn %d: tool call without a name", path, i+1)
+			}
+		}
+	}
+	return &script, nil
+}
+
+func (t *scriptTurn) matches(prompt string) bool {
+	if t.re != nil {
+		return t.re.MatchString(prompt)
+	}
+	return strings.Contains(prompt, t.Match)
+}
+
+// scriptProvider answers from a script instead of the network: each request
+// gets the first unused turn whose match is empty or fits the last user
+// message. Tool calls get IDs unique to the client.
+type scriptProvider struct {
+	c          *Client
+	script     *scriptFile
+	chunkDelay time.Duration
+	err        error // from loading the script, reported on every request
+
+	mu    sync.Mutex
+	used  []bool
+	calls int
+}
+
+func newScriptProvider(c *Client) *scriptProvider {
+	p := &scriptProvider{c: c}
+	p.script, p.err = loadScript(c.providerCfg.Script)
+	if p.err == nil && p.script.ChunkDelay != "" {
+		p.chunkDelay, p.err = time.ParseDuration(p.script.ChunkDelay)
+	}
+	if p.err != nil {
+		// NewClient cannot fail; surface the problem on the first request.
+		slog.Error("Failed to load script", "path", c.providerCfg.Script, "error", p.err)
+		return p
+	}
+	p.used = make([]bool, len(p.script.Turns))
+	slog.Info("Answering from script", "path", c.providerCfg.Script, "turns", len(p.script.Turns))
+	return p
+}
+
+func (p *scriptProvider) defaultBaseURL() string {
+	return ""
+}
+
+func (p *scriptProvider) setAuth(*http.Request, string) {}
+
+// nextTurn takes the turn answering messages.
+func (p *scriptProvider) nextTurn(messages []Message) (scriptTurn, error) {
+	if p.err != nil {
+		return scriptTurn{}, fmt.Errorf("script: %w", p.err)
+	}
+	var prompt string
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			prompt = messages[i].Content
+			break
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, turn := range p.script.Turns {
+		if !p.used[i] && turn.matches(prompt) {
+			p.used[i] = true
+			if turn.Error != "" {
+				return scriptTurn{}, errors.New(turn.Error)
+			}
+			return turn, nil
+		}
+	}
+	return scriptTurn{}, fmt.Errorf("script %s has no turn left for %q", p.c.providerCfg.Script, prompt)
+}
+
+func (p *scriptProvider) streamChat(ctx context.Context, messages []Message, _ []Tool) (<-chan StreamEvent, error) {
+	turn, err := p.nextTurn(messages)
+	if err != nil {
+		return nil, err
+	}
+	var toolCalls []ToolCall
+	for i, tc := range turn.ToolCalls {
+		args, err := scriptArguments(tc.Arguments)
+		if err != nil {
+			return nil, fmt.Errorf("script: tool call %s: %w", tc.Name, err)
+		}
+		p.mu.Lock()
+		p.calls++
+		id := fmt.Sprintf("call_script_%d", p.calls)
+		p.mu.Unlock()
+		toolCalls = append(toolCalls, ToolCall{
+			Index:    i,
+			ID:       id,
+			Type:     "function",
+			Function: FunctionCall{Name: tc.Name, Arguments: args},
+		})
+	}
+
+	var chunks []Message
+	for _, word := range strings.SplitAfter(turn.Reasoning, " ") {
+		if word != "" {
+			chunks = append(chunks, Message{Role: "assistant", ReasoningContent: word})
+		}
+	}
+	for _, word := range strings.SplitAfter(turn.Content, " ") {
+		if word != "" {
+			chunks = append(chunks, Message{Role: "assistant", Content: word})
+		}
+	}
+	if len(toolCalls) > 0 {
+		chunks = append(chunks, Message{Role: "assistant", ToolCalls: toolCalls})
+	}
+	finish := "stop"
+	if len(toolCalls) > 0 {
+		finish = "tool_calls"
+	}
+	usage := scriptUsage(messages, turn, toolCalls)
+
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		send := func(resp StreamResponse) bool {
+			select {
+			case out <- StreamEvent{Data: &resp}:
+			case <-ctx.Done():
+				return false
+			}
+			if p.chunkDelay > 0 {
+				select {
+				case <-time.After(p.chunkDelay):
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		for _, delta := range chunks {
+			if !send(StreamResponse{Model: p.c.modelCfg.Model, Choices: []Choice{{Delta: delta}}}) {
+				return
+			}
+		}
+		send(StreamResponse{Model: p.c.modelCfg.Model, Choices: []Choice{{FinishReason: finish}}, Usage: usage})
+	}()
+	return out, nil
+}
+
+func (p *scriptProvider) summarize(_ context.Context, messages []Message) (string, *Usage, error) {
+	if p.err != nil {
+		return "", nil, fmt.Errorf("script: %w", p.err)
+	}
+	summary := p.script.Summary
+	if summary == "" {
+		summary = defaultScriptSummary
+	}
+	return summary, scriptUsage(messages, scriptTurn{Content: summary}, nil), nil
+}
+
+func (p *scriptProvider) listModels(context.Context) ([]string, error) {
+	return nil, errors.New("script providers do not list models")
+}
+
+// scriptArguments renders a tool call's arguments as a JSON string.
+func scriptArguments(args any) (string, error) {
+	switch v := args.(type) {
+	case nil:
+		return "{}", nil
+	case string:
+		return v, nil
+	default:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+}
+
+// scriptUsage estimates token counts at four characters per token, so usage
+// display and cost accounting have something to show.
+func scriptUsage(messages []Message, turn scriptTurn, toolCalls []ToolCall) *Usage {
+	prompt := 0
+	for _, m := range messages {
+		prompt += len(m.Content)
+	}
+	completion := len(turn.Reasoning) + len(turn.Content)
+	for _, tc := range toolCalls {
+		completion += len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	u := &Usage{PromptTokens: prompt / 4, CompletionTokens: completion / 4}
+	u.TotalTokens = u.PromptTokens + u.CompletionTokens
+	return u
+}
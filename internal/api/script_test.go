@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newScriptTestClient(t *testing.T, name, script string) *Client {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(script), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	prov := &config.ProviderConfig{Type: config.ProviderTypeScript, Script: path}
+	return NewClient(prov, &config.ModelConfig{Model: "scripted"}, &config.ContextConfig{MaxTokens: 4096})
+}
+
+// scriptReply streams one response and returns its content and tool calls.
+func scriptReply(t *testing.T, c *Client, prompt string) (string, []ToolCall, error) {
+	t.Helper()
+	stream, err := c.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage(prompt)}, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	var content strings.Builder
+	var calls []ToolCall
+	for ev := range stream {
+		if ev.Error != nil {
+			return "", nil, ev.Error
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+			calls = append(calls, ch.Delta.ToolCalls...)
+		}
+	}
+	return content.String(), calls, nil
+}
+
+func TestScriptProviderMatchesLastUserMessage(t *testing.T) {
+	c := newScriptTestClient(t, "demo.yaml", `
+turns:
+  - match: hello
+    content: Hi there!
+  - match: /^read (\S+)$/
+    tool_calls:
+      - name: read_file
+        arguments: {path: README.md}
+  - content: It is a README.
+  - match: fail
+    error: scripted failure
+`)
+
+	calls := []struct{ prompt, content string }{
+		{"read README.md", ""},
+		{"read README.md", "It is a README."},
+		{"hello", "Hi there!"},
+	}
+	for i, call := range calls {
+		content, toolCalls, err := scriptReply(t, c, call.prompt)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if content != call.content {
+			t.Fatalf("call %d: content = %q, want %q", i, content, call.content)
+		}
+		if i == 0 {
+			if len(toolCalls) != 1 || toolCalls[0].Function.Name != "read_file" ||
+				toolCalls[0].Function.Arguments != `{"path":"README.md"}` || toolCalls[0].ID == "" {
+				t.Fatalf("tool calls = %+v", toolCalls)
+			}
+		}
+	}
+
+	if _, _, err := scriptReply(t, c, "fail now"); err == nil || err.Error() != "scripted failure" {
+		t.Fatalf("err = %v, want the scripted error", err)
+	}
+	if _, _, err := scriptReply(t, c, "hello"); err == nil || !strings.Contains(err.Error(), "no turn left") {
+		t.Fatalf("err = %v, want an exhausted script", err)
+	}
+}
+
+func TestScriptProviderJSONL(t *testing.T) {
+	c := newScriptTestClient(t, "demo.jsonl", `{"content":"one"}
+
+{"tool_calls":[{"name":"list_directory","arguments":"{\"path\":\".\"}"}]}
+`)
+	if content, _, err := scriptReply(t, c, "x"); err != nil || content != "one" {
+		t.Fatalf("content = %q, err = %v", content, err)
+	}
+	_, calls, err := scriptReply(t, c, "x")
+	if err != nil || len(calls) != 1 || calls[0].Function.Arguments != `{"path":"."}` {
+		t.Fatalf("calls = %+v, err = %v", calls, err)
+	}
+	if summary, err := c.Summarize(context.Background(), []Message{NewUserMessage("x")}); err != nil || summary != defaultScriptSummary {
+		t.Fatalf("summary = %q, err = %v", summary, err)
+	}
+}
+
+func TestScriptProviderReportsLoadErrors(t *testing.T) {
+	c := newScriptTestClient(t, "bad.yaml", "turns:\n  - match: /[/\n")
+	if _, _, err := scriptReply(t, c, "x"); err == nil || !strings.Contains(err.Error(), "match") {
+		t.Fatalf("err = %v, want the bad pattern reported", err)
+	}
+}
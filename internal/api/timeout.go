@@ -0,0 +1,159 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Phases a TimeoutError can report.
+const (
+	TimeoutConnect    = "connect"
+	TimeoutFirstToken = "first token"
+	TimeoutIdle       = "idle"
+)
+
+// TimeoutError reports that one of the provider's timeouts expired.
+type TimeoutError struct {
+	Phase string // TimeoutConnect, TimeoutFirstToken or TimeoutIdle
+	Limit time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	switch e.Phase {
+	case TimeoutConnect:
+		return fmt.Sprintf("could not connect within %s (connect_timeout)", e.Limit)
+	case TimeoutFirstToken:
+		return fmt.Sprintf("no response within %s (first_token_timeout)", e.Limit)
+	default:
+		return fmt.Sprintf("stream stalled: no data for %s (idle_timeout)", e.Limit)
+	}
+}
+
+// Timeout reports true, like the net package's timeout errors.
+func (e *TimeoutError) Timeout() bool { return true }
+
+// ConfigKey is the provider setting that controls this timeout.
+func (e *TimeoutError) ConfigKey() string {
+	switch e.Phase {
+	case TimeoutConnect:
+		return "connect_timeout"
+	case TimeoutFirstToken:
+		return "first_token_timeout"
+	default:
+		return "idle_timeout"
+	}
+}
+
+// watchdog cancels a streaming request when it is not fed in time. A zero
+// limit disables it.
+type watchdog struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel context.CancelCauseFunc
+}
+
+func newWatchdog(limit time.Duration, phase string, cancel context.CancelCauseFunc) *watchdog {
+	w := &watchdog{cancel: cancel}
+	w.reset(limit, phase)
+	return w
+}
+
+// reset restarts the countdown with a new limit and phase.
+func (w *watchdog) reset(limit time.Duration, phase string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	if limit <= 0 {
+		return
+	}
+	w.timer = time.AfterFunc(limit, func() {
+		w.cancel(&TimeoutError{Phase: phase, Limit: limit})
+	})
+}
+
+func (w *watchdog) stop() { w.reset(0, "") }
+
+// timeoutCause replaces err with the TimeoutError that cancelled ctx, if any,
+// and marks dial timeouts as connect timeouts.
+func timeoutCause(ctx context.Context, err error, connectTimeout time.Duration) error {
+	var te *TimeoutError
+	if errors.As(context.Cause(ctx), &te) {
+		return te
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" && opErr.Timeout() {
+		return &TimeoutError{Phase: TimeoutConnect, Limit: connectTimeout}
+	}
+	return err
+}
+
+// watchedBody feeds the watchdog on every read of a streaming response and
+// turns the cancellation it causes into the TimeoutError.
+type watchedBody struct {
+	ctx    context.Context
+	r      *bufio.Reader
+	body   io.ReadCloser
+	dog    *watchdog
+	idle   time.Duration
+	cancel context.CancelCauseFunc
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if n > 0 {
+		b.dog.reset(b.idle, TimeoutIdle)
+	}
+	if err != nil && err != io.EOF {
+		var te *TimeoutError
+		if errors.As(context.Cause(b.ctx), &te) {
+			err = te
+		}
+	}
+	return n, err
+}
+
+func (b *watchedBody) Close() error {
+	b.dog.stop()
+	err := b.body.Close()
+	b.cancel(nil)
+	return err
+}
+
+// postStreamOnce sends a single streaming POST attempt. Unlike post it waits
+// for the first byte of the body, so a provider that accepts the request but
+// never starts answering fails here, where the attempt can still be retried,
+// rather than in the middle of the caller's stream.
+func (c *Client) postStreamOnce(ctx context.Context, path string, body []byte) (*http.Response, error) {
+	cfg := c.providerCfg
+	attemptCtx, cancel := context.WithCancelCause(ctx)
+	dog := newWatchdog(cfg.FirstTokenTimeout, TimeoutFirstToken, cancel)
+	fail := func(err error) (*http.Response, error) {
+		dog.stop()
+		err = timeoutCause(attemptCtx, err, cfg.ConnectTimeout)
+		cancel(nil)
+		return nil, err
+	}
+
+	resp, err := c.send(attemptCtx, c.streamClient, path, body)
+	if err != nil {
+		return fail(err)
+	}
+	r := bufio.NewReader(resp.Body)
+	if _, err := r.Peek(1); err != nil && err != io.EOF {
+		closeBody(resp)
+		return fail(fmt.Errorf("read response: %w", err))
+	}
+	dog.reset(cfg.IdleTimeout, TimeoutIdle)
+	resp.Body = &watchedBody{ctx: attemptCtx, r: r, body: resp.Body, dog: dog, idle: cfg.IdleTimeout, cancel: cancel}
+	return resp, nil
+}
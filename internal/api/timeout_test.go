@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func newTimeoutTestClient(baseURL string, cfg config.ProviderConfig) *Client {
+	cfg.Type = config.ProviderTypeOpenAICompat
+	cfg.BaseURL = baseURL
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = config.RetryConfig{MaxAttempts: 1}
+	}
+	return NewClient(&cfg, &config.ModelConfig{Model: "test"}, &config.ContextConfig{MaxTokens: 4096})
+}
+
+func writeChunk(w http.ResponseWriter, content string) {
+	_, _ = fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", content)
+	w.(http.Flusher).Flush()
+}
+
+// stall blocks until the client gives up on the request. The body has to be
+// read first, or the server does not notice the client going away.
+func stall(r *http.Request) {
+	_, _ = io.Copy(io.Discard, r.Body)
+	<-r.Context().Done()
+}
+
+func collectStream(t *testing.T, stream <-chan StreamEvent) (string, error) {
+	t.Helper()
+	var content strings.Builder
+	var streamErr error
+	for ev := range stream {
+		if ev.Error != nil {
+			streamErr = ev.Error
+			continue
+		}
+		for _, ch := range ev.Data.Choices {
+			content.WriteString(ch.Delta.Content)
+		}
+	}
+	return content.String(), streamErr
+}
+
+func TestStreamFirstTokenTimeoutIsRetried(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			stall(r) // headers, then nothing
+			return
+		}
+		writeChunk(w, "hello")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	client := newTimeoutTestClient(server.URL, config.ProviderConfig{
+		FirstTokenTimeout: 50 * time.Millisecond,
+		Retry:             config.RetryConfig{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	})
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+	content, err := collectStream(t, stream)
+	if err != nil || content != "hello" {
+		t.Fatalf("content = %q, err = %v", content, err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("attempts = %d, want 2", got)
+	}
+}
+
+func TestStreamFirstTokenTimeoutReportsPhase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stall(r) // never answers, not even headers
+	}))
+	defer server.Close()
+
+	client := newTimeoutTestClient(server.URL, config.ProviderConfig{FirstTokenTimeout: 50 * time.Millisecond})
+	_, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Phase != TimeoutFirstToken {
+		t.Fatalf("err = %v, want a first token TimeoutError", err)
+	}
+	if !strings.Contains(err.Error(), "first_token_timeout") {
+		t.Fatalf("error should name the setting: %v", err)
+	}
+}
+
+func TestStreamIdleTimeoutEndsStalledStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		writeChunk(w, "partial")
+		stall(r)
+	}))
+	defer server.Close()
+
+	client := newTimeoutTestClient(server.URL, config.ProviderConfig{IdleTimeout: 50 * time.Millisecond})
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+	content, err := collectStream(t, stream)
+	var te *TimeoutError
+	if !errors.As(err, &te) || te.Phase != TimeoutIdle {
+		t.Fatalf("err = %v, want an idle TimeoutError", err)
+	}
+	if content != "partial" {
+		t.Fatalf("content = %q", content)
+	}
+	if hint := SuggestNextAction(err); !strings.Contains(hint, "/retry") || !strings.Contains(hint, "idle_timeout") {
+		t.Fatalf("hint = %q", hint)
+	}
+}
+
+func TestStreamOutlivesRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := range 5 {
+			writeChunk(w, fmt.Sprint(i))
+			time.Sleep(30 * time.Millisecond)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	// The whole stream takes longer than Timeout, but no gap exceeds
+	// IdleTimeout.
+	client := newTimeoutTestClient(server.URL, config.ProviderConfig{
+		Timeout:     50 * time.Millisecond,
+		IdleTimeout: time.Second,
+	})
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatalf("StreamChatCompletionWithTools: %v", err)
+	}
+	content, err := collectStream(t, stream)
+	if err != nil || content != "01234" {
+		t.Fatalf("content = %q, err = %v", content, err)
+	}
+}
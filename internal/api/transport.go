@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// baseTransport returns the network transport for a provider: the default
+// transport, or a copy of it with the provider's connect timeout, proxy and
+// TLS settings.
+func baseTransport(cfg *config.ProviderConfig) (http.RoundTripper, error) {
+	if cfg.ConnectTimeout == 0 && cfg.Proxy == "" && cfg.CAFile == "" && !cfg.InsecureSkipVerify {
+		return http.DefaultTransport, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: cfg.ConnectTimeout, KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+		t.TLSHandshakeTimeout = cfg.ConnectTimeout
+	}
+
+	switch cfg.Proxy {
+	case "":
+	case config.ProxyDirect:
+		t.Proxy = nil
+	default:
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if cfg.CAFile != "" || cfg.InsecureSkipVerify {
+		tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			pem, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read ca_file: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca_file %s contains no PEM certificates", cfg.CAFile)
+			}
+			tlsCfg.RootCAs = pool
+		}
+		if cfg.InsecureSkipVerify {
+			slog.Warn("TLS certificate verification is disabled for this provider")
+			tlsCfg.InsecureSkipVerify = true
+		}
+		t.TLSClientConfig = tlsCfg
+	}
+	return t, nil
+}
+
+// setAuth authenticates req according to the provider's auth_scheme and adds
+// its custom headers.
+func (c *Client) setAuth(req *http.Request, apiKey string) {
+	switch scheme := c.providerCfg.AuthScheme; {
+	case scheme == "":
+		c.provider.setAuth(req, apiKey)
+	case scheme == config.AuthSchemeBearer:
+		c.provider.setAuth(req, "")
+		if apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+	case c.providerCfg.AuthHeader() != "":
+		c.provider.setAuth(req, "")
+		if apiKey != "" {
+			req.Header.Set(c.providerCfg.AuthHeader(), apiKey)
+		}
+	default: // config.AuthSchemeNone
+		c.provider.setAuth(req, "")
+	}
+	for name, value := range c.providerCfg.Headers {
+		req.Header.Set(name, value)
+	}
+}
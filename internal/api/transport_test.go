@@ -0,0 +1,114 @@
+package api
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+const okCompletion = `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`
+
+func newTransportTestClient(prov config.ProviderConfig) *Client {
+	prov.Timeout = 5 * time.Second
+	prov.Retry = config.RetryConfig{MaxAttempts: 1}
+	return NewClient(&prov, &config.ModelConfig{Model: "test"}, &config.ContextConfig{MaxTokens: 4096})
+}
+
+func summarizeOnce(c *Client) error {
+	_, err := c.Summarize(context.Background(), []Message{NewUserMessage("hi")})
+	return err
+}
+
+func TestAuthSchemesAndHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		prov   config.ProviderConfig
+		header string
+		want   string
+		absent string
+	}{
+		{"default", config.ProviderConfig{}, "Authorization", "Bearer sk-test", ""},
+		{"header", config.ProviderConfig{AuthScheme: "header:api-key"}, "Api-Key", "sk-test", "Authorization"},
+		{"bearer on anthropic", config.ProviderConfig{Type: config.ProviderTypeAnthropic, AuthScheme: "bearer"}, "Authorization", "Bearer sk-test", "X-Api-Key"},
+		{"none", config.ProviderConfig{AuthScheme: "none"}, "X-Org-Id", "acme", "Authorization"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				http.Error(w, "stop", http.StatusTeapot)
+			}))
+			defer server.Close()
+
+			prov := tt.prov
+			prov.BaseURL = server.URL
+			prov.APIKey = "sk-test"
+			prov.Headers = map[string]string{"X-Org-Id": "acme"}
+			_ = summarizeOnce(newTransportTestClient(prov))
+
+			if got.Get(tt.header) != tt.want {
+				t.Fatalf("%s = %q, want %q (headers %v)", tt.header, got.Get(tt.header), tt.want, got)
+			}
+			if got.Get("X-Org-Id") != "acme" {
+				t.Fatalf("custom header missing: %v", got)
+			}
+			if tt.absent != "" && got.Get(tt.absent) != "" {
+				t.Fatalf("%s should not be sent: %v", tt.absent, got)
+			}
+		})
+	}
+}
+
+func TestCAFileAndInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, okCompletion)
+	}))
+	defer server.Close()
+
+	if err := summarizeOnce(newTransportTestClient(config.ProviderConfig{BaseURL: server.URL})); err == nil {
+		t.Fatal("expected a certificate error without ca_file")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := summarizeOnce(newTransportTestClient(config.ProviderConfig{BaseURL: server.URL, CAFile: caFile})); err != nil {
+		t.Fatalf("with ca_file: %v", err)
+	}
+	if err := summarizeOnce(newTransportTestClient(config.ProviderConfig{BaseURL: server.URL, InsecureSkipVerify: true})); err != nil {
+		t.Fatalf("with insecure_skip_verify: %v", err)
+	}
+
+	missing := newTransportTestClient(config.ProviderConfig{BaseURL: server.URL, CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	if err := summarizeOnce(missing); err == nil {
+		t.Fatal("expected an error for a missing ca_file")
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		_, _ = io.WriteString(w, okCompletion)
+	}))
+	defer proxy.Close()
+
+	c := newTransportTestClient(config.ProviderConfig{BaseURL: "http://llm.internal.example/v1", Proxy: proxy.URL})
+	if err := summarizeOnce(c); err != nil {
+		t.Fatalf("Summarize via proxy: %v", err)
+	}
+	if proxied != "http://llm.internal.example/v1/chat/completions" {
+		t.Fatalf("proxy saw %q", proxied)
+	}
+}
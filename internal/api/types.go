@@ -1,15 +1,13 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 )
 
-// ResponseFormat specifies the output format for the model response.
-type ResponseFormat struct {
-	Type string `json:"type"`
-}
-
 // ChatCompletionRequest represents a chat completion API request
 type ChatCompletionRequest struct {
 	Model             string          `json:"model"`
@@ -44,6 +42,66 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string     `json:"tool_call_id,omitempty"`
+	// ReasoningItems holds opaque reasoning output items returned by the
+	// OpenAI Responses API. They are persisted with the session and sent back
+	// verbatim on later turns; other providers never see them.
+	ReasoningItems []json.RawMessage `json:"reasoning_items,omitempty"`
+	// ReasoningContent is the model's thinking, streamed separately from
+	// Content (reasoning_content deltas, Anthropic thinking blocks, Gemini
+	// thought parts). It is shown but only kept in history for models with
+	// echo_reasoning, whose APIs require it back on later turns.
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+	// Model is the "provider/model" that produced an assistant message. It
+	// is only recorded in sessions and never sent to providers.
+	Model string `json:"model,omitempty"`
+	// Parts is the multimodal content of the message. When set it is sent
+	// instead of Content, and Content holds its text parts so code that only
+	// deals with text keeps working; use AttachImages to keep them in sync.
+	Parts []ContentPart `json:"-"`
+}
+
+// Content part types.
+const (
+	ContentPartText  = "text"
+	ContentPartImage = "image_url"
+)
+
+// ContentPart is one element of a multimodal message, in the chat
+// completions format.
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL. Local images are embedded as
+// "data:<mime type>;base64,<data>" URLs.
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AttachImages appends image parts to the message, converting its text
+// content into a text part first.
+func (m *Message) AttachImages(images ...ContentPart) {
+	if len(images) == 0 {
+		return
+	}
+	if len(m.Parts) == 0 && m.Content != "" {
+		m.Parts = []ContentPart{{Type: ContentPartText, Text: m.Content}}
+	}
+	m.Parts = append(m.Parts, images...)
+}
+
+// Images returns the image parts of the message.
+func (m *Message) Images() []ContentPart {
+	var images []ContentPart
+	for _, p := range m.Parts {
+		if p.Type == ContentPartImage && p.ImageURL != nil {
+			images = append(images, p)
+		}
+	}
+	return images
 }
 
 // Tool represents a function that can be called by the model
@@ -106,9 +164,36 @@ type Choice struct {
 
 // Usage tracks token usage
 type Usage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                  `json:"prompt_tokens"`
+	CompletionTokens    int                  `json:"completion_tokens"`
+	TotalTokens         int                  `json:"total_tokens"`
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	// PromptCacheHitTokens is DeepSeek's name for the cached prompt tokens.
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens,omitempty"`
+}
+
+// PromptTokensDetails breaks down Usage.PromptTokens.
+type PromptTokensDetails struct {
+	// CachedTokens is the part of the prompt served from the provider's
+	// prompt cache; it is included in PromptTokens.
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CachedTokens returns the number of prompt tokens read from cache.
+func (u *Usage) CachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return u.PromptCacheHitTokens
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
+
+// cachedDetails returns PromptTokensDetails for n cached tokens, or nil
+// when nothing was cached.
+func cachedDetails(n int) *PromptTokensDetails {
+	if n == 0 {
+		return nil
+	}
+	return &PromptTokensDetails{CachedTokens: n}
 }
 
 // StreamResponse represents a streaming response chunk
@@ -137,6 +222,9 @@ type ToolResult struct {
 	ToolCallID string `json:"tool_call_id"`
 	Output     string `json:"output"`
 	Error      error  `json:"error,omitempty"`
+	// Images are image parts produced by the tool (e.g. read_file on a PNG).
+	// They are attached to the tool message only for vision-capable models.
+	Images []ContentPart `json:"images,omitempty"`
 }
 
 // MarshalJSON customizes JSON serialization of Message.
@@ -147,23 +235,82 @@ type ToolResult struct {
 func (m Message) MarshalJSON() ([]byte, error) {
 	// Use a separate type to avoid infinite recursion.
 	type plain struct {
-		Role       string     `json:"role"`
-		Content    *string    `json:"content"`
-		ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-		ToolCallID string     `json:"tool_call_id,omitempty"`
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCalls  []ToolCall      `json:"tool_calls,omitempty"`
+		ToolCallID string          `json:"tool_call_id,omitempty"`
+
+		ReasoningItems   []json.RawMessage `json:"reasoning_items,omitempty"`
+		ReasoningContent string            `json:"reasoning_content,omitempty"`
+		Model            string            `json:"model,omitempty"`
 	}
 	p := plain{
-		Role:       m.Role,
-		ToolCalls:  m.ToolCalls,
-		ToolCallID: m.ToolCallID,
+		Role:             m.Role,
+		ToolCalls:        m.ToolCalls,
+		ToolCallID:       m.ToolCallID,
+		ReasoningItems:   m.ReasoningItems,
+		ReasoningContent: m.ReasoningContent,
+		Model:            m.Model,
 	}
-	if m.Content != "" || len(m.ToolCalls) == 0 {
-		p.Content = &m.Content
+	if len(m.Parts) > 0 {
+		parts, err := json.Marshal(m.Parts)
+		if err != nil {
+			return nil, err
+		}
+		p.Content = parts
+	} else if m.Content != "" || len(m.ToolCalls) == 0 {
+		content, err := json.Marshal(m.Content)
+		if err != nil {
+			return nil, err
+		}
+		p.Content = content
 	}
 	// else: Content stays nil → serialized as null
 	return json.Marshal(p)
 }
 
+// UnmarshalJSON accepts content as a string, null, or an array of content
+// parts; for arrays, Content is set to the concatenated text parts. Thinking
+// sent as "reasoning" (OpenRouter, newer vLLM) lands in ReasoningContent.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	var aux struct {
+		plain
+		Content   json.RawMessage `json:"content"`
+		Reasoning string          `json:"reasoning"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*m = Message(aux.plain)
+	m.Content = ""
+	m.Parts = nil
+	if m.ReasoningContent == "" {
+		m.ReasoningContent = aux.Reasoning
+	}
+
+	content := bytes.TrimSpace(aux.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+	case content[0] == '[':
+		if err := json.Unmarshal(content, &m.Parts); err != nil {
+			return fmt.Errorf("parse content parts: %w", err)
+		}
+		var text strings.Builder
+		for _, part := range m.Parts {
+			if part.Type == ContentPartText {
+				text.WriteString(part.Text)
+			}
+		}
+		m.Content = text.String()
+	default:
+		if err := json.Unmarshal(content, &m.Content); err != nil {
+			return fmt.Errorf("parse content: %w", err)
+		}
+	}
+	return nil
+}
+
 // Helper function to create a user message
 func NewUserMessage(content string) Message {
 	return Message{
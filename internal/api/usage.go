@@ -0,0 +1,61 @@
+package api
+
+import (
+	"log/slog"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// UsageReport is the token usage of one completed API request.
+type UsageReport struct {
+	Model   string          // Client.Name() of the client that answered
+	Pricing *config.Pricing // nil when the model has no pricing configured
+	Usage   Usage
+}
+
+// SetUsageObserver makes the client call fn with the usage of every request
+// made through it or its fallbacks: streamed completions, Summarize calls,
+// and subagent runs sharing the client. fn may be called from any goroutine.
+func (c *Client) SetUsageObserver(fn func(UsageReport)) {
+	c.usageObserver = fn
+	for _, fb := range c.fallbacks {
+		fb.usageObserver = fn
+	}
+}
+
+func (c *Client) reportUsage(usage *Usage) {
+	if usage == nil {
+		return
+	}
+	c.limiter.charge(usage.CompletionTokens)
+	if c.usageObserver == nil {
+		return
+	}
+	slog.Debug("Reporting usage",
+		slog.String("model", c.Name()),
+		slog.Int("promptTokens", usage.PromptTokens),
+		slog.Int("cachedTokens", usage.CachedTokens()),
+		slog.Int("completionTokens", usage.CompletionTokens))
+	c.usageObserver(UsageReport{Model: c.Name(), Pricing: c.modelCfg.Pricing, Usage: *usage})
+}
+
+// observeUsage forwards stream and reports the last usage it carried once
+// the stream ends, before the returned channel is closed.
+func (c *Client) observeUsage(stream <-chan StreamEvent) <-chan StreamEvent {
+	if c.usageObserver == nil && !c.limiter.tracksTokens() {
+		return stream
+	}
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		var last *Usage
+		for ev := range stream {
+			if ev.Data != nil && ev.Data.Usage != nil {
+				last = ev.Data.Usage
+			}
+			out <- ev
+		}
+		c.reportUsage(last)
+	}()
+	return out
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestUsageObserverSeesStreamsAndSummaries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"stream":true`) {
+			_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"summary"}}],"usage":{"prompt_tokens":50,"completion_tokens":5,"total_tokens":55}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"},\"finish_reason\":\"stop\"}]}\n\n")
+		// OpenAI sends usage after the finish reason when include_usage is set.
+		_, _ = fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":100,\"completion_tokens\":2,\"total_tokens\":102,\"prompt_tokens_details\":{\"cached_tokens\":60}}}\n\n")
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Default: config.DefaultConfig{Provider: "p", Model: "m"},
+		Providers: map[string]config.ProviderConfig{
+			"p": {BaseURL: server.URL, Models: map[string]config.ModelConfig{
+				"m": {Model: "model-id", Pricing: &config.Pricing{Input: 1, Output: 2}},
+			}},
+		},
+	}
+	client, err := NewClientForModel(cfg, "p", "m", &config.ContextConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu      sync.Mutex
+		reports []UsageReport
+	)
+	client.SetUsageObserver(func(r UsageReport) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, r)
+	})
+
+	stream, err := client.StreamChatCompletionWithTools(context.Background(), []Message{NewUserMessage("hi")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ev := range stream {
+		if ev.Data != nil && len(ev.Data.Choices) > 0 && ev.Data.Choices[0].FinishReason == "stop" {
+			break
+		}
+	}
+	if usage := DrainStream(stream); usage == nil || usage.CachedTokens() != 60 {
+		t.Fatalf("DrainStream should return the trailing usage, got %+v", usage)
+	}
+	if _, err := client.Summarize(context.Background(), []Message{NewUserMessage("hi")}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 2 {
+		t.Fatalf("expected 2 usage reports, got %+v", reports)
+	}
+	if reports[0].Model != "p/m" || reports[0].Usage.PromptTokens != 100 || reports[0].Pricing == nil {
+		t.Fatalf("unexpected stream report: %+v", reports[0])
+	}
+	if reports[1].Usage.PromptTokens != 50 {
+		t.Fatalf("unexpected summarize report: %+v", reports[1])
+	}
+}
+
+func TestUsageCachedTokens(t *testing.T) {
+	for _, tt := range []struct {
+		body string
+		want int
+	}{
+		{`{"prompt_tokens":100,"prompt_tokens_details":{"cached_tokens":80}}`, 80},
+		{`{"prompt_tokens":100,"prompt_cache_hit_tokens":60,"prompt_cache_miss_tokens":40}`, 60},
+		{`{"prompt_tokens":100}`, 0},
+	} {
+		var u Usage
+		if err := json.Unmarshal([]byte(tt.body), &u); err != nil {
+			t.Fatal(err)
+		}
+		if got := u.CachedTokens(); got != tt.want {
+			t.Errorf("%s: CachedTokens() = %d, want %d", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package cassette records HTTP exchanges between ashron and a model provider
+// and replays them later, so agent behaviour can be tested without a live LLM.
+//
+// A cassette is a JSON Lines file with one Entry per request. Recorder is an
+// http.RoundTripper that appends entries as responses are read; Player serves
+// them back, matching requests on the SHA-256 of their body.
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Entry is one recorded request/response pair.
+type Entry struct {
+	// ProviderType is the wire format of the provider that was recorded
+	// (config.ProviderType*), so a "replay" provider can decode the body.
+	ProviderType string `json:"provider_type,omitempty"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	RequestHash  string `json:"request_hash"`
+	// Request is the request body, kept for humans reading the cassette.
+	Request json.RawMessage `json:"request,omitempty"`
+	Status  int             `json:"status"`
+	Header  http.Header     `json:"header,omitempty"`
+	// Body is the complete response body; for streaming responses this is
+	// the raw server-sent event stream.
+	Body string `json:"body"`
+}
+
+// Hash returns the key requests are matched on during replay.
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Load reads all entries from a cassette file.
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open cassette: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	return entries, nil
+}
+
+// appendMu serialises appends from every Recorder in the process, since
+// the main client, fallbacks and subagents may record to the same file.
+var appendMu sync.Mutex
+
+// Append writes e as one line at the end of the cassette file.
+func Append(path string, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal cassette entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	appendMu.Lock()
+	defer appendMu.Unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open cassette: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return f.Close()
+}
+
+// recordedHeaders are the response headers kept in a cassette. Everything
+// else (request IDs, cookies, rate-limit counters) is noise for replay.
+var recordedHeaders = []string{"Content-Type", "Retry-After"}
+
+// Recorder is an http.RoundTripper that forwards requests to Transport and
+// appends each exchange to the cassette at Path once the response body has
+// been read or closed.
+type Recorder struct {
+	Path         string
+	ProviderType string
+	Transport    http.RoundTripper
+	// OnError is called when an entry cannot be written. Optional.
+	OnError func(error)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.Transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := Entry{
+		ProviderType: r.ProviderType,
+		Method:       req.Method,
+		Path:         req.URL.Path,
+		RequestHash:  Hash(body),
+		Status:       resp.StatusCode,
+		Header:       http.Header{},
+	}
+	if json.Valid(body) {
+		entry.Request = body
+	}
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			entry.Header.Set(h, v)
+		}
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, finish: func(data []byte) {
+		entry.Body = string(data)
+		if err := Append(r.Path, entry); err != nil && r.OnError != nil {
+			r.OnError(err)
+		}
+	}}
+	return resp, nil
+}
+
+// recordingBody captures everything read from the response body and hands
+// it to finish exactly once, at EOF or Close, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	once   sync.Once
+	finish func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.finish(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.finish(b.buf.Bytes()) })
+	return b.ReadCloser.Close()
+}
+
+// Player is an http.RoundTripper that answers requests from recorded
+// entries without touching the network. Entries with the same request hash
+// are served in recorded order; once they are used up the last one is
+// repeated, so replay stays deterministic when a request is retried.
+type Player struct {
+	mu      sync.Mutex
+	byHash  map[string][]Entry
+	served  map[string]int
+	entries []Entry
+}
+
+// NewPlayer returns a Player serving entries.
+func NewPlayer(entries []Entry) *Player {
+	p := &Player{
+		byHash:  make(map[string][]Entry),
+		served:  make(map[string]int),
+		entries: entries,
+	}
+	for _, e := range entries {
+		p.byHash[e.RequestHash] = append(p.byHash[e.RequestHash], e)
+	}
+	return p
+}
+
+// ProviderType returns the wire format of the first recorded entry, or ""
+// for an empty cassette.
+func (p *Player) ProviderType() string {
+	for _, e := range p.entries {
+		if e.ProviderType != "" {
+			return e.ProviderType
+		}
+	}
+	return ""
+}
+
+// NotFoundError is returned for a request that is not in the cassette.
+type NotFoundError struct {
+	Method, Path, Hash string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("cassette has no recorded response for %s %s (request hash %s)", e.Method, e.Path, e.Hash)
+}
+
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	hash := Hash(body)
+
+	p.mu.Lock()
+	candidates := p.byHash[hash]
+	if len(candidates) == 0 {
+		p.mu.Unlock()
+		return nil, &NotFoundError{Method: req.Method, Path: req.URL.Path, Hash: hash}
+	}
+	i := min(p.served[hash], len(candidates)-1)
+	p.served[hash]++
+	e := candidates[i]
+	p.mu.Unlock()
+
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(e.Status) + " " + http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(e.Body))),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}, nil
+}
+
+// readRequestBody reads req.Body and replaces it so it can be sent again.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,91 @@
+package cassette
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string, error) {
+	t.Helper()
+	resp, err := client.Post(url+"/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "fail") {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Request-Id", "noise")
+		_, _ = io.WriteString(w, "data: {\"n\":"+strconv.Itoa(calls)+"}\n\ndata: [DONE]\n\n")
+	}))
+	path := filepath.Join(t.TempDir(), "c.jsonl")
+
+	rec := &http.Client{Transport: &Recorder{Path: path, ProviderType: "openai-compat", Transport: http.DefaultTransport}}
+	for _, body := range []string{`{"a":1}`, `{"a":1}`, `{"fail":true}`} {
+		if _, _, err := post(t, rec, server.URL, body); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	server.Close()
+
+	entries, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].Header.Get("X-Request-Id") != "" || entries[0].Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected recorded headers: %v", entries[0].Header)
+	}
+	if string(entries[0].Request) != `{"a":1}` || entries[0].ProviderType != "openai-compat" {
+		t.Fatalf("unexpected entry: %+v", entries[0])
+	}
+
+	player := NewPlayer(entries)
+	if player.ProviderType() != "openai-compat" {
+		t.Fatalf("ProviderType = %q", player.ProviderType())
+	}
+	replay := &http.Client{Transport: player}
+	// Identical requests are answered in recorded order, then the last
+	// answer repeats.
+	for i, want := range []string{`{"n":1}`, `{"n":2}`, `{"n":2}`} {
+		status, body, err := post(t, replay, server.URL, `{"a":1}`)
+		if err != nil {
+			t.Fatalf("replay %d: %v", i, err)
+		}
+		if status != http.StatusOK || !strings.Contains(body, want) {
+			t.Fatalf("replay %d: status=%d body=%q, want %s", i, status, body, want)
+		}
+	}
+	if status, _, err := post(t, replay, server.URL, `{"fail":true}`); err != nil || status != http.StatusTooManyRequests {
+		t.Fatalf("error responses should replay: status=%d err=%v", status, err)
+	}
+
+	_, _, err = post(t, replay, server.URL, `{"a":2}`)
+	var nf *NotFoundError
+	if !errors.As(err, &nf) || nf.Hash != Hash([]byte(`{"a":2}`)) {
+		t.Fatalf("expected NotFoundError, got %v", err)
+	}
+}
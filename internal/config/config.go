@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/tokuhirom/ashron/internal/tokenizer"
 )
 
 type Config struct {
@@ -17,23 +18,154 @@ type Config struct {
 	DefaultContext ContextConfig
 	MCPServers     map[string]MCPServerConfig
 	Debug          bool
+	// Project is the project config layered over the user's, if any.
+	Project *ProjectConfig
+	// Warnings are the issues found in the config files that did not stop
+	// them from loading, such as unknown keys.
+	Warnings []Issue
+	// Profile is the name of the profile applied, or "" for none.
+	Profile string
+
+	sources map[string]string // dotted path -> layer, for Source
+	layers  *layers           // what the config was read from, for WithProfile
 }
 
 type DefaultConfig struct {
 	Provider string
 	Model    string
+	// Fallback is tried in order when a model without its own fallback list
+	// fails with a retryable error.
+	Fallback []ModelRef
+}
+
+// ModelRef names a model within a provider.
+type ModelRef struct {
+	Provider string
+	Model    string
 }
 
+func (r ModelRef) String() string {
+	return r.Provider + "/" + r.Model
+}
+
+// Provider types understood by the API client. An empty type is treated as
+// ProviderTypeOpenAICompat.
+const (
+	ProviderTypeOpenAICompat    = "openai-compat"
+	ProviderTypeOpenAIResponses = "openai-responses"
+	ProviderTypeAnthropic       = "anthropic"
+	ProviderTypeGemini          = "gemini"
+	// ProviderTypeAzureOpenAI speaks the OpenAI-compatible dialect at Azure
+	// OpenAI's per-deployment URLs.
+	ProviderTypeAzureOpenAI = "azure-openai"
+	// ProviderTypeReplay serves responses from the cassette named by
+	// ProviderConfig.Replay, using the wire format it was recorded with.
+	ProviderTypeReplay = "replay"
+	// ProviderTypeScript answers from the script named by
+	// ProviderConfig.Script without any network access.
+	ProviderTypeScript = "script"
+)
+
+// ProviderTypes lists every supported provider type.
+var ProviderTypes = []string{
+	ProviderTypeOpenAICompat,
+	ProviderTypeOpenAIResponses,
+	ProviderTypeAnthropic,
+	ProviderTypeGemini,
+	ProviderTypeAzureOpenAI,
+	ProviderTypeReplay,
+	ProviderTypeScript,
+}
+
+// DefaultAzureAPIVersion is the api-version sent to Azure OpenAI when the
+// provider sets none.
+const DefaultAzureAPIVersion = "2024-10-21"
+
 type ProviderConfig struct {
 	Type    string
 	BaseURL string
-	APIKey  string
+	// APIKey is the key from api_key, or from the variable named by
+	// APIKeyEnv. It wins over APIKeyCommand, so --api-key can override it.
+	APIKey string
+	// APIKeyEnv names the environment variable APIKey was read from.
+	APIKeyEnv string
+	// APIKeyCommand is a shell command printing the key. Its output is
+	// cached for APIKeyCommandTTL and refreshed when the key is rejected.
+	APIKeyCommand    string
+	APIKeyCommandTTL time.Duration
+	// AuthScheme selects how the key is sent (AuthScheme*); empty uses the
+	// provider type's native header.
+	AuthScheme string
+	// APIVersion is the api-version query parameter sent to Azure OpenAI;
+	// empty uses DefaultAzureAPIVersion.
+	APIVersion string
+	// Headers are added to every request, after authentication.
+	Headers map[string]string
+	// Proxy is the URL of an HTTP proxy, ProxyDirect to connect without
+	// one, or empty to use HTTP_PROXY/HTTPS_PROXY/NO_PROXY.
+	Proxy string
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile             string
+	InsecureSkipVerify bool
+	// Timeout bounds a whole non-streaming request (summarization).
 	Timeout time.Duration
-	Models  map[string]ModelConfig
+	// ConnectTimeout bounds dialing and the TLS handshake.
+	ConnectTimeout time.Duration
+	// FirstTokenTimeout bounds the wait from sending a streaming request
+	// until the first byte of the response body arrives.
+	FirstTokenTimeout time.Duration
+	// IdleTimeout bounds the gap between two chunks of a streaming response.
+	IdleTimeout time.Duration
+	Retry       RetryConfig
+	RateLimit   RateLimitConfig
+	Models      map[string]ModelConfig
+	// ModelDefaults is the configuration of models found by discovery;
+	// its Model field is unused.
+	ModelDefaults ModelConfig
+	// Discovered lists the model IDs the provider reported (see
+	// SetDiscovered). It is not read from the config file.
+	Discovered   []string
+	discoveredAt time.Time
+	// Record appends every request/response pair to this cassette file.
+	Record string
+	// Replay serves responses from this cassette file instead of the network.
+	Replay string
+	// Script is the file of assistant turns a script provider answers from.
+	Script string
+}
+
+// RetryConfig controls how the API client retries failed requests.
+type RetryConfig struct {
+	MaxAttempts   int // total attempts including the first; 1 disables retries
+	BaseDelay     time.Duration
+	MaxDelay      time.Duration
+	Jitter        float64 // randomise each delay by ±Jitter (0..1)
+	RetryOnStatus []int
+}
+
+// RateLimitConfig throttles requests to a provider. It is enforced across
+// every client for the provider, including subagents and fallbacks; zero
+// fields are unlimited.
+type RateLimitConfig struct {
+	MaxConcurrent     int // requests in flight, streams included
+	RequestsPerMinute int
+	TokensPerMinute   int // estimated prompt tokens plus reported completion tokens
+}
+
+// DefaultRetryConfig is used for providers without a retry section.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:   3,
+	BaseDelay:     time.Second,
+	MaxDelay:      30 * time.Second,
+	Jitter:        0.2,
+	RetryOnStatus: []int{408, 409, 429, 500, 502, 503, 504},
 }
 
 type ModelConfig struct {
-	Model             string
+	Model string
+	// Deployment is the Azure OpenAI deployment serving the model; empty
+	// uses Model.
+	Deployment        string
 	Temperature       float32
 	TopP              float32
 	MinP              float32
@@ -41,13 +173,39 @@ type ModelConfig struct {
 	FrequencyPenalty  float32
 	PresencePenalty   float32
 	Stop              []string
-	ResponseFormat    string
+	ResponseFormat    *ResponseFormat // nil leaves the provider default
 	Seed              *int
 	ParallelToolCalls *bool
 	ReasoningEffort   string
+	EchoReasoning     bool // send reasoning_content back on later turns
+	MaxOutputTokens   int
+	Tokenizer         string     // token counting scheme; guessed from Model when empty
+	Vision            bool       // the model accepts image input
+	PromptCache       *bool      // mark cache breakpoints where the API needs them; nil means enabled
+	Pricing           *Pricing   // nil when the price is unknown
+	Fallback          []ModelRef // overrides DefaultConfig.Fallback when non-nil
 	Context           *ContextConfig
 }
 
+// Pricing is the price of a model in currency units per 1M tokens.
+type Pricing struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+	// Cached is the price of prompt tokens read from the provider's cache.
+	// Zero means cached tokens are billed at the Input price.
+	Cached float64 `yaml:"cached"`
+}
+
+// Cost returns the price of a request with the given token counts. cached is
+// the part of prompt served from cache.
+func (p *Pricing) Cost(prompt, cached, completion int) float64 {
+	cachedPrice := p.Cached
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	return (float64(prompt-cached)*p.Input + float64(cached)*cachedPrice + float64(completion)*p.Output) / 1_000_000
+}
+
 type ToolsConfig struct {
 	AutoApproveTools    []string
 	AutoApproveCommands []string
@@ -82,23 +240,67 @@ type rawConfig struct {
 	DefaultContext rawContextConfig              `yaml:"default_context"`
 	MCPServers     map[string]rawMCPServerConfig `yaml:"mcp_servers"`
 	Debug          bool                          `yaml:"debug"`
+	Profiles       map[string]rawProfile         `yaml:"profiles"`
+
+	// dir is the directory of the config file, for resolving relative paths.
+	dir string
 }
 
 type rawDefaultConfig struct {
+	Provider string        `yaml:"provider"`
+	Model    string        `yaml:"model"`
+	Fallback []rawModelRef `yaml:"fallback"`
+}
+
+type rawModelRef struct {
 	Provider string `yaml:"provider"`
 	Model    string `yaml:"model"`
 }
 
 type rawProviderConfig struct {
-	Type    string                    `yaml:"type"`
-	BaseURL string                    `yaml:"base_url"`
-	APIKey  string                    `yaml:"api_key"`
-	Timeout string                    `yaml:"timeout"`
-	Models  map[string]rawModelConfig `yaml:"models"`
+	Type             string            `yaml:"type"`
+	BaseURL          string            `yaml:"base_url"`
+	APIKey           string            `yaml:"api_key"`
+	APIKeyEnv        string            `yaml:"api_key_env"`
+	APIKeyCommand    string            `yaml:"api_key_command"`
+	APIKeyCommandTTL string            `yaml:"api_key_command_ttl"`
+	AuthScheme       string            `yaml:"auth_scheme"`
+	APIVersion       string            `yaml:"api_version"`
+	Headers          map[string]string `yaml:"headers"`
+	Proxy            string            `yaml:"proxy"`
+	CAFile           string            `yaml:"ca_file"`
+	// InsecureSkipVerify disables TLS certificate verification.
+	InsecureSkipVerify bool                      `yaml:"insecure_skip_verify"`
+	Timeout            string                    `yaml:"timeout"`
+	ConnectTimeout     string                    `yaml:"connect_timeout"`
+	FirstTokenTimeout  string                    `yaml:"first_token_timeout"`
+	IdleTimeout        string                    `yaml:"idle_timeout"`
+	Retry              *rawRetryConfig           `yaml:"retry"`
+	RateLimit          rawRateLimitConfig        `yaml:"rate_limit"`
+	Models             map[string]rawModelConfig `yaml:"models"`
+	ModelDefaults      *rawModelConfig           `yaml:"model_defaults"`
+	Record             string                    `yaml:"record"`
+	Replay             string                    `yaml:"replay"`
+	Script             string                    `yaml:"script"`
+}
+
+type rawRateLimitConfig struct {
+	MaxConcurrent     int `yaml:"max_concurrent"`
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+type rawRetryConfig struct {
+	MaxAttempts   *int     `yaml:"max_attempts"`
+	BaseDelay     string   `yaml:"base_delay"`
+	MaxDelay      string   `yaml:"max_delay"`
+	Jitter        *float64 `yaml:"jitter"`
+	RetryOnStatus []int    `yaml:"retry_on_status"`
 }
 
 type rawModelConfig struct {
 	Model             string                    `yaml:"model"`
+	Deployment        string                    `yaml:"deployment"`
 	Temperature       float32                   `yaml:"temperature"`
 	TopP              float32                   `yaml:"top_p"`
 	MinP              float32                   `yaml:"min_p"`
@@ -106,10 +308,17 @@ type rawModelConfig struct {
 	FrequencyPenalty  float32                   `yaml:"frequency_penalty"`
 	PresencePenalty   float32                   `yaml:"presence_penalty"`
 	Stop              []string                  `yaml:"stop"`
-	ResponseFormat    string                    `yaml:"response_format"`
+	ResponseFormat    *rawResponseFormat        `yaml:"response_format"`
 	Seed              *int                      `yaml:"seed"`
 	ParallelToolCalls *bool                     `yaml:"parallel_tool_calls"`
 	ReasoningEffort   string                    `yaml:"reasoning_effort"`
+	EchoReasoning     bool                      `yaml:"echo_reasoning"`
+	MaxOutputTokens   int                       `yaml:"max_output_tokens"`
+	Tokenizer         string                    `yaml:"tokenizer"`
+	Vision            bool                      `yaml:"vision"`
+	PromptCache       *bool                     `yaml:"prompt_cache"`
+	Pricing           *Pricing                  `yaml:"pricing"`
+	Fallback          []rawModelRef             `yaml:"fallback"`
 	Context           *rawContextOverrideConfig `yaml:"context"`
 }
 
@@ -145,11 +354,32 @@ type rawMCPServerConfig struct {
 }
 
 func Load() (*Config, error) {
-	cfgPath := configFilePath()
+	l, err := readLayers(true)
+	if err != nil {
+		return nil, err
+	}
+	return l.config("")
+}
+
+// readLayers reads the user's config file and the project's, creating a
+// default user config when there is none and create is set. On a
+// *ValidationError it returns what it read as well.
+func readLayers(create bool) (*layers, error) {
+	var projectPath string
+	if cwd, err := os.Getwd(); err == nil {
+		projectPath = FindProjectConfig(cwd)
+	}
+	return readLayersWith(projectPath, create)
+}
+
+// readLayersWith is readLayers with the project config file given, or ""
+// for none.
+func readLayersWith(projectPath string, create bool) (*layers, error) {
+	cfgPath := FilePath()
 
 	data, err := os.ReadFile(cfgPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) && create {
 			if err := createDefaultConfig(cfgPath); err != nil {
 				return nil, fmt.Errorf("failed to create default config: %w", err)
 			}
@@ -158,25 +388,35 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file '%s': %w", cfgPath, err)
 	}
 
-	var raw rawConfig
-	if err := yaml.Unmarshal(data, &raw); err != nil {
-		return nil, fmt.Errorf("failed to parse config file '%s': %w", cfgPath, err)
+	l, err := loadLayers(cfgPath, data, projectPath)
+	if err != nil {
+		return l, err
 	}
+	raw := &l.raw
 
-	applyDefaults(&raw)
+	raw.dir = filepath.Dir(cfgPath)
 
-	// Apply OPENAI_API_KEY env var to openai provider if api_key is unset.
-	if apiKey := os.Getenv("OPENAI_API_KEY"); apiKey != "" {
-		if prov, ok := raw.Providers["openai"]; ok && prov.APIKey == "" {
-			prov.APIKey = apiKey
+	// Apply OPENAI_API_KEY env var to openai provider if it has no key source.
+	if os.Getenv("OPENAI_API_KEY") != "" {
+		if prov, ok := raw.Providers["openai"]; ok && prov.APIKey == "" && prov.APIKeyEnv == "" && prov.APIKeyCommand == "" {
+			prov.APIKeyEnv = "OPENAI_API_KEY"
 			raw.Providers["openai"] = prov
 		}
 	}
-
-	return convertConfig(raw)
+	// Likewise AZURE_OPENAI_API_KEY for azure-openai providers.
+	if os.Getenv("AZURE_OPENAI_API_KEY") != "" {
+		for name, prov := range raw.Providers {
+			if prov.Type == ProviderTypeAzureOpenAI && prov.APIKey == "" && prov.APIKeyEnv == "" && prov.APIKeyCommand == "" {
+				prov.APIKeyEnv = "AZURE_OPENAI_API_KEY"
+				raw.Providers[name] = prov
+			}
+		}
+	}
+	return l, nil
 }
 
-func configFilePath() string {
+// FilePath returns the path of the user's config file.
+func FilePath() string {
 	return filepath.Join(xdgConfigDir(), "ashron", "ashron.yaml")
 }
 
@@ -192,6 +432,10 @@ func xdgConfigDir() string {
 	return filepath.Join(home, ".config")
 }
 
+// defaultAutoApproveTools are approved without asking unless the config
+// lists its own.
+var defaultAutoApproveTools = []string{"read_file", "read_skill", "list_directory", "list_tools", "get_diagnostics", "memory_list"}
+
 func applyDefaults(raw *rawConfig) {
 	if raw.Default.Provider == "" {
 		raw.Default.Provider = "openai"
@@ -200,7 +444,7 @@ func applyDefaults(raw *rawConfig) {
 		raw.Default.Model = "gpt4"
 	}
 	if len(raw.Tools.AutoApproveTools) == 0 {
-		raw.Tools.AutoApproveTools = []string{"read_file", "read_skill", "list_directory", "list_tools", "get_diagnostics", "memory_list"}
+		raw.Tools.AutoApproveTools = slices.Clone(defaultAutoApproveTools)
 	}
 	if raw.Tools.MaxOutputSize == 0 {
 		raw.Tools.MaxOutputSize = 50000
@@ -249,39 +493,90 @@ func convertConfig(raw rawConfig) (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("invalid providers.%s.timeout: %w", name, err)
 		}
+		connectTimeout, err := parseDuration(rp.ConnectTimeout, 30*time.Second)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers.%s.connect_timeout: %w", name, err)
+		}
+		firstTokenTimeout, err := parseDuration(rp.FirstTokenTimeout, 5*time.Minute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers.%s.first_token_timeout: %w", name, err)
+		}
+		idleTimeout, err := parseDuration(rp.IdleTimeout, 2*time.Minute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers.%s.idle_timeout: %w", name, err)
+		}
+		retry, err := convertRetry(rp.Retry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers.%s.retry: %w", name, err)
+		}
+		if rl := rp.RateLimit; rl.MaxConcurrent < 0 || rl.RequestsPerMinute < 0 || rl.TokensPerMinute < 0 {
+			return nil, fmt.Errorf("invalid providers.%s.rate_limit: limits must not be negative", name)
+		}
+		sources := 0
+		for _, s := range []string{rp.APIKey, rp.APIKeyEnv, rp.APIKeyCommand} {
+			if s != "" {
+				sources++
+			}
+		}
+		if sources > 1 {
+			return nil, fmt.Errorf("providers.%s: api_key, api_key_env and api_key_command are mutually exclusive", name)
+		}
+		keyTTL, err := parseDuration(rp.APIKeyCommandTTL, 15*time.Minute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid providers.%s.api_key_command_ttl: %w", name, err)
+		}
+		apiKey := rp.APIKey
+		if rp.APIKeyEnv != "" {
+			apiKey = os.Getenv(rp.APIKeyEnv)
+		}
 		models := make(map[string]ModelConfig, len(rp.Models))
 		for mname, rm := range rp.Models {
-			modelCfg := ModelConfig{
-				Model:             rm.Model,
-				Temperature:       rm.Temperature,
-				TopP:              rm.TopP,
-				MinP:              rm.MinP,
-				TopK:              rm.TopK,
-				FrequencyPenalty:  rm.FrequencyPenalty,
-				PresencePenalty:   rm.PresencePenalty,
-				Stop:              rm.Stop,
-				ResponseFormat:    rm.ResponseFormat,
-				Seed:              rm.Seed,
-				ParallelToolCalls: rm.ParallelToolCalls,
-				ReasoningEffort:   rm.ReasoningEffort,
-			}
-			if rm.Context != nil {
-				ctx := mergeContext(defaultContext, rm.Context)
-				modelCfg.Context = &ctx
+			modelCfg, err := convertModel(rm, raw.dir, defaultContext)
+			if err != nil {
+				return nil, fmt.Errorf("invalid providers.%s.models.%s.%w", name, mname, err)
 			}
 			models[mname] = modelCfg
 		}
+		var modelDefaults ModelConfig
+		if rp.ModelDefaults != nil {
+			if modelDefaults, err = convertModel(*rp.ModelDefaults, raw.dir, defaultContext); err != nil {
+				return nil, fmt.Errorf("invalid providers.%s.model_defaults.%w", name, err)
+			}
+		}
 		providers[name] = ProviderConfig{
-			Type:    rp.Type,
-			BaseURL: rp.BaseURL,
-			APIKey:  rp.APIKey,
-			Timeout: timeout,
-			Models:  models,
+			Type:               rp.Type,
+			BaseURL:            rp.BaseURL,
+			APIKey:             apiKey,
+			APIKeyEnv:          rp.APIKeyEnv,
+			APIKeyCommand:      rp.APIKeyCommand,
+			APIKeyCommandTTL:   keyTTL,
+			AuthScheme:         rp.AuthScheme,
+			APIVersion:         rp.APIVersion,
+			Headers:            rp.Headers,
+			Proxy:              rp.Proxy,
+			CAFile:             resolvePath(rp.CAFile, raw.dir),
+			InsecureSkipVerify: rp.InsecureSkipVerify,
+			Timeout:            timeout,
+			ConnectTimeout:     connectTimeout,
+			FirstTokenTimeout:  firstTokenTimeout,
+			IdleTimeout:        idleTimeout,
+			Retry:              retry,
+			RateLimit:          RateLimitConfig(rp.RateLimit),
+			Models:             models,
+			ModelDefaults:      modelDefaults,
+			Record:             resolvePath(rp.Record, raw.dir),
+			Replay:             resolvePath(rp.Replay, raw.dir),
+			Script:             resolvePath(rp.Script, raw.dir),
 		}
+
 	}
 
 	return &Config{
-		Default:   DefaultConfig{Provider: raw.Default.Provider, Model: raw.Default.Model},
+		Default: DefaultConfig{
+			Provider: raw.Default.Provider,
+			Model:    raw.Default.Model,
+			Fallback: convertModelRefs(raw.Default.Fallback),
+		},
 		Providers: providers,
 		Tools: ToolsConfig{
 			AutoApproveTools:    raw.Tools.AutoApproveTools,
@@ -297,6 +592,86 @@ func convertConfig(raw rawConfig) (*Config, error) {
 	}, nil
 }
 
+// convertModel converts one model entry. Errors name the offending field
+// relative to the entry.
+func convertModel(rm rawModelConfig, dir string, defaultContext ContextConfig) (ModelConfig, error) {
+	responseFormat, err := convertResponseFormat(rm.ResponseFormat, dir)
+	if err != nil {
+		return ModelConfig{}, fmt.Errorf("response_format: %w", err)
+	}
+	modelCfg := ModelConfig{
+		Model:             rm.Model,
+		Deployment:        rm.Deployment,
+		Temperature:       rm.Temperature,
+		TopP:              rm.TopP,
+		MinP:              rm.MinP,
+		TopK:              rm.TopK,
+		FrequencyPenalty:  rm.FrequencyPenalty,
+		PresencePenalty:   rm.PresencePenalty,
+		Stop:              rm.Stop,
+		ResponseFormat:    responseFormat,
+		Seed:              rm.Seed,
+		ParallelToolCalls: rm.ParallelToolCalls,
+		ReasoningEffort:   rm.ReasoningEffort,
+		EchoReasoning:     rm.EchoReasoning,
+		MaxOutputTokens:   rm.MaxOutputTokens,
+		Tokenizer:         rm.Tokenizer,
+		Vision:            rm.Vision,
+		PromptCache:       rm.PromptCache,
+		Pricing:           rm.Pricing,
+		Fallback:          convertModelRefs(rm.Fallback),
+	}
+	if rm.Context != nil {
+		ctx := mergeContext(defaultContext, rm.Context)
+		modelCfg.Context = &ctx
+	}
+	return modelCfg, nil
+}
+
+// convertModelRefs converts a fallback list, keeping nil and empty distinct
+// so that "fallback: []" on a model disables the default chain.
+func convertModelRefs(raw []rawModelRef) []ModelRef {
+	if raw == nil {
+		return nil
+	}
+	refs := make([]ModelRef, 0, len(raw))
+	for _, r := range raw {
+		refs = append(refs, ModelRef(r))
+	}
+	return refs
+}
+
+// convertRetry overlays the configured retry fields on DefaultRetryConfig.
+func convertRetry(raw *rawRetryConfig) (RetryConfig, error) {
+	retry := DefaultRetryConfig
+	if raw == nil {
+		return retry, nil
+	}
+	if raw.MaxAttempts != nil {
+		if *raw.MaxAttempts < 1 {
+			return retry, fmt.Errorf("max_attempts must be at least 1")
+		}
+		retry.MaxAttempts = *raw.MaxAttempts
+	}
+	var err error
+	if retry.BaseDelay, err = parseDuration(raw.BaseDelay, retry.BaseDelay); err != nil {
+		return retry, fmt.Errorf("base_delay: %w", err)
+	}
+	if retry.MaxDelay, err = parseDuration(raw.MaxDelay, retry.MaxDelay); err != nil {
+		return retry, fmt.Errorf("max_delay: %w", err)
+	}
+	if raw.Jitter != nil {
+		if *raw.Jitter < 0 || *raw.Jitter > 1 {
+			return retry, fmt.Errorf("jitter must be between 0 and 1")
+		}
+		retry.Jitter = *raw.Jitter
+	}
+	if raw.RetryOnStatus != nil {
+		retry.RetryOnStatus = raw.RetryOnStatus
+	}
+	return retry, nil
+}
+
 func convertMCPServers(raw map[string]rawMCPServerConfig) (map[string]MCPServerConfig, error) {
 	out := make(map[string]MCPServerConfig, len(raw))
 	for name, cfg := range raw {
@@ -403,21 +778,89 @@ func (c *Config) FindModel(modelName string) (string, *ProviderConfig, *ModelCon
 	return "", nil, nil, fmt.Errorf("model %q not found in any provider", modelName)
 }
 
-// AllModelNames returns all model names across all providers, with their provider name.
-func (c *Config) AllModelNames() []struct{ Provider, Model string } {
-	var names []struct{ Provider, Model string }
-	for provName, prov := range c.Providers {
-		for modelName := range prov.Models {
-			names = append(names, struct{ Provider, Model string }{provName, modelName})
+// LookupModel returns the provider and model configs for ref.
+func (c *Config) LookupModel(ref ModelRef) (*ProviderConfig, *ModelConfig, error) {
+	prov, ok := c.Providers[ref.Provider]
+	if !ok {
+		return nil, nil, fmt.Errorf("provider %q not found in config", ref.Provider)
+	}
+	m, ok := prov.Models[ref.Model]
+	if !ok {
+		return nil, nil, fmt.Errorf("model %q not found in provider %q", ref.Model, ref.Provider)
+	}
+	return &prov, &m, nil
+}
+
+// FallbackChain returns the models to try, in order, when provName/modelName
+// fails. The model's own fallback list wins over the default one; the primary
+// model itself and duplicates are skipped.
+func (c *Config) FallbackChain(provName, modelName string) []ModelRef {
+	chain := c.Default.Fallback
+	if prov, ok := c.Providers[provName]; ok {
+		if m, ok := prov.Models[modelName]; ok && m.Fallback != nil {
+			chain = m.Fallback
 		}
 	}
-	return names
+	seen := map[ModelRef]bool{{Provider: provName, Model: modelName}: true}
+	var out []ModelRef
+	for _, ref := range chain {
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+		out = append(out, ref)
+	}
+	return out
 }
 
 func (c *Config) Validate() error {
 	if len(c.Providers) == 0 {
 		return &ConfigError{"no providers configured"}
 	}
+	for name, p := range c.Providers {
+		if p.Type != "" && !slices.Contains(ProviderTypes, p.Type) {
+			return &ConfigError{fmt.Sprintf("provider %q has unknown type %q (supported: %s)",
+				name, p.Type, strings.Join(ProviderTypes, ", "))}
+		}
+		if p.Type == ProviderTypeReplay && p.Replay == "" {
+			return &ConfigError{fmt.Sprintf("provider %q has type %q but no replay cassette", name, p.Type)}
+		}
+		if p.Type == ProviderTypeScript && p.Script == "" {
+			return &ConfigError{fmt.Sprintf("provider %q has type %q but no script", name, p.Type)}
+		}
+		if p.Type == ProviderTypeAzureOpenAI && p.BaseURL == "" {
+			return &ConfigError{fmt.Sprintf("provider %q has type %q but no base_url (https://<resource>.openai.azure.com)", name, p.Type)}
+		}
+		if err := p.validateTransport(); err != nil {
+			return &ConfigError{fmt.Sprintf("providers.%s: %v", name, err)}
+		}
+	}
+	for _, ref := range c.Default.Fallback {
+		if _, _, err := c.LookupModel(ref); err != nil {
+			return &ConfigError{fmt.Sprintf("default.fallback: %v", err)}
+		}
+	}
+	for provName, p := range c.Providers {
+		for modelName, m := range p.Models {
+			if m.Pricing != nil && (m.Pricing.Input < 0 || m.Pricing.Output < 0 || m.Pricing.Cached < 0) {
+				return &ConfigError{fmt.Sprintf("providers.%s.models.%s.pricing: prices must not be negative", provName, modelName)}
+			}
+			if m.ResponseFormat != nil {
+				if err := m.ResponseFormat.validate(); err != nil {
+					return &ConfigError{fmt.Sprintf("providers.%s.models.%s.response_format: %v", provName, modelName, err)}
+				}
+			}
+			if m.Tokenizer != "" && !slices.Contains(tokenizer.Names, m.Tokenizer) {
+				return &ConfigError{fmt.Sprintf("providers.%s.models.%s: unknown tokenizer %q (supported: %s)",
+					provName, modelName, m.Tokenizer, strings.Join(tokenizer.Names, ", "))}
+			}
+			for _, ref := range m.Fallback {
+				if _, _, err := c.LookupModel(ref); err != nil {
+					return &ConfigError{fmt.Sprintf("providers.%s.models.%s.fallback: %v", provName, modelName, err)}
+				}
+			}
+		}
+	}
 	_, _, err := c.ActiveProvider()
 	if err != nil {
 		return err
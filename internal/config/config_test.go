@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -155,3 +156,136 @@ func TestConvertConfigParsesMCPServers(t *testing.T) {
 		t.Fatalf("tools config should also include mcp servers")
 	}
 }
+
+func TestValidateRejectsUnknownProviderType(t *testing.T) {
+	cfg := &Config{
+		Default: DefaultConfig{Provider: "p", Model: "m"},
+		Providers: map[string]ProviderConfig{
+			"p": {Type: "openai", Models: map[string]ModelConfig{"m": {Model: "x"}}},
+		},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for unknown provider type")
+	}
+
+	for _, typ := range append([]string{""}, ProviderTypes...) {
+		cfg.Providers["p"] = ProviderConfig{Type: typ, BaseURL: "https://example.com", Replay: "c.jsonl", Script: "s.yaml", Models: map[string]ModelConfig{"m": {Model: "x"}}}
+		if err := cfg.Validate(); err != nil {
+			t.Fatalf("type %q: unexpected error: %v", typ, err)
+		}
+	}
+
+	cfg.Providers["p"] = ProviderConfig{Type: ProviderTypeReplay, Models: map[string]ModelConfig{"m": {Model: "x"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for replay provider without a cassette")
+	}
+}
+
+func TestAzureOpenAIProvider(t *testing.T) {
+	raw := rawConfig{
+		Default: rawDefaultConfig{Provider: "azure", Model: "m"},
+		Providers: map[string]rawProviderConfig{
+			"azure": {
+				Type:       ProviderTypeAzureOpenAI,
+				BaseURL:    "https://acme.openai.azure.com",
+				APIVersion: "2025-01-01-preview",
+				Models:     map[string]rawModelConfig{"m": {Model: "gpt-4.1", Deployment: "prod-gpt"}},
+			},
+		},
+	}
+	cfg, err := convertConfig(raw)
+	if err != nil {
+		t.Fatalf("convertConfig: %v", err)
+	}
+	p := cfg.Providers["azure"]
+	if p.APIVersion != "2025-01-01-preview" || p.Models["m"].Deployment != "prod-gpt" {
+		t.Fatalf("api_version = %q, deployment = %q", p.APIVersion, p.Models["m"].Deployment)
+	}
+	if p.Discoverable() || cfg.NeedsDiscovery("azure") {
+		t.Fatal("azure-openai providers should not be asked for models")
+	}
+
+	p.BaseURL = ""
+	cfg.Providers["azure"] = p
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "base_url") {
+		t.Fatalf("expected base_url error, got %v", err)
+	}
+}
+
+func TestConvertRetryOverlaysDefaults(t *testing.T) {
+	attempts := 5
+	retry, err := convertRetry(&rawRetryConfig{MaxAttempts: &attempts, BaseDelay: "250ms"})
+	if err != nil {
+		t.Fatalf("convertRetry: %v", err)
+	}
+	if retry.MaxAttempts != 5 || retry.BaseDelay != 250*time.Millisecond {
+		t.Fatalf("configured fields not applied: %+v", retry)
+	}
+	if retry.MaxDelay != DefaultRetryConfig.MaxDelay || len(retry.RetryOnStatus) != len(DefaultRetryConfig.RetryOnStatus) {
+		t.Fatalf("unset fields should keep defaults: %+v", retry)
+	}
+
+	zero := 0
+	if _, err := convertRetry(&rawRetryConfig{MaxAttempts: &zero}); err == nil {
+		t.Fatal("expected error for max_attempts: 0")
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	cfg := &Config{
+		Default: DefaultConfig{
+			Provider: "a",
+			Model:    "m1",
+			Fallback: []ModelRef{{Provider: "a", Model: "m1"}, {Provider: "b", Model: "m2"}, {Provider: "b", Model: "m2"}},
+		},
+		Providers: map[string]ProviderConfig{
+			"a": {Models: map[string]ModelConfig{
+				"m1": {Model: "x"},
+				"m3": {Model: "z", Fallback: []ModelRef{}},
+			}},
+			"b": {Models: map[string]ModelConfig{
+				"m2": {Model: "y", Fallback: []ModelRef{{Provider: "a", Model: "m1"}}},
+			}},
+		},
+	}
+
+	if got := cfg.FallbackChain("a", "m1"); len(got) != 1 || got[0].String() != "b/m2" {
+		t.Fatalf("default chain should skip the primary and duplicates: %+v", got)
+	}
+	if got := cfg.FallbackChain("b", "m2"); len(got) != 1 || got[0].String() != "a/m1" {
+		t.Fatalf("model chain should override default: %+v", got)
+	}
+	if got := cfg.FallbackChain("a", "m3"); len(got) != 0 {
+		t.Fatalf("empty model chain should disable fallback: %+v", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg.Default.Fallback = append(cfg.Default.Fallback, ModelRef{Provider: "b", Model: "missing"})
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected error for unknown fallback model")
+	}
+}
+
+func TestConvertConfigStreamTimeouts(t *testing.T) {
+	raw := rawConfig{
+		Default: rawDefaultConfig{Provider: "p", Model: "m"},
+		Providers: map[string]rawProviderConfig{
+			"p": {Type: "openai-compat", IdleTimeout: "45s", Models: map[string]rawModelConfig{"m": {Model: "x"}}},
+		},
+	}
+	cfg, err := convertConfig(raw)
+	if err != nil {
+		t.Fatalf("convertConfig: %v", err)
+	}
+	p := cfg.Providers["p"]
+	if p.ConnectTimeout != 30*time.Second || p.FirstTokenTimeout != 5*time.Minute || p.IdleTimeout != 45*time.Second {
+		t.Fatalf("unexpected timeouts: connect=%v first_token=%v idle=%v", p.ConnectTimeout, p.FirstTokenTimeout, p.IdleTimeout)
+	}
+
+	raw.Providers["p"] = rawProviderConfig{Type: "openai-compat", FirstTokenTimeout: "soon"}
+	if _, err := convertConfig(raw); err == nil || !strings.Contains(err.Error(), "first_token_timeout") {
+		t.Fatalf("expected first_token_timeout error, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package config
+
+// RedactSecret masks a secret for display, keeping only its last four
+// characters when it is long enough that they reveal nothing useful.
+func RedactSecret(s string) string {
+	switch {
+	case s == "":
+		return ""
+	case len(s) < 16:
+		return "****"
+	default:
+		return "****" + s[len(s)-4:]
+	}
+}
+
+// APIKeySummary describes where the provider's API key comes from, with the
+// key itself redacted. It never runs api_key_command.
+func (p *ProviderConfig) APIKeySummary() string {
+	switch {
+	case p.APIKey != "" && p.APIKeyEnv != "":
+		return RedactSecret(p.APIKey) + " (from $" + p.APIKeyEnv + ")"
+	case p.APIKey != "":
+		return RedactSecret(p.APIKey)
+	case p.APIKeyCommand != "":
+		return "(from api_key_command, cached for " + p.APIKeyCommandTTL.String() + ")"
+	case p.APIKeyEnv != "":
+		return "(not set: $" + p.APIKeyEnv + " is empty)"
+	default:
+		return "(none)"
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func convertProvider(t *testing.T, rp rawProviderConfig) (ProviderConfig, error) {
+	t.Helper()
+	rp.Models = map[string]rawModelConfig{"m": {Model: "x"}}
+	cfg, err := convertConfig(rawConfig{
+		Default:   rawDefaultConfig{Provider: "p", Model: "m"},
+		Providers: map[string]rawProviderConfig{"p": rp},
+	})
+	if err != nil {
+		return ProviderConfig{}, err
+	}
+	return cfg.Providers["p"], nil
+}
+
+func TestConvertConfigAPIKeySources(t *testing.T) {
+	t.Setenv("ASHRON_TEST_KEY", "sk-from-environment-1234")
+
+	prov, err := convertProvider(t, rawProviderConfig{APIKeyEnv: "ASHRON_TEST_KEY"})
+	if err != nil {
+		t.Fatalf("convertConfig: %v", err)
+	}
+	if prov.APIKey != "sk-from-environment-1234" || prov.APIKeyEnv != "ASHRON_TEST_KEY" {
+		t.Fatalf("api_key_env not resolved: %+v", prov)
+	}
+
+	prov, err = convertProvider(t, rawProviderConfig{APIKeyCommand: "pass show openai", APIKeyCommandTTL: "1h"})
+	if err != nil {
+		t.Fatalf("convertConfig: %v", err)
+	}
+	if prov.APIKey != "" || prov.APIKeyCommand != "pass show openai" || prov.APIKeyCommandTTL != time.Hour {
+		t.Fatalf("api_key_command not converted: %+v", prov)
+	}
+
+	prov, err = convertProvider(t, rawProviderConfig{APIKeyCommand: "pass show openai"})
+	if err != nil || prov.APIKeyCommandTTL != 15*time.Minute {
+		t.Fatalf("default TTL not applied: %+v, %v", prov, err)
+	}
+
+	if _, err := convertProvider(t, rawProviderConfig{APIKey: "sk", APIKeyCommand: "pass show openai"}); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected mutual exclusion error, got %v", err)
+	}
+}
+
+func TestAPIKeySummaryRedactsKeys(t *testing.T) {
+	tests := []struct {
+		prov ProviderConfig
+		want string
+	}{
+		{ProviderConfig{APIKey: "sk-proj-abcdefghijklmnop"}, "****mnop"},
+		{ProviderConfig{APIKey: "short"}, "****"},
+		{ProviderConfig{APIKey: "sk-proj-abcdefghijklmnop", APIKeyEnv: "OPENAI_API_KEY"}, "****mnop (from $OPENAI_API_KEY)"},
+		{ProviderConfig{APIKeyEnv: "OPENAI_API_KEY"}, "(not set: $OPENAI_API_KEY is empty)"},
+		{ProviderConfig{APIKeyCommand: "pass show openai", APIKeyCommandTTL: 15 * time.Minute}, "(from api_key_command, cached for 15m0s)"},
+		{ProviderConfig{}, "(none)"},
+	}
+	for _, tt := range tests {
+		if got := tt.prov.APIKeySummary(); got != tt.want {
+			t.Errorf("APIKeySummary(%+v) = %q, want %q", tt.prov, got, tt.want)
+		}
+	}
+}
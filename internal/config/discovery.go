@@ -0,0 +1,296 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelCacheTTL is how long a discovered model list is used before the
+// provider is asked again.
+const ModelCacheTTL = 24 * time.Hour
+
+// ModelName is one entry of AllModelNames.
+type ModelName struct {
+	Provider string
+	// Model is the alias of a configured model, or the ID of a discovered one.
+	Model      string
+	Discovered bool
+}
+
+// AllModelNames returns all model names across all providers, with their
+// provider name: the configured aliases, then the discovered models that no
+// configured entry already covers.
+func (c *Config) AllModelNames() []ModelName {
+	var names []ModelName
+	for provName, prov := range c.Providers {
+		for modelName := range prov.Models {
+			names = append(names, ModelName{Provider: provName, Model: modelName})
+		}
+	}
+	for provName, prov := range c.Providers {
+		for _, id := range prov.Discovered {
+			if !prov.configures(id) {
+				names = append(names, ModelName{Provider: provName, Model: id, Discovered: true})
+			}
+		}
+	}
+	return names
+}
+
+// configures reports whether id is the alias or model ID of a configured model.
+func (p *ProviderConfig) configures(id string) bool {
+	if _, ok := p.Models[id]; ok {
+		return true
+	}
+	for _, m := range p.Models {
+		if m.Model == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDiscovered records the model IDs a provider reported.
+func (c *Config) SetDiscovered(provName string, ids []string) {
+	c.setDiscovered(provName, ids, time.Now())
+}
+
+func (c *Config) setDiscovered(provName string, ids []string, at time.Time) {
+	p, ok := c.Providers[provName]
+	if !ok {
+		return
+	}
+	p.Discovered = ids
+	p.discoveredAt = at
+	c.Providers[provName] = p
+}
+
+// Discoverable reports whether the provider can be asked for its models.
+// Replay cassettes and scripts cannot, and Azure OpenAI serves deployments,
+// which are configured under models instead.
+func (p *ProviderConfig) Discoverable() bool {
+	switch p.Type {
+	case ProviderTypeReplay, ProviderTypeScript, ProviderTypeAzureOpenAI:
+		return false
+	}
+	return p.Replay == ""
+}
+
+// NeedsDiscovery reports whether the provider's model list is missing or
+// older than ModelCacheTTL. Providers that are not Discoverable are never
+// asked.
+func (c *Config) NeedsDiscovery(provName string) bool {
+	p, ok := c.Providers[provName]
+	if !ok || !p.Discoverable() {
+		return false
+	}
+	return time.Since(p.discoveredAt) > ModelCacheTTL
+}
+
+// UseDiscoveredModel adds a discovered model to its provider's models, with
+// the provider's model_defaults, and returns where it was added. name is a
+// model ID, or "provider/id" when several providers serve the same ID.
+func (c *Config) UseDiscoveredModel(name string) (ModelRef, error) {
+	var matches []ModelRef
+	for provName, prov := range c.Providers {
+		id := name
+		if rest, ok := strings.CutPrefix(name, provName+"/"); ok && slices.Contains(prov.Discovered, rest) {
+			id = rest
+		}
+		if slices.Contains(prov.Discovered, id) {
+			matches = append(matches, ModelRef{Provider: provName, Model: id})
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return ModelRef{}, fmt.Errorf("model %q not found in any provider", name)
+	case 1:
+	default:
+		return ModelRef{}, fmt.Errorf("model %q is served by several providers; use <provider>/%s", name, name)
+	}
+
+	ref := matches[0]
+	p := c.Providers[ref.Provider]
+	if _, ok := p.Models[ref.Model]; !ok {
+		models := make(map[string]ModelConfig, len(p.Models)+1)
+		for k, v := range p.Models {
+			models[k] = v
+		}
+		m := p.ModelDefaults
+		m.Model = ref.Model
+		models[ref.Model] = m
+		p.Models = models
+		c.Providers[ref.Provider] = p
+	}
+	return ref, nil
+}
+
+// modelCacheEntry is one provider's entry in the model cache file. BaseURL
+// invalidates the entry when the provider is pointed elsewhere.
+type modelCacheEntry struct {
+	BaseURL string    `json:"base_url"`
+	Fetched time.Time `json:"fetched"`
+	Models  []string  `json:"models"`
+}
+
+// modelCachePath returns $XDG_CACHE_HOME/ashron/models.json.
+func modelCachePath() string {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = os.Getenv("HOME")
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "ashron", "models.json")
+}
+
+// LoadModelCache fills in the discovered models saved by SaveModelCache.
+// A missing cache file is not an error.
+func (c *Config) LoadModelCache() error {
+	data, err := os.ReadFile(modelCachePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries map[string]modelCacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parse model cache: %w", err)
+	}
+	for provName, e := range entries {
+		if p, ok := c.Providers[provName]; ok && p.BaseURL == e.BaseURL {
+			c.setDiscovered(provName, e.Models, e.Fetched)
+		}
+	}
+	return nil
+}
+
+// SaveModelCache writes the discovered models of every provider.
+func (c *Config) SaveModelCache() error {
+	entries := make(map[string]modelCacheEntry)
+	for provName, p := range c.Providers {
+		if !p.discoveredAt.IsZero() {
+			entries[provName] = modelCacheEntry{BaseURL: p.BaseURL, Fetched: p.discoveredAt, Models: p.Discovered}
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := modelCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// SaveModel adds the model configured under ref to the providers section of
+// the config file, so a discovered model survives a restart. The entry copies
+// the provider's model_defaults. Comments in the file are kept; its layout
+// may be normalised. It returns the path of the file.
+func (c *Config) SaveModel(ref ModelRef) (string, error) {
+	_, modelCfg, err := c.LookupModel(ref)
+	if err != nil {
+		return "", err
+	}
+	path := FilePath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return "", fmt.Errorf("parse %s: %w", path, err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return "", fmt.Errorf("%s is empty", path)
+	}
+
+	prov := mappingValue(mappingValue(doc.Content[0], "providers"), ref.Provider)
+	if prov == nil || prov.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("provider %q not found in %s", ref.Provider, path)
+	}
+	models := mappingValue(prov, "models")
+	if models == nil {
+		models = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		prov.Content = append(prov.Content, scalarNode("models"), models)
+	}
+	if mappingValue(models, ref.Model) != nil {
+		return "", fmt.Errorf("model %q already exists in provider %q in %s", ref.Model, ref.Provider, path)
+	}
+
+	entry := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if defaults := mappingValue(prov, "model_defaults"); defaults != nil && defaults.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(defaults.Content); i += 2 {
+			if defaults.Content[i].Value != "model" {
+				entry.Content = append(entry.Content, defaults.Content[i], defaults.Content[i+1])
+			}
+		}
+	}
+	entry.Content = append(entry.Content, scalarNode("model"), scalarNode(modelCfg.Model))
+	models.Content = append(models.Content, scalarNode(ref.Model), entry)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return path, writeFileAtomic(path, buf.Bytes())
+}
+
+// mappingValue returns the value for key in a YAML mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// writeFileAtomic replaces path with data, keeping its permissions.
+func writeFileAtomic(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".ashron-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func discoveryTestConfig() *Config {
+	return &Config{
+		Providers: map[string]ProviderConfig{
+			"openai": {
+				BaseURL:       "https://api.openai.com/v1",
+				Models:        map[string]ModelConfig{"gpt4": {Model: "gpt-4.1"}},
+				ModelDefaults: ModelConfig{Temperature: 0.3},
+			},
+			"local": {BaseURL: "http://localhost:11434/v1"},
+		},
+	}
+}
+
+func TestUseDiscoveredModelAppliesModelDefaults(t *testing.T) {
+	cfg := discoveryTestConfig()
+	cfg.SetDiscovered("openai", []string{"gpt-4.1", "o3"})
+
+	var discovered []string
+	for _, n := range cfg.AllModelNames() {
+		if n.Discovered {
+			discovered = append(discovered, n.Model)
+		}
+	}
+	if len(discovered) != 1 || discovered[0] != "o3" {
+		t.Fatalf("configured models should not be listed again: %v", discovered)
+	}
+
+	ref, err := cfg.UseDiscoveredModel("o3")
+	if err != nil {
+		t.Fatalf("UseDiscoveredModel: %v", err)
+	}
+	_, m, err := cfg.LookupModel(ref)
+	if err != nil {
+		t.Fatalf("LookupModel: %v", err)
+	}
+	if m.Model != "o3" || m.Temperature != 0.3 {
+		t.Fatalf("unexpected model config: %+v", m)
+	}
+}
+
+func TestUseDiscoveredModelNeedsProviderWhenAmbiguous(t *testing.T) {
+	cfg := discoveryTestConfig()
+	cfg.SetDiscovered("openai", []string{"llama3"})
+	cfg.SetDiscovered("local", []string{"llama3"})
+
+	if _, err := cfg.UseDiscoveredModel("llama3"); err == nil {
+		t.Fatal("expected an ambiguity error")
+	}
+	ref, err := cfg.UseDiscoveredModel("local/llama3")
+	if err != nil || ref != (ModelRef{Provider: "local", Model: "llama3"}) {
+		t.Fatalf("ref = %v, err = %v", ref, err)
+	}
+}
+
+func TestModelCacheRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cfg := discoveryTestConfig()
+	if !cfg.NeedsDiscovery("openai") {
+		t.Fatal("a provider without a model list needs discovery")
+	}
+	cfg.SetDiscovered("openai", []string{"o3"})
+	if err := cfg.SaveModelCache(); err != nil {
+		t.Fatalf("SaveModelCache: %v", err)
+	}
+
+	loaded := discoveryTestConfig()
+	if err := loaded.LoadModelCache(); err != nil {
+		t.Fatalf("LoadModelCache: %v", err)
+	}
+	if got := loaded.Providers["openai"].Discovered; len(got) != 1 || got[0] != "o3" || loaded.NeedsDiscovery("openai") {
+		t.Fatalf("cache not restored: %v", got)
+	}
+
+	moved := discoveryTestConfig()
+	p := moved.Providers["openai"]
+	p.BaseURL = "https://gateway.example.com/v1"
+	moved.Providers["openai"] = p
+	if err := moved.LoadModelCache(); err != nil {
+		t.Fatalf("LoadModelCache: %v", err)
+	}
+	if len(moved.Providers["openai"].Discovered) != 0 {
+		t.Fatal("cache for another base_url must be ignored")
+	}
+}
+
+func TestSaveModelWritesConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	path := filepath.Join(dir, "ashron", "ashron.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	original := `# my config
+providers:
+  openai:
+    type: openai-compat
+    model_defaults:
+      temperature: 0.3
+    models:
+      gpt4:
+        model: gpt-4.1 # the default
+`
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := discoveryTestConfig()
+	cfg.SetDiscovered("openai", []string{"o3"})
+	ref, err := cfg.UseDiscoveredModel("o3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.SaveModel(ref); err != nil {
+		t.Fatalf("SaveModel: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{"# my config", "# the default", "o3:\n        temperature: 0.3\n        model: o3\n"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("config file missing %q:\n%s", want, got)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("permissions not kept: %v %v", info.Mode(), err)
+	}
+
+	if _, err := cfg.SaveModel(ref); err == nil {
+		t.Fatal("saving the same model twice should fail")
+	}
+}
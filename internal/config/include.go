@@ -0,0 +1,190 @@
+package config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pathKeys hold file paths, which are relative to the file that sets them.
+var pathKeys = []string{
+	"providers.*.ca_file",
+	"providers.*.script",
+	"providers.*.record",
+	"providers.*.replay",
+	"providers.*.models.*.response_format.schema_file",
+	"providers.*.model_defaults.response_format.schema_file",
+}
+
+// configReader reads config files along with the files they include.
+type configReader struct {
+	// extraKeys are accepted at the top level besides the config's own.
+	extraKeys []string
+
+	stack  []string  // files being read, to catch include cycles
+	hash   hash.Hash // content of every file read
+	files  []string  // every file read, in order
+	issues []Issue   // found while reading
+}
+
+func newConfigReader() *configReader {
+	return &configReader{hash: sha256.New()}
+}
+
+// read parses the config file at path with content data. Environment
+// variables are interpolated first, then the files listed under include are
+// merged in order with the file itself on top: mappings merge key by key,
+// lists are appended and scalars are overridden. Problems with the values
+// are collected in r.issues; it fails only on files it cannot parse.
+func (r *configReader) read(path string, data []byte) (*yaml.Node, error) {
+	r.hash.Write([]byte(path))
+	r.hash.Write(data)
+	r.files = append(r.files, path)
+	tree, err := parseTree(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := interpolateTree(tree, path); err != nil {
+		return nil, err
+	}
+	includes := mapValue(tree, "include")
+	mapDelete(tree, "include")
+	r.issues = append(r.issues, checkTree(path, tree, r.extraKeys...)...)
+	if err := tree.Decode(&rawConfig{}); err != nil {
+		issues := decodeIssues(path, err)
+		if issues == nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		r.issues = append(r.issues, issues...)
+	}
+	dir := filepath.Dir(path)
+	for _, pattern := range pathKeys {
+		for _, p := range matchPaths(tree, pattern) {
+			if v := getPath(tree, p); v.Kind == yaml.ScalarNode {
+				v.Value = resolvePath(v.Value, dir)
+			}
+		}
+	}
+	if includes == nil {
+		return tree, nil
+	}
+
+	var files []*yaml.Node
+	switch includes.Kind {
+	case yaml.ScalarNode:
+		files = []*yaml.Node{includes}
+	case yaml.SequenceNode:
+		files = includes.Content
+	}
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	discard := make(map[string]string)
+	r.stack = append(r.stack, path)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	for _, f := range files {
+		if f.Kind != yaml.ScalarNode || f.Value == "" {
+			return nil, fmt.Errorf("%s:%d: include must be a file or a list of files", path, f.Line)
+		}
+		incPath := resolvePath(f.Value, dir)
+		if slices.Contains(r.stack, incPath) {
+			return nil, fmt.Errorf("%s:%d: include cycle: %s", path, f.Line, strings.Join(append(r.stack, incPath), " -> "))
+		}
+		incData, err := os.ReadFile(incPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: include: %w", path, f.Line, err)
+		}
+		sub, err := r.read(incPath, incData)
+		if err != nil {
+			return nil, err
+		}
+		mergeTree(merged, sub, "", nil, discard, "")
+	}
+	mergeTree(merged, tree, "", nil, discard, "")
+	return merged, nil
+}
+
+// interpolateTree expands environment variables in every scalar value of a
+// config file: ${VAR}, or ${VAR:-default} when VAR is unset or empty. $${
+// stands for a literal ${. A plain scalar is re-resolved afterwards, so
+// "max_tokens: ${MAX_TOKENS:-8000}" is still a number.
+func interpolateTree(n *yaml.Node, path string) error {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			if err := interpolateTree(n.Content[i], path); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			if err := interpolateTree(c, path); err != nil {
+				return err
+			}
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return nil
+		}
+		v, err := interpolate(n.Value)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n.Line, err)
+		}
+		n.Value = v
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+	return nil
+}
+
+// interpolate expands the environment variable references in s.
+func interpolate(s string) (string, error) {
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			sb.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		sb.WriteString(s[:i])
+		expr, rest, ok := strings.Cut(s[i+2:], "}")
+		if !ok {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+		name, def, hasDef := strings.Cut(expr, ":-")
+		if !validEnvName(name) {
+			return "", fmt.Errorf("invalid variable name in ${%s}", expr)
+		}
+		val, set := os.LookupEnv(name)
+		switch {
+		case hasDef && val == "":
+			val = def
+		case !set:
+			return "", fmt.Errorf("${%s} is not set; use ${%s:-default} to allow that", name, name)
+		}
+		sb.WriteString(val)
+		s = rest
+	}
+}
+
+func validEnvName(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("ASHRON_TEST_HOST", "llm.internal")
+	t.Setenv("ASHRON_TEST_EMPTY", "")
+	tests := []struct{ in, want string }{
+		{"https://${ASHRON_TEST_HOST}/v1", "https://llm.internal/v1"},
+		{"${ASHRON_TEST_UNSET:-http://localhost:8080}", "http://localhost:8080"},
+		{"${ASHRON_TEST_EMPTY:-fallback}", "fallback"},
+		{"${ASHRON_TEST_EMPTY}", ""},
+		{"echo $${HOME} $HOME", "echo ${HOME} $HOME"},
+	}
+	for _, tt := range tests {
+		got, err := interpolate(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("interpolate(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"${ASHRON_TEST_UNSET}", "${ASHRON_TEST_HOST", "${1X}"} {
+		if _, err := interpolate(in); err == nil {
+			t.Errorf("interpolate(%q) should fail", in)
+		}
+	}
+}
+
+func TestLoadLayersInterpolatesAllStrings(t *testing.T) {
+	t.Setenv("ASHRON_TEST_HOST", "llm.internal")
+	t.Setenv("ASHRON_TEST_TOKENS", "8000")
+	l, err := loadLayers("user.yaml", []byte(`
+providers:
+  local:
+    base_url: https://${ASHRON_TEST_HOST}/v1
+    models:
+      m:
+        model: ${ASHRON_TEST_MODEL:-qwen}
+default_context:
+  max_tokens: ${ASHRON_TEST_TOKENS}
+mcp_servers:
+  docs:
+    command: docs
+    args: ["--host", "${ASHRON_TEST_HOST}"]
+    env: {TOKEN: "${ASHRON_TEST_TOKEN:-none}"}
+    working_dir: ${ASHRON_TEST_DIR:-/srv}
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := l.raw
+	if got := raw.Providers["local"].BaseURL; got != "https://llm.internal/v1" {
+		t.Errorf("base_url = %q", got)
+	}
+	if got := raw.Providers["local"].Models["m"].Model; got != "qwen" {
+		t.Errorf("model = %q", got)
+	}
+	if raw.DefaultContext.MaxTokens != 8000 {
+		t.Errorf("max_tokens = %d", raw.DefaultContext.MaxTokens)
+	}
+	docs := raw.MCPServers["docs"]
+	if !slices.Equal(docs.Args, []string{"--host", "llm.internal"}) || docs.Env["TOKEN"] != "none" || docs.WorkingDir != "/srv" {
+		t.Errorf("mcp server = %+v", docs)
+	}
+}
+
+func TestLoadLayersReportsUnsetVariableWithLine(t *testing.T) {
+	_, err := loadLayers("/etc/ashron.yaml", []byte("default:\n  provider: x\nproviders:\n  x:\n    base_url: ${ASHRON_TEST_UNSET}\n"), "")
+	if err == nil || !strings.Contains(err.Error(), "/etc/ashron.yaml:5:") || !strings.Contains(err.Error(), "ASHRON_TEST_UNSET") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLoadLayersIncludes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("shared/providers.yaml", `
+providers:
+  corp:
+    type: openai-compat
+    base_url: https://corp.example/v1
+    ca_file: corp-ca.pem
+    record: sessions/corp.jsonl
+    replay: testdata/corp.jsonl
+    models:
+      big: {model: big-model}
+tools:
+  auto_approve_commands: ["git status"]
+`)
+	write("local.yaml", `
+providers:
+  corp:
+    timeout: 1m
+`)
+	userPath := write("ashron.yaml", `
+include:
+  - shared/providers.yaml
+  - local.yaml
+default:
+  provider: corp
+  model: big
+tools:
+  auto_approve_commands: ["go test ./..."]
+`)
+	data, _ := os.ReadFile(userPath)
+	l, err := loadLayers(userPath, data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, sources := l.raw, l.sources
+	corp := raw.Providers["corp"]
+	if corp.BaseURL != "https://corp.example/v1" || corp.Timeout != "1m" || corp.Models["big"].Model != "big-model" {
+		t.Fatalf("corp = %+v", corp)
+	}
+	if want := filepath.Join(dir, "shared", "corp-ca.pem"); corp.CAFile != want {
+		t.Errorf("ca_file = %q, want %q", corp.CAFile, want)
+	}
+	if want := filepath.Join(dir, "shared", "sessions", "corp.jsonl"); corp.Record != want {
+		t.Errorf("record = %q, want %q", corp.Record, want)
+	}
+	if want := filepath.Join(dir, "shared", "testdata", "corp.jsonl"); corp.Replay != want {
+		t.Errorf("replay = %q, want %q", corp.Replay, want)
+	}
+	if !slices.Equal(raw.Tools.AutoApproveCommands, []string{"git status", "go test ./..."}) {
+		t.Errorf("auto_approve_commands = %v", raw.Tools.AutoApproveCommands)
+	}
+	if sources["providers.corp.base_url"] != LayerUser {
+		t.Errorf("included values should count as the user layer: %v", sources)
+	}
+
+	write("a.yaml", "include: b.yaml\n")
+	write("b.yaml", "debug: true\ninclude: [a.yaml]\n")
+	data, _ = os.ReadFile(filepath.Join(dir, "a.yaml"))
+	if _, err := loadLayers(filepath.Join(dir, "a.yaml"), data, ""); err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("err = %v, want an include cycle", err)
+	}
+	if _, err := loadLayers(userPath, []byte("debug: true\ninclude: missing.yaml\n"), ""); err == nil ||
+		!strings.Contains(err.Error(), userPath+":2:") {
+		t.Errorf("err = %v, want the include line", err)
+	}
+	write("bad.yaml", "tools:\n  max_output_size: lots\n")
+	if _, err := loadLayers(userPath, []byte("include: bad.yaml\n"), ""); err == nil ||
+		!strings.Contains(err.Error(), filepath.Join(dir, "bad.yaml")+":2:") {
+		t.Errorf("err = %v, want the included file and line", err)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Check reads the config files like Load but reports every issue in them,
+// including the ones Validate finds with each profile applied, instead of
+// stopping at the first. It fails only when the files cannot be read or
+// parsed at all.
+func Check() ([]Issue, error) {
+	l, err := readLayers(false)
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr.Issues, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	issues := l.warnings
+	if p := l.project; p != nil && !p.Trusted && len(p.Sensitive) > 0 {
+		issues = append(issues, Issue{File: p.Path, Warning: true,
+			Message: "ignoring " + strings.Join(p.Sensitive, ", ") + " until the project config is trusted"})
+	}
+	profiles := append([]string{""}, slices.Sorted(maps.Keys(l.raw.Profiles))...)
+	for _, profile := range profiles {
+		cfg, err := l.config(profile)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err != nil {
+			issue := Issue{Message: err.Error()}
+			if profile != "" {
+				issue.Key = "profiles." + profile
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// Files lists the config files in effect for the working directory, in the
+// order they are layered: the user's file and what it includes, then the
+// project's.
+func Files() []string {
+	if l, _ := readLayers(false); l != nil {
+		return l.files
+	}
+	files := []string{FilePath()}
+	if cwd, err := os.Getwd(); err == nil {
+		if p := FindProjectConfig(cwd); p != "" {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// Show writes the configuration as YAML. By default that is the config files
+// merged as written, each value commented with the layer it came from;
+// resolved shows the values ashron runs with instead, defaults and the named
+// profile included. API keys and secret-looking headers and environment
+// variables are redacted.
+func Show(w io.Writer, resolved bool, profile string) error {
+	l, err := readLayers(false)
+	if err != nil {
+		return err
+	}
+	n := l.tree
+	if resolved {
+		raw, _, err := l.resolve(profile)
+		if err != nil {
+			return err
+		}
+		raw.Profiles = nil
+		n = &yaml.Node{}
+		if err := n.Encode(raw); err != nil {
+			return err
+		}
+		pruneZero(n)
+	} else {
+		annotateSources(n, "", l.sources)
+	}
+	redactSecrets(n)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(n); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// annotateSources replaces the comments in a merged tree with the layer of
+// each value.
+func annotateSources(n *yaml.Node, path string, sources map[string]string) {
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		n.Style = 0 // comments do not fit in flow style
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			k.HeadComment, k.LineComment, k.FootComment = "", "", ""
+			annotateSources(v, joinPath(path, k.Value), sources)
+		}
+		return
+	}
+	n.HeadComment, n.FootComment = "", ""
+	if n.Kind == yaml.SequenceNode {
+		n.Style = yaml.FlowStyle // a block list drops its line comment
+	}
+	for _, c := range n.Content {
+		c.HeadComment, c.LineComment, c.FootComment = "", "", ""
+	}
+	n.LineComment = sources[path]
+	if n.LineComment == "" {
+		n.LineComment = LayerDefault
+	}
+}
+
+// pruneZero drops unset values from an encoded rawConfig.
+func pruneZero(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			pruneZero(c)
+		}
+		return false
+	case yaml.MappingNode:
+		kept := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !pruneZero(n.Content[i+1]) {
+				kept = append(kept, n.Content[i], n.Content[i+1])
+			}
+		}
+		n.Content = kept
+		return len(kept) == 0
+	case yaml.SequenceNode:
+		return len(n.Content) == 0
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!null":
+			return true
+		case "!!str":
+			return n.Value == ""
+		case "!!int", "!!float":
+			return n.Value == "0"
+		case "!!bool":
+			return n.Value == "false"
+		}
+	}
+	return false
+}
+
+var secretName = regexp.MustCompile(`(?i)auth|key|token|secret|password`)
+
+// redactSecrets blanks out API keys, and headers and MCP server environment
+// variables whose names look secret.
+func redactSecrets(n *yaml.Node) {
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	var values []*yaml.Node
+	for _, p := range matchPaths(n, "providers.*.api_key") {
+		values = append(values, getPath(n, p))
+	}
+	for _, pattern := range []string{"providers.*.headers.*", "mcp_servers.*.env.*"} {
+		for _, p := range matchPaths(n, pattern) {
+			if secretName.MatchString(p[strings.LastIndex(p, ".")+1:]) {
+				values = append(values, getPath(n, p))
+			}
+		}
+	}
+	for _, v := range values {
+		if v.Kind == yaml.ScalarNode && v.Value != "" {
+			v.Value, v.Tag, v.Style = "<redacted>", "!!str", 0
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rawProfile is a named set of overrides under profiles:. Each part is kept
+// as YAML and decoded over the config, so only the keys the profile has
+// change; its lists replace the config's.
+type rawProfile struct {
+	Default        yaml.Node `yaml:"default"`
+	Tools          yaml.Node `yaml:"tools"`
+	DefaultContext yaml.Node `yaml:"default_context"`
+}
+
+// profileSchema is what a profile may contain, for checking it.
+type profileSchema struct {
+	Default        rawDefaultConfig `yaml:"default"`
+	Tools          rawToolsConfig   `yaml:"tools"`
+	DefaultContext rawContextConfig `yaml:"default_context"`
+}
+
+// ProfileNames returns the names of the configured profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	if c.layers == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(c.layers.raw.Profiles))
+}
+
+// WithProfile returns the configuration with the named profile applied
+// instead of the current one, or with none for "". Providers, MCP servers
+// and YOLO mode are not part of profiles and are kept from c, along with
+// any command line overrides and discovered models.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if c.layers == nil {
+		return nil, fmt.Errorf("configuration was not loaded from files")
+	}
+	cfg, err := c.layers.config(name)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Providers = c.Providers
+	cfg.MCPServers = c.MCPServers
+	if c.Tools.Yolo {
+		cfg.Tools.Yolo = true
+		cfg.Tools.SandboxMode = "off"
+	}
+	return cfg, nil
+}
+
+// config converts the layers with the named profile, if any, applied.
+func (l *layers) config(profile string) (*Config, error) {
+	raw, sources, err := l.resolve(profile)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := convertConfig(raw)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Profile = profile
+	cfg.Project = l.project
+	cfg.Warnings = l.warnings
+	cfg.sources = sources
+	cfg.layers = l
+	return cfg, nil
+}
+
+// resolve returns the raw config with the named profile, if any, and the
+// defaults applied, along with the layer of each value.
+func (l *layers) resolve(profile string) (rawConfig, map[string]string, error) {
+	raw := l.raw
+	sources := maps.Clone(l.sources)
+	if profile != "" {
+		p, ok := raw.Profiles[profile]
+		if !ok {
+			names := slices.Sorted(maps.Keys(raw.Profiles))
+			if len(names) == 0 {
+				return raw, nil, &ConfigError{fmt.Sprintf("unknown profile %q: no profiles are configured", profile)}
+			}
+			return raw, nil, &ConfigError{fmt.Sprintf("unknown profile %q (available: %s)", profile, strings.Join(names, ", "))}
+		}
+		if err := applyProfile(&raw, &p, sources); err != nil {
+			return raw, nil, fmt.Errorf("profiles.%s: %w", profile, err)
+		}
+	}
+	applyDefaults(&raw)
+	return raw, sources, nil
+}
+
+// applyProfile decodes the profile's parts over raw.
+func applyProfile(raw *rawConfig, p *rawProfile, sources map[string]string) error {
+	// Decoding writes through pointers, which raw shares with the layers.
+	if v := raw.DefaultContext.AutoCompact; v != nil {
+		autoCompact := *v
+		raw.DefaultContext.AutoCompact = &autoCompact
+	}
+	parts := []struct {
+		key  string
+		node *yaml.Node
+		into any
+	}{
+		{"default", &p.Default, &raw.Default},
+		{"tools", &p.Tools, &raw.Tools},
+		{"default_context", &p.DefaultContext, &raw.DefaultContext},
+	}
+	for _, part := range parts {
+		if part.node.Kind == 0 {
+			continue
+		}
+		if err := part.node.Decode(part.into); err != nil {
+			return fmt.Errorf("%s: %w", part.key, err)
+		}
+		markSources(part.node, part.key, sources, LayerProfile)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const profileConfig = `
+default:
+  provider: local
+  model: small
+providers:
+  local:
+    type: openai-compat
+    base_url: http://localhost:8080/v1
+    models:
+      small: {model: small-model}
+      big: {model: big-model}
+tools:
+  auto_approve_tools: [read_file, list_directory]
+  command_timeout: 1m
+default_context:
+  max_tokens: 8000
+  auto_compact: true
+profiles:
+  work:
+    default: {model: big}
+    tools:
+      auto_approve_tools: [read_file]
+    default_context:
+      auto_compact: false
+  quick:
+    default_context: {max_tokens: 2000}
+`
+
+func TestWithProfile(t *testing.T) {
+	writeUserConfig(t, profileConfig)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.ProfileNames(); !slices.Equal(got, []string{"quick", "work"}) {
+		t.Fatalf("ProfileNames() = %v", got)
+	}
+
+	work, err := cfg.WithProfile("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Profile != "work" || work.Default.Provider != "local" || work.Default.Model != "big" {
+		t.Errorf("default = %+v (profile %q)", work.Default, work.Profile)
+	}
+	if !slices.Equal(work.Tools.AutoApproveTools, []string{"read_file"}) {
+		t.Errorf("profile lists should replace: %v", work.Tools.AutoApproveTools)
+	}
+	if work.Tools.CommandTimeout.String() != "1m0s" || work.DefaultContext.MaxTokens != 8000 || work.DefaultContext.AutoCompact {
+		t.Errorf("tools = %+v, context = %+v", work.Tools, work.DefaultContext)
+	}
+	if work.Source("default.model") != LayerProfile || work.Source("default.provider") != LayerUser {
+		t.Errorf("sources: model %s, provider %s", work.Source("default.model"), work.Source("default.provider"))
+	}
+
+	// Switching again starts from the files, not from the previous profile.
+	quick, err := work.WithProfile("quick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quick.Default.Model != "small" || quick.DefaultContext.MaxTokens != 2000 || !quick.DefaultContext.AutoCompact {
+		t.Errorf("quick = %+v, %+v", quick.Default, quick.DefaultContext)
+	}
+	none, err := quick.WithProfile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if none.Profile != "" || none.DefaultContext.MaxTokens != 8000 || len(none.Tools.AutoApproveTools) != 2 {
+		t.Errorf("none = %+v", none)
+	}
+
+	var cerr *ConfigError
+	if _, err := cfg.WithProfile("home"); !errors.As(err, &cerr) || !strings.Contains(err.Error(), "available: quick, work") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWithProfileKeepsOverrides(t *testing.T) {
+	writeUserConfig(t, profileConfig)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov := cfg.Providers["local"]
+	prov.APIKey = "from-flag"
+	cfg.Providers["local"] = prov
+	cfg.Tools.Yolo = true
+
+	work, err := cfg.WithProfile("work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if work.Providers["local"].APIKey != "from-flag" || !work.Tools.Yolo || work.Tools.SandboxMode != "off" {
+		t.Errorf("overrides lost: %+v, %+v", work.Providers["local"], work.Tools)
+	}
+}
+
+func TestProfilesAreChecked(t *testing.T) {
+	_, err := loadLayers("/etc/ashron.yaml", []byte(`profiles:
+  work:
+    tools:
+      auto_aprove_tools: [read_file]
+      command_timeout: soon
+      auto_approve_commands: ["/git (/"]
+    default_context:
+      max_tokens: lots
+    editor: vim
+`), "")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	var got []string
+	for _, issue := range verr.Issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`warning: /etc/ashron.yaml:4: profiles.work.tools.auto_aprove_tools: unknown key (did you mean "auto_approve_tools"?)`,
+		`warning: /etc/ashron.yaml:9: profiles.work.editor: unknown key`,
+		"error: /etc/ashron.yaml:8: cannot unmarshal !!str `lots` into int",
+		`error: /etc/ashron.yaml:5: profiles.work.tools.command_timeout: invalid duration "soon" (use e.g. 30s, 5m or 1h30m)`,
+		"error: /etc/ashron.yaml:6: profiles.work.tools.auto_approve_commands: invalid regexp /git (/: error parsing regexp: missing closing ): `git (`",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCheckValidatesEachProfile(t *testing.T) {
+	writeUserConfig(t, profileConfig+`
+  broken:
+    default: {model: missing}
+`)
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Key != "profiles.broken" || !strings.Contains(issues[0].Message, "missing") {
+		t.Fatalf("issues = %+v", issues)
+	}
+}
+
+func TestShowResolvedProfile(t *testing.T) {
+	writeUserConfig(t, profileConfig)
+	var sb strings.Builder
+	if err := Show(&sb, true, "quick"); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	if !strings.Contains(out, "max_tokens: 2000") || strings.Contains(out, "profiles") {
+		t.Errorf("resolved output:\n%s", out)
+	}
+}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectConfigFile is the repository's own config, relative to its root. It
+// is layered over the user's ashron.yaml: mappings merge key by key, scalars
+// from the project win, and lists are appended unless their key is named in
+// the file's top-level replace list.
+const ProjectConfigFile = ".ashron/config.yaml"
+
+// Layers a config value can come from, as reported by Config.Source.
+const (
+	LayerDefault = "default"
+	LayerUser    = "user"
+	LayerProject = "project"
+	LayerProfile = "profile"
+)
+
+// ProjectConfig describes the project config file found for the working
+// directory.
+type ProjectConfig struct {
+	Path string
+	// Trusted is true once the user approved the file's current content.
+	Trusted bool
+	// Sensitive lists the keys in the file that grant approvals, run
+	// commands or redirect API keys. They are ignored unless Trusted.
+	Sensitive []string
+
+	sum string
+}
+
+// sensitiveKeys are the project keys that need the user's trust. "*" matches
+// any one key.
+var sensitiveKeys = []string{
+	"tools.auto_approve_tools",
+	"tools.auto_approve_commands",
+	"tools.sandbox_mode",
+	"profiles.*.tools.auto_approve_tools",
+	"profiles.*.tools.auto_approve_commands",
+	"profiles.*.tools.sandbox_mode",
+	"default.provider",
+	"default.model",
+	"default.fallback",
+	"profiles.*.default.provider",
+	"profiles.*.default.model",
+	"profiles.*.default.fallback",
+	"mcp_servers",
+	"providers.*.type",
+	"providers.*.models.*.model",
+	"providers.*.models.*.deployment",
+	"providers.*.models.*.fallback",
+	"providers.*.api_key_env",
+	"providers.*.api_key_command",
+	"providers.*.base_url",
+	"providers.*.headers",
+	"providers.*.proxy",
+	"providers.*.ca_file",
+	"providers.*.insecure_skip_verify",
+	"providers.*.record",
+	"providers.*.replay",
+}
+
+// FindProjectConfig returns the project config file for dir: the one at the
+// root of the enclosing git repository, or in dir itself outside of one. It
+// returns "" when there is none.
+func FindProjectConfig(dir string) string {
+	root := dir
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			root = d
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	path := filepath.Join(root, ProjectConfigFile)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// Source reports which layer set the value at a dotted path such as
+// "tools.sandbox_mode" or "providers.openai.timeout": LayerDefault,
+// LayerUser, LayerProject or LayerProfile, or "user+project" for lists and
+// mappings both added to.
+func (c *Config) Source(path string) string {
+	if layer, ok := c.sources[path]; ok {
+		return layer
+	}
+	var layers []string
+	for p, layer := range c.sources {
+		if strings.HasPrefix(p, path+".") {
+			for _, l := range strings.Split(layer, "+") {
+				if !slices.Contains(layers, l) {
+					layers = append(layers, l)
+				}
+			}
+		}
+	}
+	if len(layers) == 0 {
+		return LayerDefault
+	}
+	return joinLayers(layers)
+}
+
+// joinLayers orders layers from lowest to highest precedence.
+func joinLayers(layers []string) string {
+	var out []string
+	for _, l := range []string{LayerDefault, LayerUser, LayerProject, LayerProfile} {
+		if slices.Contains(layers, l) {
+			out = append(out, l)
+		}
+	}
+	return strings.Join(out, "+")
+}
+
+// layers is the configuration as read from its files, before conversion.
+type layers struct {
+	raw rawConfig
+	// tree is the merged files as written, with includes and variables
+	// resolved but without defaults.
+	tree     *yaml.Node
+	project  *ProjectConfig
+	sources  map[string]string
+	files    []string
+	warnings []Issue
+}
+
+// loadLayers merges the user's config file with the project's, when
+// projectPath is set. Issues that stop the config from loading are returned
+// as a *ValidationError, along with what was read.
+func loadLayers(userPath string, userData []byte, projectPath string) (*layers, error) {
+	r := newConfigReader()
+	merged, err := r.read(userPath, userData)
+	if err != nil {
+		return nil, err
+	}
+	l := &layers{tree: merged, sources: make(map[string]string)}
+	if len(merged.Content) > 0 {
+		markSources(merged, "", l.sources, LayerUser)
+	}
+	l.files = r.files
+	issues := r.issues
+	if projectPath != "" {
+		pr := newConfigReader()
+		if l.project, err = mergeProject(pr, merged, projectPath, l.sources); err != nil {
+			return nil, err
+		}
+		l.files = append(l.files, pr.files...)
+		issues = append(issues, pr.issues...)
+	}
+	if !hasErrors(issues) {
+		if err := merged.Decode(&l.raw); err != nil {
+			if issues = append(issues, decodeIssues("", err)...); !hasErrors(issues) {
+				return nil, err
+			}
+		}
+	}
+	l.warnings = issues
+	if hasErrors(issues) {
+		return l, &ValidationError{Issues: issues}
+	}
+	return l, nil
+}
+
+// mergeProject merges the project config file at path into merged, leaving
+// out its sensitive keys unless the user trusts it.
+func mergeProject(r *configReader, merged *yaml.Node, path string, sources map[string]string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project config '%s': %w", path, err)
+	}
+	r.extraKeys = []string{"replace"}
+	tree, err := r.read(path, data)
+	if err != nil {
+		return nil, err
+	}
+	var replace []string
+	if v := mapValue(tree, "replace"); v != nil {
+		if err := v.Decode(&replace); err != nil {
+			return nil, fmt.Errorf("%s: replace must be a list of keys: %w", path, err)
+		}
+		mapDelete(tree, "replace")
+	}
+
+	// Trust covers the files the project config includes, too.
+	project := &ProjectConfig{Path: path, sum: hex.EncodeToString(r.hash.Sum(nil))}
+	project.Trusted = isTrustedProject(project)
+	for _, pattern := range sensitiveKeys {
+		for _, p := range matchPaths(tree, pattern) {
+			project.Sensitive = append(project.Sensitive, p)
+			if !project.Trusted {
+				deletePath(tree, p)
+			}
+		}
+	}
+	slices.Sort(project.Sensitive)
+
+	// Appending to an unset list would otherwise drop its defaults.
+	const approveTools = "tools.auto_approve_tools"
+	if getPath(tree, approveTools) != nil && getPath(merged, approveTools) == nil && !slices.Contains(replace, approveTools) {
+		setPath(merged, approveTools, stringList(defaultAutoApproveTools))
+		sources[approveTools] = LayerDefault
+	}
+	mergeTree(merged, tree, "", replace, sources, LayerProject)
+	return project, nil
+}
+
+// trustedProjectsPath stores the project config files the user trusted, with
+// the checksum of the content they saw.
+func trustedProjectsPath() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "ashron", "trusted_projects.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share", "ashron", "trusted_projects.json")
+}
+
+func readTrustedProjects() map[string]string {
+	trusted := make(map[string]string)
+	if data, err := os.ReadFile(trustedProjectsPath()); err == nil {
+		_ = json.Unmarshal(data, &trusted)
+	}
+	return trusted
+}
+
+func isTrustedProject(p *ProjectConfig) bool {
+	return readTrustedProjects()[p.Path] == p.sum
+}
+
+// TrustProject records that the user trusts the project config's current
+// content. Editing the file withdraws the trust.
+func TrustProject(p *ProjectConfig) error {
+	trusted := readTrustedProjects()
+	trusted[p.Path] = p.sum
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := trustedProjectsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
@@ -0,0 +1,267 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const projectTestUserConfig = `
+default:
+  provider: openai
+  model: gpt4
+providers:
+  openai:
+    type: openai-compat
+    base_url: https://api.openai.com/v1
+    timeout: 5m
+    models:
+      gpt4:
+        model: gpt-4
+        temperature: 0.5
+        stop: ["END"]
+tools:
+  auto_approve_commands: ["git status"]
+`
+
+func writeProjectConfig(t *testing.T, content string) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, ProjectConfigFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTestLayers(t *testing.T, projectPath string) (*Config, *ProjectConfig) {
+	t.Helper()
+	l, err := loadLayers("user.yaml", []byte(projectTestUserConfig), projectPath)
+	if err != nil {
+		t.Fatalf("loadLayers: %v", err)
+	}
+	applyDefaults(&l.raw)
+	cfg, err := convertConfig(l.raw)
+	if err != nil {
+		t.Fatalf("convertConfig: %v", err)
+	}
+	cfg.Project = l.project
+	cfg.sources = l.sources
+	return cfg, l.project
+}
+
+func TestFindProjectConfigUsesRepoRoot(t *testing.T) {
+	path := writeProjectConfig(t, "debug: true\n")
+	sub := filepath.Join(filepath.Dir(filepath.Dir(path)), "a", "b")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfig(sub); got != path {
+		t.Fatalf("FindProjectConfig = %q, want %q", got, path)
+	}
+	if got := FindProjectConfig(t.TempDir()); got != "" {
+		t.Fatalf("FindProjectConfig without a project = %q", got)
+	}
+}
+
+func TestProjectConfigMergesOverUserConfig(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := writeProjectConfig(t, `
+default:
+  model: fast
+providers:
+  openai:
+    models:
+      fast:
+        model: gpt-4o-mini
+      gpt4:
+        temperature: 0.1
+        stop: ["STOP"]
+        response_format:
+          type: json_schema
+          schema_file: schema.json
+default_context:
+  max_tokens: 8000
+`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "schema.json"), []byte(`{"type":"object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Switching or redefining models needs trust.
+	_, project := loadTestLayers(t, path)
+	if err := TrustProject(project); err != nil {
+		t.Fatal(err)
+	}
+	cfg, project := loadTestLayers(t, path)
+
+	if project == nil || project.Path != path || !project.Trusted || !slices.Equal(project.Sensitive, []string{"default.model", "providers.openai.models.fast.model"}) {
+		t.Fatalf("project = %+v", project)
+	}
+	prov := cfg.Providers["openai"]
+	if cfg.Default.Model != "fast" || prov.Timeout.String() != "5m0s" || prov.Models["fast"].Model != "gpt-4o-mini" {
+		t.Fatalf("unexpected merge: default=%+v provider=%+v", cfg.Default, prov)
+	}
+	gpt4 := prov.Models["gpt4"]
+	if gpt4.Model != "gpt-4" || gpt4.Temperature != 0.1 {
+		t.Fatalf("gpt4 = %+v", gpt4)
+	}
+	if !slices.Equal(gpt4.Stop, []string{"END", "STOP"}) {
+		t.Fatalf("lists should be appended, got %v", gpt4.Stop)
+	}
+	if gpt4.ResponseFormat == nil || string(gpt4.ResponseFormat.Schema) != `{"type":"object"}` {
+		t.Fatalf("schema_file should resolve against .ashron: %+v", gpt4.ResponseFormat)
+	}
+
+	sources := map[string]string{
+		"default.provider":                         LayerUser,
+		"default.model":                            LayerProject,
+		"providers.openai.timeout":                 LayerUser,
+		"providers.openai.models.gpt4.stop":        "user+project",
+		"providers.openai.models.gpt4":             "user+project",
+		"default_context.max_tokens":               LayerProject,
+		"default_context.compaction_ratio":         LayerDefault,
+		"providers.openai.models.gpt4.temperature": LayerProject,
+	}
+	for path, want := range sources {
+		if got := cfg.Source(path); got != want {
+			t.Errorf("Source(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestProjectConfigReplaceList(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := writeProjectConfig(t, `
+replace:
+  - providers.openai.models.gpt4.stop
+providers:
+  openai:
+    models:
+      gpt4:
+        stop: ["STOP"]
+`)
+	cfg, _ := loadTestLayers(t, path)
+	if got := cfg.Providers["openai"].Models["gpt4"].Stop; !slices.Equal(got, []string{"STOP"}) {
+		t.Fatalf("replace should drop the user's list, got %v", got)
+	}
+	if got := cfg.Source("providers.openai.models.gpt4.stop"); got != LayerProject {
+		t.Fatalf("Source = %q", got)
+	}
+}
+
+func TestProjectConfigSensitiveKeysNeedTrust(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := writeProjectConfig(t, `
+tools:
+  auto_approve_commands: ["make test"]
+  auto_approve_tools: ["run_tests"]
+  max_output_size: 1000
+mcp_servers:
+  docs:
+    command: docs-server
+`)
+	cfg, project := loadTestLayers(t, path)
+	want := []string{"mcp_servers", "tools.auto_approve_commands", "tools.auto_approve_tools"}
+	if project.Trusted || !slices.Equal(project.Sensitive, want) {
+		t.Fatalf("project = %+v", project)
+	}
+	if !slices.Equal(cfg.Tools.AutoApproveCommands, []string{"git status"}) || len(cfg.MCPServers) != 0 {
+		t.Fatalf("untrusted approvals applied: %v %v", cfg.Tools.AutoApproveCommands, cfg.MCPServers)
+	}
+	if cfg.Tools.MaxOutputSize != 1000 {
+		t.Fatalf("other project keys should still apply, max_output_size = %d", cfg.Tools.MaxOutputSize)
+	}
+
+	if err := TrustProject(project); err != nil {
+		t.Fatal(err)
+	}
+	cfg, project = loadTestLayers(t, path)
+	if !project.Trusted {
+		t.Fatal("project should be trusted")
+	}
+	if !slices.Equal(cfg.Tools.AutoApproveCommands, []string{"git status", "make test"}) || cfg.MCPServers["docs"].Command != "docs-server" {
+		t.Fatalf("trusted approvals not applied: %v %v", cfg.Tools.AutoApproveCommands, cfg.MCPServers)
+	}
+	if !slices.Contains(cfg.Tools.AutoApproveTools, "read_file") || !slices.Contains(cfg.Tools.AutoApproveTools, "run_tests") {
+		t.Fatalf("appending should keep the default tools: %v", cfg.Tools.AutoApproveTools)
+	}
+
+	if err := os.WriteFile(path, []byte("tools:\n  auto_approve_commands: [\"rm -rf /\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, project = loadTestLayers(t, path); project.Trusted {
+		t.Fatal("editing the file should withdraw trust")
+	}
+}
+
+func TestProjectConfigSensitiveKeysTable(t *testing.T) {
+	tests := []struct {
+		key, yaml string
+	}{
+		{"default.provider", "default:\n  provider: evil\n"},
+		{"default.model", "default:\n  model: evil\n"},
+		{"profiles.work.default.provider", "profiles:\n  work:\n    default: {provider: evil}\n"},
+		{"profiles.work.default.model", "profiles:\n  work:\n    default: {model: evil}\n"},
+		{"providers.openai.type", "providers:\n  openai:\n    type: anthropic\n"},
+		{"providers.openai.api_key_env", "providers:\n  openai:\n    api_key_env: OTHER_KEY\n"},
+		{"providers.openai.headers", "providers:\n  openai:\n    headers: {X-Forward-To: evil}\n"},
+		{"providers.openai.ca_file", "providers:\n  openai:\n    ca_file: evil.pem\n"},
+		{"providers.openai.insecure_skip_verify", "providers:\n  openai:\n    insecure_skip_verify: true\n"},
+		{"providers.openai.record", "providers:\n  openai:\n    record: leak.jsonl\n"},
+		{"providers.openai.replay", "providers:\n  openai:\n    replay: answers.jsonl\n"},
+		{"default.fallback", "default:\n  fallback: [{provider: openai, model: evil}]\n"},
+		{"profiles.work.default.fallback", "profiles:\n  work:\n    default: {fallback: [{provider: openai, model: evil}]}\n"},
+		{"providers.openai.models.gpt4.model", "providers:\n  openai:\n    models:\n      gpt4: {model: evil}\n"},
+		{"providers.openai.models.gpt4.deployment", "providers:\n  openai:\n    models:\n      gpt4: {deployment: evil}\n"},
+		{"providers.openai.models.gpt4.fallback", "providers:\n  openai:\n    models:\n      gpt4: {fallback: [{provider: openai, model: evil}]}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			path := writeProjectConfig(t, tt.yaml)
+			cfg, project := loadTestLayers(t, path)
+			if project.Trusted || !slices.Equal(project.Sensitive, []string{tt.key}) {
+				t.Fatalf("project = %+v", project)
+			}
+			if src := cfg.Source(tt.key); strings.Contains(src, LayerProject) {
+				t.Fatalf("untrusted %s applied (source %q)", tt.key, src)
+			}
+
+			if err := TrustProject(project); err != nil {
+				t.Fatal(err)
+			}
+			if cfg, _ = loadTestLayers(t, path); !strings.Contains(cfg.Source(tt.key), LayerProject) {
+				t.Fatalf("trusted %s not applied (source %q)", tt.key, cfg.Source(tt.key))
+			}
+		})
+	}
+}
+
+func TestProjectConfigParseErrorNamesFile(t *testing.T) {
+	path := writeProjectConfig(t, "tools:\n  max_output_size: lots\n")
+	_, err := loadLayers("user.yaml", []byte(projectTestUserConfig), path)
+	if err == nil || !strings.Contains(err.Error(), path) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestProjectConfigKeepsScalarsVerbatim(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	path := writeProjectConfig(t, `
+providers:
+  azure:
+    type: azure-openai
+    api_version: 2024-10-21
+`)
+	cfg, _ := loadTestLayers(t, path)
+	if got := cfg.Providers["azure"].APIVersion; got != "2024-10-21" {
+		t.Fatalf("api_version = %q", got)
+	}
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReloadInterval is how often a running session looks for edits to its
+// config files.
+const ReloadInterval = 2 * time.Second
+
+// Watcher notices edits to the config files a configuration was read from.
+type Watcher struct {
+	stamps map[string]fileStamp
+}
+
+// fileStamp identifies a version of a file; the zero value stands for a
+// missing one.
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// NewWatcher watches the files c was read from: the user's config file, the
+// project's and the files they include.
+func NewWatcher(c *Config) *Watcher {
+	w := &Watcher{}
+	w.Reset(c)
+	return w
+}
+
+// Reset watches the files c was read from instead.
+func (w *Watcher) Reset(c *Config) {
+	w.stamps = make(map[string]fileStamp)
+	if c.layers == nil {
+		return
+	}
+	for _, f := range c.layers.files {
+		w.stamps[f] = statFile(f)
+	}
+}
+
+// Changed reports whether a watched file was modified or removed since the
+// last call, or since Reset.
+func (w *Watcher) Changed() bool {
+	changed := false
+	for f, old := range w.stamps {
+		if st := statFile(f); st != old {
+			w.stamps[f] = st
+			changed = true
+		}
+	}
+	return changed
+}
+
+func statFile(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime().UnixNano(), size: fi.Size()}
+}
+
+// Change is a setting that differs between two reads of the config files.
+type Change struct {
+	Key string
+	// Old and New are the values in YAML flow style, "" when the key was
+	// added or removed.
+	Old, New string
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return "+ " + c.Key + ": " + c.New
+	case c.New == "":
+		return "- " + c.Key + ": " + c.Old
+	}
+	return "~ " + c.Key + ": " + c.Old + " -> " + c.New
+}
+
+// Reload reads the files c was read from again, with the same profile, and
+// returns the new configuration along with what changed in the files. What
+// was changed since loading is kept unless the files change the same
+// setting: a provider's key, base URL or cassettes given on the command
+// line, the model picked with --model or /model, discovered models and YOLO
+// mode. An invalid configuration is returned as an error, as Load and
+// Validate report it.
+func (c *Config) Reload() (*Config, []Change, error) {
+	if c.layers == nil {
+		return nil, nil, fmt.Errorf("configuration was not loaded from files")
+	}
+	var projectPath string
+	if p := c.layers.project; p != nil {
+		if _, err := os.Stat(p.Path); err == nil {
+			projectPath = p.Path
+		}
+	}
+	l, err := readLayersWith(projectPath, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	next, err := l.config(c.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	base, err := c.layers.config(c.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	next.keepRuntimeChanges(c, base)
+	if err := next.Validate(); err != nil {
+		return nil, nil, err
+	}
+	changes, err := diffLayers(c.layers, l, c.Profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return next, changes, nil
+}
+
+// keepRuntimeChanges carries over what cur changed relative to base, the
+// configuration it was loaded as, where c still has base's value.
+func (c *Config) keepRuntimeChanges(cur, base *Config) {
+	for name, p := range c.Providers {
+		cp, ok := cur.Providers[name]
+		if !ok {
+			continue
+		}
+		bp := base.Providers[name]
+		keepChanged(&p.APIKey, cp.APIKey, bp.APIKey)
+		keepChanged(&p.APIKeyEnv, cp.APIKeyEnv, bp.APIKeyEnv)
+		keepChanged(&p.BaseURL, cp.BaseURL, bp.BaseURL)
+		keepChanged(&p.Record, cp.Record, bp.Record)
+		keepChanged(&p.Replay, cp.Replay, bp.Replay)
+		if p.BaseURL == cp.BaseURL {
+			p.Discovered, p.discoveredAt = cp.Discovered, cp.discoveredAt
+		}
+		// Discovered models in use, as UseDiscoveredModel added them.
+		for id, m := range cp.Models {
+			_, inBase := bp.Models[id]
+			_, inNext := p.Models[id]
+			if inBase || inNext || !slices.Contains(p.Discovered, m.Model) {
+				continue
+			}
+			models := maps.Clone(p.Models)
+			if models == nil {
+				models = make(map[string]ModelConfig)
+			}
+			dm := p.ModelDefaults
+			dm.Model = m.Model
+			models[id] = dm
+			p.Models = models
+		}
+		c.Providers[name] = p
+	}
+
+	picked := ModelRef{Provider: cur.Default.Provider, Model: cur.Default.Model}
+	if c.Default.Provider == base.Default.Provider && c.Default.Model == base.Default.Model {
+		if _, _, err := c.LookupModel(picked); err == nil {
+			c.Default.Provider, c.Default.Model = picked.Provider, picked.Model
+		}
+	}
+	if cur.Tools.Yolo {
+		c.Tools.Yolo = true
+		c.Tools.SandboxMode = "off"
+	}
+}
+
+// keepChanged sets *next to cur when cur was changed from base and *next
+// was not.
+func keepChanged[T comparable](next *T, cur, base T) {
+	if cur != base && *next == base {
+		*next = cur
+	}
+}
+
+// diffLayers lists the settings that differ between two reads of the config
+// files with the named profile applied, secrets redacted.
+func diffLayers(prev, next *layers, profile string) ([]Change, error) {
+	var before, after, shownBefore, shownAfter map[string]string
+	for _, s := range []struct {
+		l              *layers
+		values, redact *map[string]string
+	}{
+		{prev, &before, &shownBefore},
+		{next, &after, &shownAfter},
+	} {
+		var err error
+		if *s.values, err = settings(s.l, profile, false); err != nil {
+			return nil, err
+		}
+		if *s.redact, err = settings(s.l, profile, true); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []Change
+	for key, v := range after {
+		if was, ok := before[key]; !ok || was != v {
+			changes = append(changes, Change{Key: key, Old: shownBefore[key], New: shownAfter[key]})
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changes = append(changes, Change{Key: key, Old: shownBefore[key]})
+		}
+	}
+	slices.SortFunc(changes, func(a, b Change) int { return strings.Compare(a.Key, b.Key) })
+	return changes, nil
+}
+
+// settings flattens the resolved configuration to dotted keys and their
+// values in flow style. Unset values are left out.
+func settings(l *layers, profile string, redact bool) (map[string]string, error) {
+	raw, _, err := l.resolve(profile)
+	if err != nil {
+		return nil, err
+	}
+	raw.Profiles = nil
+	n := &yaml.Node{}
+	if err := n.Encode(raw); err != nil {
+		return nil, err
+	}
+	pruneZero(n)
+	if redact {
+		redactSecrets(n)
+	}
+	if n.Kind == yaml.DocumentNode {
+		n = n.Content[0]
+	}
+	values := make(map[string]string)
+	flatten(n, "", values)
+	return values, nil
+}
+
+func flatten(n *yaml.Node, path string, values map[string]string) {
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			flatten(n.Content[i+1], joinPath(path, n.Content[i].Value), values)
+		}
+		return
+	}
+	if path != "" {
+		values[path] = flowString(n)
+	}
+}
+
+// flowString renders a value in YAML flow style, e.g. [a, b].
+func flowString(n *yaml.Node) string {
+	var parts []string
+	switch n.Kind {
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			parts = append(parts, flowString(c))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			parts = append(parts, n.Content[i].Value+": "+flowString(n.Content[i+1]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return n.Value
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+const reloadConfig = `
+default:
+  provider: local
+  model: small
+providers:
+  local:
+    type: openai-compat
+    base_url: http://localhost:8080/v1
+    api_key: file-key
+    models:
+      small: {model: small-model}
+      big: {model: big-model}
+tools:
+  auto_approve_tools: [read_file]
+`
+
+func TestReload(t *testing.T) {
+	path := writeUserConfig(t, reloadConfig)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(cfg)
+	if w.Changed() {
+		t.Fatal("nothing was edited yet")
+	}
+
+	// Changes made at runtime: --api-key and a model picked with /model.
+	prov := cfg.Providers["local"]
+	prov.APIKey = "flag-key"
+	cfg.Providers["local"] = prov
+	cfg.Default.Model = "big"
+
+	edited := strings.Replace(reloadConfig, "[read_file]", "[read_file, list_directory]", 1)
+	edited = strings.Replace(edited, "http://localhost:8080/v1", "http://localhost:9090/v1", 1)
+	edited += "mcp_servers:\n  docs: {command: docs-mcp}\n"
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Fatal("the edit should be noticed")
+	}
+	if w.Changed() {
+		t.Fatal("Changed should report an edit once")
+	}
+
+	next, changes, err := cfg.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"+ mcp_servers.docs.command: docs-mcp",
+		"~ providers.local.base_url: http://localhost:8080/v1 -> http://localhost:9090/v1",
+		"~ tools.auto_approve_tools: [read_file] -> [read_file, list_directory]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if next.Providers["local"].APIKey != "flag-key" || next.Default.Model != "big" {
+		t.Errorf("runtime changes lost: key %q, model %q", next.Providers["local"].APIKey, next.Default.Model)
+	}
+	if next.Providers["local"].BaseURL != "http://localhost:9090/v1" || next.Tools.MCPServers["docs"].Command != "docs-mcp" {
+		t.Errorf("edits not applied: %+v, %+v", next.Providers["local"], next.Tools.MCPServers)
+	}
+
+	// Editing the setting that was overridden applies the edit.
+	edited = strings.Replace(edited, "default:\n  provider: local\n  model: small", "default:\n  provider: local\n  model: small-v2", 1)
+	edited = strings.Replace(edited, "small: {model: small-model}", "small-v2: {model: small-model-v2}", 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	next2, _, err := next.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next2.Default.Model != "small-v2" {
+		t.Errorf("default.model = %q, want the edited one", next2.Default.Model)
+	}
+}
+
+func TestReloadRefusesInvalidConfig(t *testing.T) {
+	path := writeUserConfig(t, reloadConfig)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(reloadConfig+"  command_timeout: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if _, _, err := cfg.Reload(); !errors.As(err, &verr) || !strings.Contains(err.Error(), "tools.command_timeout") {
+		t.Errorf("err = %v, want a *ValidationError", err)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Replace(reloadConfig, "model: small\n", "model: missing\n", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := cfg.Reload(); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("err = %v, want the missing model", err)
+	}
+
+	if _, _, err := (&Config{}).Reload(); err == nil {
+		t.Error("a configuration not read from files cannot be reloaded")
+	}
+}
+
+func TestReloadRedactsSecrets(t *testing.T) {
+	path := writeUserConfig(t, reloadConfig)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Replace(reloadConfig, "file-key", "new-key", 1)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, changes, err := cfg.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].String() != "~ providers.local.api_key: <redacted> -> <redacted>" {
+		t.Fatalf("changes = %v", changes)
+	}
+}
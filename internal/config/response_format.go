@@ -0,0 +1,111 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tokuhirom/ashron/internal/jsonschema"
+)
+
+// Response format types.
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormatTypes lists every supported response format type.
+var ResponseFormatTypes = []string{ResponseFormatText, ResponseFormatJSONObject, ResponseFormatJSONSchema}
+
+// ResponseFormat constrains the shape of the model's answers.
+type ResponseFormat struct {
+	Type string
+	// Name identifies the schema to the provider; json_schema only.
+	Name string
+	// Strict asks the provider to enforce the schema while decoding; nil
+	// leaves the provider default.
+	Strict *bool
+	// Schema is the JSON Schema document; json_schema only.
+	Schema json.RawMessage
+}
+
+// rawResponseFormat accepts either a bare type ("json_object") or a mapping
+// with an inline schema or a schema_file:
+//
+//	response_format:
+//	  type: json_schema
+//	  name: review
+//	  strict: true
+//	  schema_file: schemas/review.json   # relative to the config file
+type rawResponseFormat struct {
+	Type       string `yaml:"type"`
+	Name       string `yaml:"name"`
+	Strict     *bool  `yaml:"strict"`
+	Schema     any    `yaml:"schema"`
+	SchemaFile string `yaml:"schema_file"`
+}
+
+func (r *rawResponseFormat) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&r.Type)
+	}
+	type plain rawResponseFormat
+	return node.Decode((*plain)(r))
+}
+
+// convertResponseFormat resolves schema_file relative to baseDir and
+// converts an inline YAML schema to JSON.
+func convertResponseFormat(raw *rawResponseFormat, baseDir string) (*ResponseFormat, error) {
+	if raw == nil || (raw.Type == "" && raw.Schema == nil && raw.SchemaFile == "") {
+		return nil, nil
+	}
+	rf := &ResponseFormat{Type: raw.Type, Name: raw.Name, Strict: raw.Strict}
+	switch {
+	case raw.Schema != nil && raw.SchemaFile != "":
+		return nil, fmt.Errorf("schema and schema_file are mutually exclusive")
+	case raw.SchemaFile != "":
+		data, err := os.ReadFile(resolvePath(raw.SchemaFile, baseDir))
+		if err != nil {
+			return nil, fmt.Errorf("read schema_file: %w", err)
+		}
+		rf.Schema = data
+	case raw.Schema != nil:
+		data, err := json.Marshal(raw.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("convert schema to JSON: %w", err)
+		}
+		rf.Schema = data
+	}
+	if rf.Type == "" && rf.Schema != nil {
+		rf.Type = ResponseFormatJSONSchema
+	}
+	if rf.Type == ResponseFormatJSONSchema && rf.Name == "" {
+		rf.Name = "response"
+	}
+	return rf, nil
+}
+
+// validate checks the format type and compiles the schema.
+func (rf *ResponseFormat) validate() error {
+	if !slices.Contains(ResponseFormatTypes, rf.Type) {
+		return fmt.Errorf("unknown type %q (supported: %s)", rf.Type, strings.Join(ResponseFormatTypes, ", "))
+	}
+	if rf.Type != ResponseFormatJSONSchema {
+		if rf.Schema != nil {
+			return fmt.Errorf("schema requires type %q", ResponseFormatJSONSchema)
+		}
+		return nil
+	}
+	if rf.Schema == nil {
+		return fmt.Errorf("type %q requires schema or schema_file", rf.Type)
+	}
+	if _, err := jsonschema.Compile(rf.Schema); err != nil {
+		return err
+	}
+	return nil
+}
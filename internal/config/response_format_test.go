@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestResponseFormatYAML(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "review.json"), []byte(`{"type":"object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		yaml string
+		want ResponseFormat
+	}{
+		{`json_object`, ResponseFormat{Type: ResponseFormatJSONObject}},
+		{`{schema_file: review.json, strict: true}`, ResponseFormat{Type: ResponseFormatJSONSchema, Name: "response", Schema: []byte(`{"type":"object"}`)}},
+		{"name: item\nschema:\n  type: object\n  required: [id]", ResponseFormat{Type: ResponseFormatJSONSchema, Name: "item", Schema: []byte(`{"required":["id"],"type":"object"}`)}},
+	}
+	for _, tt := range tests {
+		var raw rawResponseFormat
+		if err := yaml.Unmarshal([]byte(tt.yaml), &raw); err != nil {
+			t.Fatalf("%s: %v", tt.yaml, err)
+		}
+		got, err := convertResponseFormat(&raw, dir)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.yaml, err)
+		}
+		if got.Type != tt.want.Type || got.Name != tt.want.Name || string(got.Schema) != string(tt.want.Schema) {
+			t.Errorf("%s: got %+v (schema %s), want %+v", tt.yaml, got, got.Schema, tt.want)
+		}
+		if err := got.validate(); err != nil {
+			t.Errorf("%s: validate: %v", tt.yaml, err)
+		}
+	}
+}
+
+func TestResponseFormatValidate(t *testing.T) {
+	for _, tt := range []struct {
+		rf   ResponseFormat
+		want string
+	}{
+		{ResponseFormat{Type: "xml"}, "unknown type"},
+		{ResponseFormat{Type: ResponseFormatJSONSchema}, "requires schema"},
+		{ResponseFormat{Type: ResponseFormatJSONObject, Schema: []byte(`{}`)}, "schema requires type"},
+		{ResponseFormat{Type: ResponseFormatJSONSchema, Schema: []byte(`{"$ref":"#/nope"}`)}, "unresolvable reference"},
+	} {
+		if err := tt.rf.validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: got %v, want error containing %q", tt.rf, err, tt.want)
+		}
+	}
+}
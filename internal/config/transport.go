@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Values of ProviderConfig.AuthScheme.
+const (
+	// AuthSchemeBearer sends "Authorization: Bearer <key>".
+	AuthSchemeBearer = "bearer"
+	// AuthSchemeNone sends no key, e.g. when a gateway authenticates the
+	// request through Headers.
+	AuthSchemeNone = "none"
+	// AuthSchemeHeaderPrefix followed by a header name ("header:api-key")
+	// sends the bare key in that header.
+	AuthSchemeHeaderPrefix = "header:"
+)
+
+// ProxyDirect as ProviderConfig.Proxy connects without a proxy even when
+// the proxy environment variables are set.
+const ProxyDirect = "direct"
+
+// AuthHeader returns the header an "header:<name>" auth scheme sends the
+// key in, or "" for other schemes.
+func (p *ProviderConfig) AuthHeader() string {
+	name, _ := strings.CutPrefix(p.AuthScheme, AuthSchemeHeaderPrefix)
+	if name == p.AuthScheme {
+		return ""
+	}
+	return http.CanonicalHeaderKey(name)
+}
+
+// validateTransport checks the auth scheme, headers and proxy URL.
+func (p *ProviderConfig) validateTransport() error {
+	switch {
+	case p.AuthScheme == "", p.AuthScheme == AuthSchemeBearer, p.AuthScheme == AuthSchemeNone:
+	case p.AuthHeader() != "":
+	default:
+		return fmt.Errorf("unknown auth_scheme %q (supported: %s, %s, %s<name>)",
+			p.AuthScheme, AuthSchemeBearer, AuthSchemeNone, AuthSchemeHeaderPrefix)
+	}
+	for name := range p.Headers {
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+	if p.Proxy != "" && p.Proxy != ProxyDirect {
+		u, err := url.Parse(p.Proxy)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid proxy %q: want a URL such as http://proxy.example.com:8080 or %q", p.Proxy, ProxyDirect)
+		}
+	}
+	return nil
+}
+
+// resolvePath makes a relative path from the config file relative to the
+// directory the file is in.
+func resolvePath(path, baseDir string) string {
+	if path == "" || filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
@@ -0,0 +1,41 @@
+package config
+
+import "testing"
+
+func TestValidateTransport(t *testing.T) {
+	valid := []ProviderConfig{
+		{},
+		{AuthScheme: AuthSchemeBearer},
+		{AuthScheme: AuthSchemeNone, Headers: map[string]string{"X-Org-Id": "acme"}},
+		{AuthScheme: "header:api-key", Proxy: "http://proxy.example.com:8080"},
+		{Proxy: ProxyDirect},
+	}
+	for _, p := range valid {
+		if err := p.validateTransport(); err != nil {
+			t.Errorf("%+v: unexpected error: %v", p, err)
+		}
+	}
+
+	invalid := []ProviderConfig{
+		{AuthScheme: "basic"},
+		{AuthScheme: "header:"},
+		{Headers: map[string]string{"X Org": "acme"}},
+		{Proxy: "proxy.example.com:8080"},
+	}
+	for _, p := range invalid {
+		if err := p.validateTransport(); err == nil {
+			t.Errorf("%+v: expected an error", p)
+		}
+	}
+}
+
+func TestAuthHeader(t *testing.T) {
+	p := ProviderConfig{AuthScheme: "header:x-api-key"}
+	if got := p.AuthHeader(); got != "X-Api-Key" {
+		t.Fatalf("AuthHeader() = %q", got)
+	}
+	p.AuthScheme = AuthSchemeBearer
+	if got := p.AuthHeader(); got != "" {
+		t.Fatalf("AuthHeader() = %q, want empty", got)
+	}
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config layers are merged as YAML node trees rather than decoded values, so
+// scalars keep their exact text (a date-like api_version stays a string) and
+// their line numbers.
+
+// parseTree parses a config file into its top-level mapping node.
+func parseTree(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// mapValue returns the value under key in a mapping node, or nil.
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mapDelete removes key from a mapping node.
+func mapDelete(n *yaml.Node, key string) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = slices.Delete(n.Content, i, i+2)
+			return
+		}
+	}
+}
+
+// mapSet sets key in a mapping node, replacing an existing value.
+func mapSet(n *yaml.Node, key string, v *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = v
+			return
+		}
+	}
+	n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
+
+func getPath(n *yaml.Node, path string) *yaml.Node {
+	head, rest, more := strings.Cut(path, ".")
+	v := mapValue(n, head)
+	if !more || v == nil {
+		return v
+	}
+	return getPath(v, rest)
+}
+
+// setPath sets the value at a dotted path, creating mappings on the way.
+func setPath(n *yaml.Node, path string, v *yaml.Node) {
+	head, rest, more := strings.Cut(path, ".")
+	if !more {
+		mapSet(n, head, v)
+		return
+	}
+	sub := mapValue(n, head)
+	if sub == nil || sub.Kind != yaml.MappingNode {
+		sub = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		mapSet(n, head, sub)
+	}
+	setPath(sub, rest, v)
+}
+
+func deletePath(n *yaml.Node, path string) {
+	head, rest, more := strings.Cut(path, ".")
+	if !more {
+		mapDelete(n, head)
+		return
+	}
+	if sub := mapValue(n, head); sub != nil && sub.Kind == yaml.MappingNode {
+		deletePath(sub, rest)
+	}
+}
+
+// matchPaths returns the paths in n matching a dotted pattern, where "*"
+// matches any one key.
+func matchPaths(n *yaml.Node, pattern string) []string {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	head, rest, more := strings.Cut(pattern, ".")
+	var out []string
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i].Value
+		if head != "*" && k != head {
+			continue
+		}
+		if !more {
+			out = append(out, k)
+			continue
+		}
+		for _, p := range matchPaths(n.Content[i+1], rest) {
+			out = append(out, k+"."+p)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// stringList builds a sequence node of strings.
+func stringList(list []string) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, s := range list {
+		seq.Content = append(seq.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s})
+	}
+	return seq
+}
+
+// mergeTree merges the mapping over into base: mappings merge key by key,
+// lists are appended unless their path is in replace, and anything else in
+// over wins. The layer of every value over sets is recorded in sources.
+func mergeTree(base, over *yaml.Node, prefix string, replace []string, sources map[string]string, layer string) {
+	for i := 0; i+1 < len(over.Content); i += 2 {
+		k, v := over.Content[i].Value, over.Content[i+1]
+		path := joinPath(prefix, k)
+		old := mapValue(base, k)
+		if old != nil && !slices.Contains(replace, path) {
+			if old.Kind == yaml.MappingNode && v.Kind == yaml.MappingNode {
+				mergeTree(old, v, path, replace, sources, layer)
+				continue
+			}
+			if old.Kind == yaml.SequenceNode && v.Kind == yaml.SequenceNode {
+				old.Content = append(old.Content, v.Content...)
+				sources[path] = joinLayers(append(strings.Split(sources[path], "+"), layer))
+				continue
+			}
+		}
+		for p := range sources {
+			if p == path || strings.HasPrefix(p, path+".") {
+				delete(sources, p)
+			}
+		}
+		mapSet(base, k, v)
+		markSources(v, path, sources, layer)
+	}
+}
+
+func markSources(n *yaml.Node, path string, sources map[string]string, layer string) {
+	if n.Kind == yaml.MappingNode && len(n.Content) > 0 {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			markSources(n.Content[i+1], joinPath(path, n.Content[i].Value), sources, layer)
+		}
+		return
+	}
+	sources[path] = layer
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
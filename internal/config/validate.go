@@ -0,0 +1,271 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue is a problem found in a config file.
+type Issue struct {
+	File string
+	Line int
+	// Key is the dotted path of the value, e.g. "tools.command_timeout".
+	Key     string
+	Message string
+	// Warning issues do not stop ashron from starting.
+	Warning bool
+}
+
+func (i Issue) String() string {
+	var sb strings.Builder
+	if i.Warning {
+		sb.WriteString("warning: ")
+	} else {
+		sb.WriteString("error: ")
+	}
+	if i.File != "" {
+		sb.WriteString(i.File)
+		if i.Line > 0 {
+			fmt.Fprintf(&sb, ":%d", i.Line)
+		}
+		sb.WriteString(": ")
+	}
+	if i.Key != "" {
+		sb.WriteString(i.Key + ": ")
+	}
+	sb.WriteString(i.Message)
+	return sb.String()
+}
+
+// ValidationError reports the config file issues that stop ashron from
+// starting, along with any warnings.
+type ValidationError struct {
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	errors := 0
+	for _, issue := range e.Issues {
+		if !issue.Warning {
+			errors++
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d error(s) in the config files:", errors)
+	for _, issue := range e.Issues {
+		sb.WriteString("\n  " + issue.String())
+	}
+	return sb.String()
+}
+
+// hasErrors reports whether any issue is more than a warning.
+func hasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if !issue.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeIssues turns the type errors from decoding a config file into
+// issues, or returns nil for other errors.
+func decodeIssues(path string, err error) []Issue {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return nil
+	}
+	var issues []Issue
+	for _, msg := range typeErr.Errors {
+		issue := Issue{File: path, Message: msg}
+		if rest, ok := strings.CutPrefix(msg, "line "); ok {
+			if num, text, ok := strings.Cut(rest, ": "); ok {
+				if line, err := strconv.Atoi(num); err == nil {
+					issue.Line, issue.Message = line, text
+				}
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// durationKeys are the keys holding durations such as "30s".
+var durationKeys = []string{
+	"tools.command_timeout",
+	"profiles.*.tools.command_timeout",
+	"providers.*.timeout",
+	"providers.*.connect_timeout",
+	"providers.*.first_token_timeout",
+	"providers.*.idle_timeout",
+	"providers.*.api_key_command_ttl",
+	"providers.*.retry.base_delay",
+	"providers.*.retry.max_delay",
+	"mcp_servers.*.startup_timeout",
+	"mcp_servers.*.call_timeout",
+}
+
+// keyHints explain keys people expect to exist.
+var keyHints = map[string]string{
+	"yolo": "YOLO mode is only enabled with the --yolo flag",
+}
+
+// checkTree checks one config file: unknown keys, durations and the
+// /regexp/ rules of auto_approve_commands. extraKeys are accepted at the top
+// level besides the config's own.
+func checkTree(path string, tree *yaml.Node, extraKeys ...string) []Issue {
+	var issues []Issue
+	checkKeys(tree, reflect.TypeFor[rawConfig](), "", path, extraKeys, &issues)
+
+	// Profiles are kept as YAML until applied, so decoding the file does
+	// not check their types.
+	for _, key := range matchPaths(tree, "profiles.*") {
+		if err := getPath(tree, key).Decode(&profileSchema{}); err != nil {
+			if found := decodeIssues(path, err); found != nil {
+				issues = append(issues, found...)
+			} else {
+				issues = append(issues, Issue{File: path, Key: key, Message: err.Error()})
+			}
+		}
+	}
+
+	for _, pattern := range durationKeys {
+		for _, key := range matchPaths(tree, pattern) {
+			v := getPath(tree, key)
+			if v.Kind != yaml.ScalarNode || v.Value == "" {
+				continue
+			}
+			if _, err := time.ParseDuration(v.Value); err != nil {
+				issues = append(issues, Issue{File: path, Line: v.Line, Key: key,
+					Message: fmt.Sprintf("invalid duration %q (use e.g. 30s, 5m or 1h30m)", v.Value)})
+			}
+		}
+	}
+
+	keys := append([]string{"tools.auto_approve_commands"}, matchPaths(tree, "profiles.*.tools.auto_approve_commands")...)
+	for _, key := range keys {
+		cmds := getPath(tree, key)
+		if cmds == nil || cmds.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, c := range cmds.Content {
+			pattern, ok := strings.CutPrefix(c.Value, "/")
+			if !ok || len(pattern) == 0 || !strings.HasSuffix(pattern, "/") {
+				continue
+			}
+			if _, err := regexp.Compile(strings.TrimSuffix(pattern, "/")); err != nil {
+				issues = append(issues, Issue{File: path, Line: c.Line, Key: key,
+					Message: fmt.Sprintf("invalid regexp %s: %v", c.Value, err)})
+			}
+		}
+	}
+	return issues
+}
+
+// checkKeys warns about keys in n that the config type t does not have.
+// Type mismatches are left to decoding, which reports them with lines.
+func checkKeys(n *yaml.Node, t reflect.Type, prefix, path string, extraKeys []string, issues *[]Issue) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeFor[rawProfile]() {
+		t = reflect.TypeFor[profileSchema]()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k := n.Content[i]
+			key := joinPath(prefix, k.Value)
+			ft, ok := fields[k.Value]
+			if !ok {
+				if prefix == "" && slices.Contains(extraKeys, k.Value) {
+					continue
+				}
+				*issues = append(*issues, Issue{File: path, Line: k.Line, Key: key,
+					Message: unknownKeyMessage(k.Value, fields), Warning: true})
+				continue
+			}
+			checkKeys(n.Content[i+1], ft, key, path, nil, issues)
+		}
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			checkKeys(n.Content[i+1], t.Elem(), joinPath(prefix, n.Content[i].Value), path, nil, issues)
+		}
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return
+		}
+		for _, c := range n.Content {
+			checkKeys(c, t.Elem(), prefix, path, nil, issues)
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of a struct type to their field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func unknownKeyMessage(key string, fields map[string]reflect.Type) string {
+	if hint, ok := keyHints[key]; ok {
+		return "unknown key; " + hint
+	}
+	best, bestDist := "", len(key)/3+2
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist || d == bestDist && name < best {
+			best, bestDist = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf("unknown key (did you mean %q?)", best)
+	}
+	return "unknown key"
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
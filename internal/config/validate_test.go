@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadLayersReportsIssues(t *testing.T) {
+	_, err := loadLayers("/etc/ashron.yaml", []byte(`default:
+  provider: local
+providers:
+  local:
+    type: openai-compat
+    timeout: 5 minutes
+    retry:
+      max_delay: 30s
+    models:
+      m: {model: m, temprature: 0.2}
+tools:
+  yolo: true
+  auto_aprove_tools: [read_file]
+  auto_approve_commands: ["/git (add/", "/go test .*/"]
+  max_output_size: lots
+`), "")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("err = %v, want a *ValidationError", err)
+	}
+	var got []string
+	for _, issue := range verr.Issues {
+		got = append(got, issue.String())
+	}
+	want := []string{
+		`warning: /etc/ashron.yaml:10: providers.local.models.m.temprature: unknown key (did you mean "temperature"?)`,
+		`warning: /etc/ashron.yaml:12: tools.yolo: unknown key; YOLO mode is only enabled with the --yolo flag`,
+		`warning: /etc/ashron.yaml:13: tools.auto_aprove_tools: unknown key (did you mean "auto_approve_tools"?)`,
+		`error: /etc/ashron.yaml:6: providers.local.timeout: invalid duration "5 minutes" (use e.g. 30s, 5m or 1h30m)`,
+		"error: /etc/ashron.yaml:14: tools.auto_approve_commands: invalid regexp /git (add/: error parsing regexp: missing closing ): `git (add`",
+		"error: /etc/ashron.yaml:15: cannot unmarshal !!str `lots` into int",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadLayersKeepsWarnings(t *testing.T) {
+	l, err := loadLayers("user.yaml", []byte("debgu: true\n"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l.warnings) != 1 || l.warnings[0].Key != "debgu" || !strings.Contains(l.warnings[0].Message, `"debug"`) {
+		t.Fatalf("warnings = %+v", l.warnings)
+	}
+}
+
+func writeUserConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Chdir(t.TempDir())
+	path := filepath.Join(dir, "ashron", "ashron.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckIncludesValidateErrors(t *testing.T) {
+	writeUserConfig(t, `
+default:
+  provider: local
+  model: missing
+providers:
+  local:
+    type: openai-compat
+    base_url: http://localhost:8080/v1
+    models:
+      m: {model: m}
+tools:
+  sandbox: off
+`)
+	issues, err := Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || !issues[0].Warning || issues[0].Key != "tools.sandbox" ||
+		issues[1].Warning || !strings.Contains(issues[1].Message, "missing") {
+		t.Fatalf("issues = %+v", issues)
+	}
+}
+
+func TestShow(t *testing.T) {
+	t.Setenv("ASHRON_TEST_KEY", "sk-secret")
+	writeUserConfig(t, `
+# my providers
+default:
+  provider: local
+  model: m
+providers:
+  local:
+    type: openai-compat
+    base_url: http://localhost:8080/v1
+    api_key: ${ASHRON_TEST_KEY}
+    headers: {X-Team: core, Authorization: Bearer abc}
+    models:
+      m: {model: m}
+tools:
+  auto_approve_tools:
+    - read_file
+`)
+	var sb strings.Builder
+	if err := Show(&sb, false, ""); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{"base_url: http://localhost:8080/v1 # user", "api_key: <redacted> # user", "X-Team: core # user", "Authorization: <redacted> # user", "auto_approve_tools: [read_file] # user"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("show output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "sk-secret") || strings.Contains(out, "my providers") {
+		t.Errorf("show output leaks the key or old comments:\n%s", out)
+	}
+
+	sb.Reset()
+	if err := Show(&sb, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	out = sb.String()
+	for _, want := range []string{"max_output_size: 50000", "command_timeout: 10m", "api_key: <redacted>", "- read_file"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("resolved output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "temperature") || strings.Contains(out, "sk-secret") {
+		t.Errorf("resolved output should drop unset values and keys:\n%s", out)
+	}
+}
+
+func TestFilesListsIncludesAndProject(t *testing.T) {
+	path := writeUserConfig(t, "include: extra.yaml\n")
+	extra := filepath.Join(filepath.Dir(path), "extra.yaml")
+	if err := os.WriteFile(extra, []byte("debug: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	project := writeProjectConfig(t, "debug: false\n")
+	t.Chdir(filepath.Dir(filepath.Dir(project)))
+	got := Files()
+	if len(got) != 3 || got[0] != path || got[1] != extra || got[2] != project {
+		t.Fatalf("Files() = %v", got)
+	}
+}
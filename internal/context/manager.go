@@ -1,8 +1,13 @@
 package context
 
 import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+
 	"github.com/tokuhirom/ashron/internal/api"
 	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/tokenizer"
 )
 
 const (
@@ -21,30 +26,157 @@ const (
 	// summarizeRatio is the fraction of MaxTokens at which full LLM-based
 	// summarization is triggered. This is the last resort.
 	summarizeRatio = 0.90
+
+	// calibrationWeight is how much each Calibrate call moves the scale
+	// toward the latest observed ratio (exponential moving average).
+	calibrationWeight = 0.5
 )
 
 // Manager handles context management and compaction
 type Manager struct {
 	config *config.ContextConfig
+
+	// mu guards the fields below, which are read while rendering and
+	// updated from the request goroutine.
+	mu        sync.Mutex
+	tokenizer tokenizer.Tokenizer
+	// toolTokens is the token count of the tool definitions sent with each
+	// request (see SetTools).
+	toolTokens int
+	// scale corrects the tokenizer estimate using the prompt token counts
+	// reported by the API. It is 1 until the first Calibrate call.
+	scale float64
+	// counted holds the token count of each message last counted, by index,
+	// so a growing history only tokenizes its new messages.
+	counted []countedMessage
+}
+
+// countedMessage is a message together with its uncalibrated token count.
+type countedMessage struct {
+	msg    api.Message
+	tokens int
 }
 
-// NewManager creates a new context manager
+// NewManager creates a new context manager that estimates tokens as ~4
+// characters each until SetTokenizer is called.
 func NewManager(cfg *config.ContextConfig) *Manager {
+	tok, _ := tokenizer.New(tokenizer.Chars)
 	return &Manager{
-		config: cfg,
+		config:    cfg,
+		tokenizer: tok,
+		scale:     1,
 	}
 }
 
-// GetTokenUsage estimates the token usage of messages using ~4 chars per token.
-func (m *Manager) GetTokenUsage(messages []api.Message) int {
-	totalChars := 0
-	for _, msg := range messages {
-		totalChars += len(msg.Content)
-		for _, tc := range msg.ToolCalls {
-			totalChars += len(tc.Function.Name) + len(tc.Function.Arguments)
+// SetTokenizer replaces the tokenizer and resets calibration.
+func (m *Manager) SetTokenizer(tok tokenizer.Tokenizer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokenizer = tok
+	m.scale = 1
+	m.counted = nil
+}
+
+// TokenizerName returns the name of the tokenizer in use.
+func (m *Manager) TokenizerName() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.tokenizer.Name()
+}
+
+// SetTools records the tool definitions sent with requests so their tokens
+// are included in GetTokenUsage.
+func (m *Manager) SetTools(tools []api.Tool) {
+	var data []byte
+	if len(tools) > 0 {
+		var err error
+		if data, err = json.Marshal(tools); err != nil {
+			slog.Warn("Failed to marshal tools for token counting", "error", err)
+			data = nil
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolTokens = m.tokenizer.Count(string(data))
+}
+
+// imageTokens is the estimated cost of one image. Providers charge by
+// resolution; about a thousand tokens covers a typical screenshot.
+const imageTokens = 1000
+
+// countTokens returns the uncalibrated token count of messages plus tools.
+// Messages unchanged since the last call at the same index are not
+// tokenized again. m.mu must be held.
+func (m *Manager) countTokens(messages []api.Message) int {
+	total := m.toolTokens
+	for i, msg := range messages {
+		if i < len(m.counted) && sameTokens(&m.counted[i].msg, &msg) {
+			total += m.counted[i].tokens
+			continue
+		}
+		entry := countedMessage{msg: msg, tokens: m.messageTokens(&msg)}
+		if i < len(m.counted) {
+			m.counted[i] = entry
+		} else {
+			m.counted = append(m.counted, entry)
 		}
+		total += entry.tokens
 	}
-	return totalChars / 4
+	m.counted = m.counted[:len(messages)]
+	return total
+}
+
+// messageTokens returns the uncalibrated token count of one message.
+func (m *Manager) messageTokens(msg *api.Message) int {
+	total := m.tokenizer.Count(msg.Content)
+	total += m.tokenizer.Count(msg.ReasoningContent)
+	total += len(msg.Images()) * imageTokens
+	for _, tc := range msg.ToolCalls {
+		total += m.tokenizer.Count(tc.Function.Name) + m.tokenizer.Count(tc.Function.Arguments)
+	}
+	return total
+}
+
+// sameTokens reports whether a and b have the same counted content. Text
+// parts are mirrored in Content, so only the number of parts is compared.
+func sameTokens(a, b *api.Message) bool {
+	if a.Content != b.Content || a.ReasoningContent != b.ReasoningContent ||
+		len(a.Parts) != len(b.Parts) || len(a.ToolCalls) != len(b.ToolCalls) {
+		return false
+	}
+	for i := range a.ToolCalls {
+		if a.ToolCalls[i].Function != b.ToolCalls[i].Function {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTokenUsage estimates the token usage of messages and the current tool
+// definitions, corrected by the calibration scale.
+func (m *Manager) GetTokenUsage(messages []api.Message) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int(float64(m.countTokens(messages)) * m.scale)
+}
+
+// Calibrate adjusts future estimates using promptTokens, the prompt token
+// count the API reported for a request containing messages.
+func (m *Manager) Calibrate(messages []api.Message, promptTokens int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	estimated := m.countTokens(messages)
+	if estimated <= 0 || promptTokens <= 0 {
+		return
+	}
+	// Clamp so a single odd response (e.g. cached prompt accounting) cannot
+	// throw the estimate off by more than 4x in either direction.
+	ratio := min(max(float64(promptTokens)/float64(estimated), 0.25), 4)
+	m.scale += (ratio - m.scale) * calibrationWeight
+	slog.Debug("Calibrated token estimate",
+		slog.Int("estimated", estimated),
+		slog.Int("actual", promptTokens),
+		slog.Float64("scale", m.scale))
 }
 
 // CompactionStatus returns the current estimated token usage, the summarization
@@ -108,9 +240,10 @@ func (m *Manager) CompactionLevel(messages []api.Message) CompactionLevel {
 	return CompactionNone
 }
 
-// Prune reduces token usage by truncating large tool outputs in messages
-// outside the recent window without removing messages or breaking message
-// pairing. This is a cheap pre-step before LLM-based summarization.
+// Prune reduces token usage by truncating large tool outputs and dropping
+// tool images in messages outside the recent window without removing
+// messages or breaking message pairing. This is a cheap pre-step before
+// LLM-based summarization.
 func (m *Manager) Prune(messages []api.Message) []api.Message {
 	if len(messages) <= recentMessagesToKeep {
 		return messages
@@ -119,7 +252,14 @@ func (m *Manager) Prune(messages []api.Message) []api.Message {
 	result := make([]api.Message, len(messages))
 	copy(result, messages)
 	for i := 0; i < cutoff; i++ {
-		if result[i].Role == "tool" && len(result[i].Content) > toolOutputTruncateLen {
+		if result[i].Role != "tool" {
+			continue
+		}
+		if len(result[i].Parts) > 0 {
+			result[i].Parts = nil
+			result[i].Content += "\n[image removed]"
+		}
+		if len(result[i].Content) > toolOutputTruncateLen {
 			result[i].Content = result[i].Content[:toolOutputTruncateLen] + "\n[truncated]"
 		}
 	}
@@ -168,6 +168,22 @@ func TestPrune_TruncatesOldToolOutput(t *testing.T) {
 	}
 }
 
+func TestPrune_DropsOldToolImages(t *testing.T) {
+	t.Parallel()
+	mgr := NewManager(&config.ContextConfig{MaxTokens: 10000})
+	msgs := makeMessages(make([]string, 25)...)
+	msgs[2] = api.NewToolMessage("tc1", "Image file a.png")
+	msgs[2].AttachImages(api.NewImagePart("image/png", []byte("png")))
+
+	pruned := mgr.Prune(msgs)
+	if pruned[2].Parts != nil || !strings.Contains(pruned[2].Content, "[image removed]") {
+		t.Fatalf("expected image to be dropped: %+v", pruned[2])
+	}
+	if len(msgs[2].Parts) == 0 {
+		t.Fatal("original messages must not be modified")
+	}
+}
+
 func TestBuildCompacted_PreservesSystemMessages(t *testing.T) {
 	t.Parallel()
 	mgr := NewManager(&config.ContextConfig{MaxTokens: 10000})
@@ -226,3 +242,109 @@ func TestBuildCompacted_NoScratchpadWhenEmpty(t *testing.T) {
 		}
 	}
 }
+
+func TestGetTokenUsage_CountsTools(t *testing.T) {
+	t.Parallel()
+	mgr := NewManager(&config.ContextConfig{MaxTokens: 1000})
+	msgs := []api.Message{{Role: "user", Content: strings.Repeat("x", 400)}}
+	before := mgr.GetTokenUsage(msgs)
+	mgr.SetTools([]api.Tool{{Type: "function", Function: api.FunctionDef{
+		Name:        "read_file",
+		Description: strings.Repeat("d", 400),
+	}}})
+	if after := mgr.GetTokenUsage(msgs); after <= before+100 {
+		t.Fatalf("tool definitions should be counted: before=%d after=%d", before, after)
+	}
+	mgr.SetTools(nil)
+	if got := mgr.GetTokenUsage(msgs); got != before {
+		t.Fatalf("expected %d after clearing tools, got %d", before, got)
+	}
+}
+
+func TestCalibrate_MovesEstimateTowardActual(t *testing.T) {
+	t.Parallel()
+	mgr := NewManager(&config.ContextConfig{MaxTokens: 1000})
+	msgs := []api.Message{{Role: "user", Content: strings.Repeat("x", 400)}} // 100 tokens by chars/4
+	mgr.Calibrate(msgs, 300)
+	if got := mgr.GetTokenUsage(msgs); got != 200 {
+		t.Fatalf("expected scale halfway to 3x (200 tokens), got %d", got)
+	}
+	for range 10 {
+		mgr.Calibrate(msgs, 300)
+	}
+	if got := mgr.GetTokenUsage(msgs); got < 295 || got > 300 {
+		t.Fatalf("expected estimate to converge on 300, got %d", got)
+	}
+	// Outliers are clamped to 4x.
+	mgr.Calibrate(msgs, 100000)
+	if got := mgr.GetTokenUsage(msgs); got > 400 {
+		t.Fatalf("calibration should be clamped, got %d", got)
+	}
+}
+
+// countingTokenizer is chars/4 that records the text it is asked to count.
+type countingTokenizer struct{ counted []string }
+
+func (c *countingTokenizer) Name() string { return "counting" }
+
+func (c *countingTokenizer) Count(text string) int {
+	if text != "" {
+		c.counted = append(c.counted, text)
+	}
+	return len(text) / 4
+}
+
+func TestGetTokenUsage_CountsOnlyNewMessages(t *testing.T) {
+	t.Parallel()
+	tok := &countingTokenizer{}
+	mgr := NewManager(&config.ContextConfig{MaxTokens: 1000})
+	mgr.SetTokenizer(tok)
+	msgs := []api.Message{
+		{Role: "user", Content: strings.Repeat("a", 40)},
+		{Role: "assistant", Content: strings.Repeat("b", 40)},
+	}
+	if got := mgr.GetTokenUsage(msgs); got != 20 {
+		t.Fatalf("usage = %d, want 20", got)
+	}
+	if got := mgr.GetTokenUsage(msgs); got != 20 || len(tok.counted) != 2 {
+		t.Fatalf("usage = %d after %d counts, want 20 after 2", got, len(tok.counted))
+	}
+
+	msgs = append(msgs, api.Message{Role: "user", Content: strings.Repeat("c", 40)})
+	if got := mgr.GetTokenUsage(msgs); got != 30 || len(tok.counted) != 3 {
+		t.Fatalf("usage = %d after %d counts, want 30 after 3", got, len(tok.counted))
+	}
+
+	// A message replaced at the same index, as pruning does, is counted again.
+	msgs[0].Content = "short"
+	if got := mgr.GetTokenUsage(msgs); got != 21 || len(tok.counted) != 4 || tok.counted[3] != "short" {
+		t.Fatalf("usage = %d, counted %q", got, tok.counted)
+	}
+}
+
+// TestManager_ConcurrentCalibrateAndRead is meant for go test -race: the
+// request goroutine calibrates while the UI renders the estimate.
+func TestManager_ConcurrentCalibrateAndRead(t *testing.T) {
+	t.Parallel()
+	mgr := NewManager(&config.ContextConfig{MaxTokens: 1000, AutoCompact: true})
+	msgs := makeMessages("user", "assistant", "user")
+	tools := []api.Tool{{Type: "function", Function: api.FunctionDef{Name: "read_file"}}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 200 {
+			mgr.SetTools(tools)
+			mgr.Calibrate(msgs, 50+i)
+		}
+	}()
+	for range 200 {
+		mgr.CompactionStatus(msgs)
+		mgr.CompactionLevel(msgs)
+		_ = mgr.TokenizerName()
+	}
+	<-done
+	if got := mgr.GetTokenUsage(msgs); got <= 0 {
+		t.Fatalf("usage = %d", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Package headless runs a single prompt to completion without a UI, so
+// scripts can use ashron and read the final answer from stdout.
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/jsonschema"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+// maxTurns bounds the model requests of one run, so a model that keeps
+// calling tools cannot run forever.
+const maxTurns = 100
+
+// Options configures Run.
+type Options struct {
+	// Schema is the JSON Schema the final answer must match, or nil for
+	// free-form text. An answer that does not match is sent back to the
+	// model with the violations, up to SchemaRetries times.
+	Schema        *jsonschema.Schema
+	SchemaRetries int
+	// Progress receives a line for each tool call. Optional.
+	Progress io.Writer
+}
+
+// Run sends prompt after messages (typically the system prompt) and lets
+// the model call tools until it gives a final answer, which is returned.
+// Tools that would need approval in the TUI are refused, since there is
+// nobody to ask; use --yolo or auto_approve_tools to allow them.
+func Run(ctx context.Context, cfg *config.Config, client *api.Client, messages []api.Message, prompt string, opts Options) (string, error) {
+	exec := tools.NewExecutor(&cfg.Tools, tools.NewResultStore())
+	var toolset tools.Toolset
+	builtinTools := toolset.Select(prompt)
+	messages = append(messages, api.NewUserMessage(prompt))
+
+	retries := 0
+	for turn := 0; turn < maxTurns; turn++ {
+		reply, err := complete(ctx, client, messages, builtinTools)
+		if err != nil {
+			return "", err
+		}
+		messages = append(messages, reply)
+
+		if len(reply.ToolCalls) > 0 {
+			for _, tc := range reply.ToolCalls {
+				messages = append(messages, runTool(cfg, exec, client, tc, opts.Progress))
+			}
+			continue
+		}
+
+		if opts.Schema == nil {
+			return reply.Content, nil
+		}
+		answer := jsonschema.ExtractJSON(reply.Content)
+		err = opts.Schema.Validate(answer)
+		if err == nil {
+			return string(answer), nil
+		}
+		if retries >= opts.SchemaRetries {
+			return "", fmt.Errorf("final answer does not match the output schema after %d retries: %w", retries, err)
+		}
+		retries++
+		slog.Info("Final answer does not match the output schema, retrying", "retry", retries, "error", err)
+		messages = append(messages, api.NewUserMessage(
+			err.Error()+"\n\nReply again with only the corrected JSON, without any other text."))
+	}
+	return "", fmt.Errorf("no final answer after %d model turns", maxTurns)
+}
+
+// runTool executes one tool call, or refuses it when it needs approval.
+func runTool(cfg *config.Config, exec *tools.Executor, client *api.Client, tc api.ToolCall, progress io.Writer) api.Message {
+	if !tools.IsAutoApproved(&cfg.Tools, tc) {
+		if progress != nil {
+			_, _ = fmt.Fprintf(progress, "refused %s (needs approval)\n", tc.Function.Name)
+		}
+		return api.NewToolMessage(tc.ID, "Tool execution was refused: it needs approval, and this is a non-interactive run.")
+	}
+	if progress != nil {
+		_, _ = fmt.Fprintf(progress, "%s %s\n", tc.Function.Name, tc.Function.Arguments)
+	}
+	return tools.ToolMessage(tc, exec.Execute(tc), client.SupportsVision())
+}
+
+// complete streams one model reply and assembles it into a message.
+func complete(ctx context.Context, client *api.Client, messages []api.Message, builtinTools []api.Tool) (api.Message, error) {
+	answeredBy := client.Name()
+	ctx = api.WithFallbackNotifier(ctx, func(info api.FallbackInfo) { answeredBy = info.To })
+	stream, err := client.StreamChatCompletionWithTools(ctx, messages, builtinTools)
+	if err != nil {
+		return api.Message{}, err
+	}
+
+	var (
+		content        strings.Builder
+		reasoningItems []json.RawMessage
+		reasoning      strings.Builder
+		calls          []*api.ToolCall
+		callsByIndex   = make(map[int]*api.ToolCall)
+	)
+	for ev := range stream {
+		if ev.Error != nil {
+			api.DrainStream(stream)
+			return api.Message{}, ev.Error
+		}
+		if ev.Data == nil || len(ev.Data.Choices) == 0 {
+			continue
+		}
+		delta := ev.Data.Choices[0].Delta
+		content.WriteString(delta.Content)
+		reasoningItems = append(reasoningItems, delta.ReasoningItems...)
+		reasoning.WriteString(delta.ReasoningContent)
+		for _, dtc := range delta.ToolCalls {
+			tc, ok := callsByIndex[dtc.Index]
+			if !ok {
+				tc = &api.ToolCall{ID: dtc.ID, Type: dtc.Type, Function: api.FunctionCall{Name: dtc.Function.Name}}
+				callsByIndex[dtc.Index] = tc
+				calls = append(calls, tc)
+			}
+			tc.Function.Arguments += dtc.Function.Arguments
+		}
+	}
+
+	reply := api.Message{
+		Role:           "assistant",
+		Content:        content.String(),
+		ReasoningItems: reasoningItems,
+		Model:          answeredBy,
+	}
+	if client.EchoesReasoning() {
+		reply.ReasoningContent = reasoning.String()
+	}
+	for _, tc := range calls {
+		if tc.Function.Arguments == "" {
+			tc.Function.Arguments = "{}"
+		}
+		reply.ToolCalls = append(reply.ToolCalls, *tc)
+	}
+	return reply, nil
+}
@@ -0,0 +1,155 @@
+package headless
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/jsonschema"
+)
+
+// scriptedServer answers each chat completion request with the next chunk
+// list in replies, as a server-sent event stream, and records the requests.
+type scriptedServer struct {
+	mu       sync.Mutex
+	replies  [][]string
+	requests []api.ChatCompletionRequest
+}
+
+func (s *scriptedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req api.ChatCompletionRequest
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	i := len(s.requests) - 1
+	s.mu.Unlock()
+	if i >= len(s.replies) {
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, chunk := range s.replies[i] {
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+}
+
+func contentChunk(text string) string {
+	data, _ := json.Marshal(text)
+	return `{"choices":[{"index":0,"delta":{"content":` + string(data) + `}}]}`
+}
+
+func toolCallChunk(id, name, args string) string {
+	data, _ := json.Marshal(args)
+	return `{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"` + id +
+		`","type":"function","function":{"name":"` + name + `","arguments":` + string(data) + `}}]}}]}`
+}
+
+func newTestRun(t *testing.T, s *scriptedServer) (*config.Config, *api.Client) {
+	t.Helper()
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	cfg := &config.Config{Tools: config.ToolsConfig{
+		AutoApproveTools: []string{"read_file"},
+		MaxOutputSize:    10000,
+	}}
+	client := api.NewClient(
+		&config.ProviderConfig{Type: config.ProviderTypeOpenAICompat, BaseURL: server.URL, Timeout: 5 * time.Second},
+		&config.ModelConfig{Model: "test"},
+		&config.ContextConfig{MaxTokens: 4096},
+	)
+	return cfg, client
+}
+
+func TestRunExecutesToolsUntilFinalAnswer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("the answer is 42"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args, _ := json.Marshal(map[string]string{"path": path})
+	s := &scriptedServer{replies: [][]string{
+		{toolCallChunk("call_1", "read_file", string(args))},
+		{contentChunk("It is "), contentChunk("42.")},
+	}}
+	cfg, client := newTestRun(t, s)
+
+	answer, err := Run(context.Background(), cfg, client, nil, "what is the answer?", Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != "It is 42." {
+		t.Fatalf("unexpected answer: %q", answer)
+	}
+	last := s.requests[1].Messages
+	if got := last[len(last)-1]; got.Role != "tool" || got.ToolCallID != "call_1" || !strings.Contains(got.Content, "the answer is 42") {
+		t.Fatalf("tool result not sent back: %+v", got)
+	}
+}
+
+func TestRunRefusesToolsNeedingApproval(t *testing.T) {
+	s := &scriptedServer{replies: [][]string{
+		{toolCallChunk("call_1", "execute_command", `{"command":"rm -rf /tmp/x"}`)},
+		{contentChunk("ok")},
+	}}
+	cfg, client := newTestRun(t, s)
+
+	if _, err := Run(context.Background(), cfg, client, nil, "clean up", Options{}); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	last := s.requests[1].Messages
+	if got := last[len(last)-1]; got.Role != "tool" || !strings.Contains(got.Content, "refused") {
+		t.Fatalf("expected a refusal tool result, got %+v", got)
+	}
+}
+
+func TestRunRetriesSchemaViolations(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(`{"type":"object","required":["count"],"properties":{"count":{"type":"integer"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scriptedServer{replies: [][]string{
+		{contentChunk(`{"count":"three"}`)},
+		{contentChunk("```json\n{\"count\":3}\n```")},
+	}}
+	cfg, client := newTestRun(t, s)
+
+	answer, err := Run(context.Background(), cfg, client, nil, "count", Options{Schema: schema, SchemaRetries: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if answer != `{"count":3}` {
+		t.Fatalf("unexpected answer: %q", answer)
+	}
+	last := s.requests[1].Messages
+	if got := last[len(last)-1]; got.Role != "user" || !strings.Contains(got.Content, "$.count: expected integer, got string") {
+		t.Fatalf("violations not sent back: %+v", got)
+	}
+}
+
+func TestRunFailsAfterSchemaRetries(t *testing.T) {
+	schema, err := jsonschema.Compile([]byte(`{"type":"object"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scriptedServer{replies: [][]string{
+		{contentChunk("not json")},
+		{contentChunk("still not json")},
+	}}
+	cfg, client := newTestRun(t, s)
+
+	_, err = Run(context.Background(), cfg, client, nil, "count", Options{Schema: schema, SchemaRetries: 1})
+	if err == nil || !strings.Contains(err.Error(), "after 1 retries") {
+		t.Fatalf("expected schema failure, got %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema that structured model output uses: type, enum, const, properties,
+// required, additionalProperties, items, length and range bounds, pattern,
+// allOf/anyOf/oneOf/not and local $ref. Unknown keywords (format, title,
+// description, ...) are ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrors caps the violations reported for one document.
+const maxErrors = 20
+
+// Schema is a compiled JSON Schema.
+type Schema struct {
+	raw      json.RawMessage
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// Compile parses a JSON Schema document.
+func Compile(data []byte) (*Schema, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	s := &Schema{raw: bytes.TrimSpace(data), root: root, patterns: make(map[string]*regexp.Regexp)}
+	if err := s.check(root, "#"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Raw returns the schema document as given to Compile.
+func (s *Schema) Raw() json.RawMessage {
+	return s.raw
+}
+
+// ValidationError lists the ways a document violates a schema.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "JSON does not match schema:\n- " + strings.Join(e.Errors, "\n- ")
+}
+
+// Validate checks that data is a JSON document matching the schema. It
+// returns a *ValidationError for schema violations.
+func (s *Schema) Validate(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return &ValidationError{Errors: []string{"invalid JSON: " + err.Error()}}
+	}
+	var errs []string
+	s.validate(s.root, v, "$", &errs, 0)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// check verifies the schema itself: keyword value types, regular
+// expressions and $ref targets.
+func (s *Schema) check(schema any, at string) error {
+	switch sch := schema.(type) {
+	case bool:
+		return nil
+	case map[string]any:
+		if ref, ok := sch["$ref"]; ok {
+			r, ok := ref.(string)
+			if !ok {
+				return fmt.Errorf("%s/$ref: must be a string", at)
+			}
+			if _, err := s.resolve(r); err != nil {
+				return fmt.Errorf("%s/$ref: %w", at, err)
+			}
+		}
+		if p, ok := sch["pattern"]; ok {
+			ps, ok := p.(string)
+			if !ok {
+				return fmt.Errorf("%s/pattern: must be a string", at)
+			}
+			re, err := regexp.Compile(ps)
+			if err != nil {
+				return fmt.Errorf("%s/pattern: %w", at, err)
+			}
+			s.patterns[ps] = re
+		}
+		for _, kw := range []string{"properties", "$defs", "definitions"} {
+			if m, ok := sch[kw]; ok {
+				props, ok := m.(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s/%s: must be an object", at, kw)
+				}
+				for name, sub := range props {
+					if err := s.check(sub, at+"/"+kw+"/"+name); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for _, kw := range []string{"items", "additionalProperties", "not"} {
+			if sub, ok := sch[kw]; ok {
+				if err := s.check(sub, at+"/"+kw); err != nil {
+					return err
+				}
+			}
+		}
+		for _, kw := range []string{"allOf", "anyOf", "oneOf"} {
+			if list, ok := sch[kw]; ok {
+				subs, ok := list.([]any)
+				if !ok {
+					return fmt.Errorf("%s/%s: must be an array", at, kw)
+				}
+				for i, sub := range subs {
+					if err := s.check(sub, at+"/"+kw+"/"+strconv.Itoa(i)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s: schema must be an object or a boolean", at)
+	}
+}
+
+// resolve follows a local reference such as "#/$defs/item".
+func (s *Schema) resolve(ref string) (any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("only local references are supported: %q", ref)
+	}
+	node := s.root
+	if pointer == "" {
+		return node, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+		if node, ok = m[token]; !ok {
+			return nil, fmt.Errorf("unresolvable reference %q", ref)
+		}
+	}
+	return node, nil
+}
+
+func (s *Schema) validate(schema, v any, path string, errs *[]string, depth int) {
+	if len(*errs) >= maxErrors {
+		return
+	}
+	addf := func(format string, args ...any) {
+		if len(*errs) < maxErrors {
+			*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	sch, ok := schema.(map[string]any)
+	if !ok {
+		if schema == false {
+			addf("no value is allowed here")
+		}
+		return
+	}
+	if ref, ok := sch["$ref"].(string); ok {
+		if depth > 64 {
+			addf("$ref nesting too deep")
+			return
+		}
+		target, _ := s.resolve(ref) // checked by Compile
+		s.validate(target, v, path, errs, depth+1)
+	}
+
+	if t, ok := sch["type"]; ok && !matchesType(t, v) {
+		addf("expected %s, got %s", typeNames(t), typeOf(v))
+		return
+	}
+	if enum, ok := sch["enum"].([]any); ok && !containsValue(enum, v) {
+		addf("must be one of %s", compactJSON(enum))
+	}
+	if c, ok := sch["const"]; ok && !reflect.DeepEqual(c, v) {
+		addf("must be %s", compactJSON(c))
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		s.validateObject(sch, val, path, errs, depth)
+	case []any:
+		if n, ok := number(sch["minItems"]); ok && float64(len(val)) < n {
+			addf("must have at least %v items", n)
+		}
+		if n, ok := number(sch["maxItems"]); ok && float64(len(val)) > n {
+			addf("must have at most %v items", n)
+		}
+		if items, ok := sch["items"]; ok {
+			for i, item := range val {
+				s.validate(items, item, path+"["+strconv.Itoa(i)+"]", errs, depth)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if n, ok := number(sch["minLength"]); ok && length < n {
+			addf("must be at least %v characters", n)
+		}
+		if n, ok := number(sch["maxLength"]); ok && length > n {
+			addf("must be at most %v characters", n)
+		}
+		if p, ok := sch["pattern"].(string); ok && !s.patterns[p].MatchString(val) {
+			addf("must match pattern %q", p)
+		}
+	case float64:
+		if n, ok := number(sch["minimum"]); ok && val < n {
+			addf("must be >= %v", n)
+		}
+		if n, ok := number(sch["maximum"]); ok && val > n {
+			addf("must be <= %v", n)
+		}
+		if n, ok := number(sch["exclusiveMinimum"]); ok && val <= n {
+			addf("must be > %v", n)
+		}
+		if n, ok := number(sch["exclusiveMaximum"]); ok && val >= n {
+			addf("must be < %v", n)
+		}
+	}
+
+	if all, ok := sch["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.validate(sub, v, path, errs, depth)
+		}
+	}
+	if anyOf, ok := sch["anyOf"].([]any); ok && s.countMatches(anyOf, v, depth) == 0 {
+		addf("must match at least one schema in anyOf")
+	}
+	if oneOf, ok := sch["oneOf"].([]any); ok {
+		if n := s.countMatches(oneOf, v, depth); n != 1 {
+			addf("must match exactly one schema in oneOf (matched %d)", n)
+		}
+	}
+	if not, ok := sch["not"]; ok && s.countMatches([]any{not}, v, depth) == 1 {
+		addf("must not match the schema in not")
+	}
+}
+
+func (s *Schema) validateObject(sch, obj map[string]any, path string, errs *[]string, depth int) {
+	if required, ok := sch["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := obj[name]; !present && len(*errs) < maxErrors {
+					*errs = append(*errs, fmt.Sprintf("%s: missing required property %q", path, name))
+				}
+			}
+		}
+	}
+	props, _ := sch["properties"].(map[string]any)
+	additional, hasAdditional := sch["additionalProperties"]
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic error order
+	for _, name := range names {
+		sub := path + "." + name
+		if propSchema, ok := props[name]; ok {
+			s.validate(propSchema, obj[name], sub, errs, depth)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if additional == false {
+			if len(*errs) < maxErrors {
+				*errs = append(*errs, fmt.Sprintf("%s: unexpected property %q", path, name))
+			}
+			continue
+		}
+		s.validate(additional, obj[name], sub, errs, depth)
+	}
+}
+
+func (s *Schema) countMatches(schemas []any, v any, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		var errs []string
+		s.validate(sub, v, "$", &errs, depth)
+		if len(errs) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func matchesType(t, v any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, v)
+	case []any:
+		for _, name := range tt {
+			if s, ok := name.(string); ok && matchesTypeName(s, v) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesTypeName(name string, v any) bool {
+	switch name {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	default:
+		return typeOf(v) == name
+	}
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func typeNames(t any) string {
+	if list, ok := t.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, n := range list {
+			names = append(names, fmt.Sprint(n))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func containsValue(list []any, v any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func number(v any) (float64, bool) {
+	f, ok := v.(float64)
+	return f, ok
+}
+
+func compactJSON(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// ExtractJSON returns the JSON document in a model response, removing a
+// surrounding Markdown code fence if the model added one.
+func ExtractJSON(text string) []byte {
+	text = strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(text, "```"); ok {
+		if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+			rest = rest[nl+1:]
+		}
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "```"))
+	}
+	return []byte(text)
+}
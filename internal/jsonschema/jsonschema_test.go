@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const personSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "integer", "minimum": 0},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "maxItems": 2},
+		"role": {"enum": ["admin", "user"]}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"$defs": {"tag": {"type": "string", "pattern": "^[a-z]+$"}}
+}`
+
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(personSchema))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	tests := []struct {
+		doc  string
+		want []string // substrings of the reported errors; nil for valid
+	}{
+		{`{"name":"a","age":3,"tags":["x"],"role":"user"}`, nil},
+		{`{"name":"","age":1.5}`, []string{`$.age: expected integer`, `$.name: must be at least 1 characters`}},
+		{`{"age":1,"extra":true}`, []string{`missing required property "name"`, `unexpected property "extra"`}},
+		{`{"name":"a","age":1,"tags":["x","Y","z"]}`, []string{`$.tags: must have at most 2 items`, `$.tags[1]: must match pattern`}},
+		{`{"name":"a","age":-1,"role":"root"}`, []string{`$.age: must be >= 0`, `$.role: must be one of ["admin","user"]`}},
+		{`[1]`, []string{`$: expected object, got array`}},
+		{`not json`, []string{`invalid JSON`}},
+	}
+	for _, tt := range tests {
+		err := s.Validate([]byte(tt.doc))
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.doc, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: expected ValidationError, got %v", tt.doc, err)
+			continue
+		}
+		if len(verr.Errors) != len(tt.want) {
+			t.Errorf("%s: got errors %q, want %d", tt.doc, verr.Errors, len(tt.want))
+			continue
+		}
+		for i, want := range tt.want {
+			if !strings.Contains(verr.Errors[i], want) {
+				t.Errorf("%s: error %d = %q, want it to contain %q", tt.doc, i, verr.Errors[i], want)
+			}
+		}
+	}
+}
+
+func TestValidateCombinators(t *testing.T) {
+	s, err := Compile([]byte(`{"oneOf":[{"type":"string"},{"type":"number","not":{"const":0}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for doc, ok := range map[string]bool{`"x"`: true, `3`: true, `0`: false, `null`: false} {
+		if got := s.Validate([]byte(doc)) == nil; got != ok {
+			t.Errorf("Validate(%s) ok = %v, want %v", doc, got, ok)
+		}
+	}
+}
+
+func TestCompileRejectsBadSchemas(t *testing.T) {
+	for _, doc := range []string{
+		`{"$ref":"#/$defs/missing"}`,
+		`{"properties":{"a":{"pattern":"("}}}`,
+		`{"anyOf":{}}`,
+		`"string"`,
+	} {
+		if _, err := Compile([]byte(doc)); err == nil {
+			t.Errorf("Compile(%s) should fail", doc)
+		}
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		`{"a":1}`:                 `{"a":1}`,
+		"```json\n{\"a\":1}\n```": `{"a":1}`,
+		"  ```\n[1]\n```  ":       `[1]`,
+	} {
+		if got := string(ExtractJSON(in)); got != want {
+			t.Errorf("ExtractJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/usage"
 )
 
 // Session holds a persisted conversation.
@@ -20,6 +21,8 @@ type Session struct {
 	Provider   string        `json:"provider"`
 	Model      string        `json:"model"`
 	Messages   []api.Message `json:"messages"`
+	// Usage is the token usage and cost of the session per day and model.
+	Usage []usage.Record `json:"usage,omitempty"`
 }
 
 // Summary is a lightweight representation of a persisted session.
@@ -29,6 +32,7 @@ type Summary struct {
 	WorkingDir string
 	Provider   string
 	Model      string
+	Usage      []usage.Record
 }
 
 // New creates a new session with a timestamp-based ID.
@@ -141,6 +145,7 @@ func ListSummaries(limit int) ([]Summary, error) {
 			WorkingDir: s.WorkingDir,
 			Provider:   s.Provider,
 			Model:      s.Model,
+			Usage:      s.Usage,
 		})
 	}
 
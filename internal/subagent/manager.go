@@ -37,10 +37,13 @@ type AgentSummary struct {
 	ID       string
 	Status   AgentStatus
 	LastLine string
+	// Queue describes the wait for the provider's rate limit, or is empty.
+	Queue string
 }
 
 type managerAgent struct {
 	id        string
+	client    *api.Client // the client the agent was spawned with
 	status    AgentStatus
 	messages  []api.Message
 	createdAt time.Time
@@ -50,9 +53,10 @@ type managerAgent struct {
 	cancel    context.CancelFunc
 	done      chan struct{}
 
-	// logMu guards logBuf; written by the stream goroutine, read by external callers.
+	// logMu guards logBuf and queue; written by the stream goroutine, read by external callers.
 	logMu  sync.Mutex
 	logBuf strings.Builder
+	queue  string
 }
 
 type Manager struct {
@@ -71,6 +75,14 @@ func NewManager(client *api.Client, ctxConfig *config.ContextConfig) *Manager {
 	}
 }
 
+// SetClient makes subagents spawned from now on use client. Existing
+// subagents keep the client they were spawned with.
+func (m *Manager) SetClient(client *api.Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiClient = client
+}
+
 func (m *Manager) Spawn(prompt string) (string, error) {
 	if strings.TrimSpace(prompt) == "" {
 		return "", fmt.Errorf("prompt is required")
@@ -88,6 +100,7 @@ func (m *Manager) Spawn(prompt string) (string, error) {
 	}
 
 	m.mu.Lock()
+	ag.client = m.apiClient
 	m.agents[id] = ag
 	m.mu.Unlock()
 
@@ -218,11 +231,13 @@ func (m *Manager) GetRunningSummary() []AgentSummary {
 		if ag.status == AgentStatusRunning {
 			ag.logMu.Lock()
 			log := ag.logBuf.String()
+			queue := ag.queue
 			ag.logMu.Unlock()
 			out = append(out, AgentSummary{
 				ID:       ag.id,
 				Status:   ag.status,
 				LastLine: lastNonEmptyLine(log),
+				Queue:    queue,
 			})
 		}
 	}
@@ -250,21 +265,21 @@ func (m *Manager) startRun(id string) error {
 	ag.status = AgentStatusRunning
 	ag.updatedAt = time.Now()
 	done := ag.done
+	client := ag.client
 	messages := make([]api.Message, len(ag.messages))
 	copy(messages, ag.messages)
 	m.mu.Unlock()
 
-	stream, err := m.apiClient.StreamChatCompletionWithTools(ctx, messages, nil)
-	if err != nil {
-		cancel()
-		m.mu.Lock()
-		ag.status = AgentStatusFailed
-		ag.err = err.Error()
-		ag.updatedAt = time.Now()
-		close(done)
-		m.mu.Unlock()
-		return nil
-	}
+	// The stream is started in the goroutine: with a provider rate limit
+	// the request may queue behind the main agent and other subagents.
+	ctx = api.WithQueueNotifier(ctx, func(info api.QueueInfo) {
+		ag.logMu.Lock()
+		defer ag.logMu.Unlock()
+		ag.queue = ""
+		if info.Waiting {
+			ag.queue = info.String()
+		}
+	})
 
 	go func() {
 		defer cancel()
@@ -272,21 +287,30 @@ func (m *Manager) startRun(id string) error {
 		status := AgentStatusCompleted
 		errText := ""
 
-		for ev := range stream {
-			if ev.Error != nil {
+		stream, err := client.StreamChatCompletionWithTools(ctx, messages, nil)
+		if err != nil {
+			// Cancellation and timeouts are reported below.
+			if ctx.Err() == nil {
 				status = AgentStatusFailed
-				errText = ev.Error.Error()
-				break
+				errText = err.Error()
 			}
-			if ev.Data == nil {
-				continue
-			}
-			for _, ch := range ev.Data.Choices {
-				if ch.Delta.Content != "" {
-					sb.WriteString(ch.Delta.Content)
-					ag.logMu.Lock()
-					ag.logBuf.WriteString(ch.Delta.Content)
-					ag.logMu.Unlock()
+		} else {
+			for ev := range stream {
+				if ev.Error != nil {
+					status = AgentStatusFailed
+					errText = ev.Error.Error()
+					break
+				}
+				if ev.Data == nil {
+					continue
+				}
+				for _, ch := range ev.Data.Choices {
+					if ch.Delta.Content != "" {
+						sb.WriteString(ch.Delta.Content)
+						ag.logMu.Lock()
+						ag.logBuf.WriteString(ch.Delta.Content)
+						ag.logMu.Unlock()
+					}
 				}
 			}
 		}
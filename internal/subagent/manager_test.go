@@ -155,6 +155,50 @@ func TestManagerCloseCancelsAndRemovesAgent(t *testing.T) {
 	}
 }
 
+func TestManagerRunningSummaryShowsRateLimitQueue(t *testing.T) {
+	server := newSubagentCancelableServer(t)
+	defer server.Close()
+
+	provider := &config.ProviderConfig{
+		Type:      "openai-compat",
+		BaseURL:   server.URL,
+		APIKey:    "dummy-key",
+		RateLimit: config.RateLimitConfig{MaxConcurrent: 1},
+	}
+	ctxCfg := &config.ContextConfig{MaxMessages: 50, MaxTokens: 4096}
+	mgr := NewManager(api.NewClient(provider, &config.ModelConfig{Model: "dummy-model"}, ctxCfg), ctxCfg)
+
+	first, err := mgr.Spawn("first")
+	if err != nil {
+		t.Fatalf("Spawn error: %v", err)
+	}
+	second, err := mgr.Spawn("second")
+	if err != nil {
+		t.Fatalf("Spawn error: %v", err)
+	}
+	defer func() {
+		_ = mgr.Close(second)
+		_ = mgr.Close(first)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		queued := 0
+		for _, s := range mgr.GetRunningSummary() {
+			if strings.Contains(s.Queue, "max_concurrent") {
+				queued++
+			}
+		}
+		if queued == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one queued subagent: %#v", mgr.GetRunningSummary())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
 func newTestManager(t *testing.T, baseURL string) *Manager {
 	t.Helper()
 	provider := &config.ProviderConfig{
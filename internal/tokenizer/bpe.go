@@ -0,0 +1,107 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"unicode"
+)
+
+// loadRankFile reads a tiktoken rank table from path.
+func loadRankFile(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return loadRanks(f, path)
+}
+
+// loadRanks reads a tiktoken rank table: one "<base64 token> <rank>" pair
+// per line. name is used in error messages.
+func loadRanks(r io.Reader, name string) (map[string]int, error) {
+	ranks := make(map[string]int, 200000)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := bytes.Fields(scanner.Bytes())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<token> <rank>\"", name, line)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
+
+// bytePairCount returns the number of tokens piece encodes to. Starting from
+// single bytes, it repeatedly merges the adjacent pair with the lowest rank
+// until no adjacent pair is in the table, as tiktoken does.
+func bytePairCount(piece []byte, ranks map[string]int) int {
+	if len(piece) <= 1 {
+		return len(piece)
+	}
+	if _, ok := ranks[string(piece)]; ok {
+		return 1
+	}
+
+	// bounds[i] is the start offset of part i; the last entry is len(piece).
+	bounds := make([]int, len(piece)+1)
+	for i := range bounds {
+		bounds[i] = i
+	}
+	for len(bounds) > 2 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i+2 < len(bounds); i++ {
+			if rank, ok := ranks[string(piece[bounds[i]:bounds[i+2]])]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		bounds = append(bounds[:best+1], bounds[best+2:]...)
+	}
+	return len(bounds) - 1
+}
+
+// estimatePiece approximates the token count of a pre-tokenized piece when
+// no rank table is available. Short ASCII words are almost always a single
+// token and longer identifiers split every few characters; CJK characters
+// cost about one token each (less in o200k_base), and other non-ASCII
+// letters roughly one token per two characters.
+func (e *encoding) estimatePiece(piece string) int {
+	var ascii, cjk, other int
+	for _, r := range piece {
+		switch {
+		case r <= unicode.MaxASCII:
+			ascii++
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		default:
+			other++
+		}
+	}
+	tokens := float64(cjk)*e.cjkWeight + float64(other)/2
+	if ascii > 0 {
+		tokens += float64((ascii + 5) / 6)
+	}
+	return max(1, int(math.Round(tokens)))
+}
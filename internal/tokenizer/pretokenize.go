@@ -0,0 +1,113 @@
+package tokenizer
+
+import (
+	"unicode"
+)
+
+// pretokenize splits text into the pieces that BPE is applied to. It
+// implements the cl100k_base split pattern
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// by hand, since Go's regexp package has no lookahead. o200k_base uses a
+// case-aware variant of the same pattern; the resulting piece boundaries are
+// close enough for counting.
+func pretokenize(text string) []string {
+	runes := []rune(text)
+	var pieces []string
+	for pos := 0; pos < len(runes); {
+		end := matchPiece(runes, pos)
+		pieces = append(pieces, string(runes[pos:end]))
+		pos = end
+	}
+	return pieces
+}
+
+func isLetter(r rune) bool  { return unicode.IsLetter(r) }
+func isNumber(r rune) bool  { return unicode.IsNumber(r) }
+func isSpace(r rune) bool   { return unicode.IsSpace(r) }
+func isNewline(r rune) bool { return r == '\r' || r == '\n' }
+
+// matchPiece returns the end of the piece starting at pos. It tries the
+// alternatives of the split pattern in order, like a regexp engine would.
+func matchPiece(runes []rune, pos int) int {
+	n := len(runes)
+	r := runes[pos]
+
+	// (?i:'s|'t|'re|'ve|'m|'ll|'d)
+	if r == '\'' && pos+1 < n {
+		c1 := unicode.ToLower(runes[pos+1])
+		if c1 == 's' || c1 == 't' || c1 == 'm' || c1 == 'd' {
+			return pos + 2
+		}
+		if pos+2 < n {
+			c2 := unicode.ToLower(runes[pos+2])
+			if (c1 == 'r' && c2 == 'e') || (c1 == 'v' && c2 == 'e') || (c1 == 'l' && c2 == 'l') {
+				return pos + 3
+			}
+		}
+	}
+
+	// [^\r\n\p{L}\p{N}]?\p{L}+
+	start := pos
+	if !isNewline(r) && !isLetter(r) && !isNumber(r) {
+		start = pos + 1
+	}
+	if end := scan(runes, start, isLetter); end > start {
+		return end
+	}
+
+	// \p{N}{1,3}
+	if isNumber(r) {
+		end := pos
+		for end < n && end-pos < 3 && isNumber(runes[end]) {
+			end++
+		}
+		return end
+	}
+
+	// ' ?[^\s\p{L}\p{N}]+[\r\n]*'
+	start = pos
+	if r == ' ' {
+		start = pos + 1
+	}
+	isSymbol := func(r rune) bool { return !isSpace(r) && !isLetter(r) && !isNumber(r) }
+	if end := scan(runes, start, isSymbol); end > start {
+		return scan(runes, end, isNewline)
+	}
+
+	// The remaining alternatives all start with whitespace.
+	wsEnd := scan(runes, pos, isSpace)
+	if wsEnd == pos {
+		// Not reachable for valid input, but never loop forever.
+		return pos + 1
+	}
+
+	// \s*[\r\n]+ : the whitespace run up to and including its last newline.
+	for i := wsEnd - 1; i >= pos; i-- {
+		if isNewline(runes[i]) {
+			return i + 1
+		}
+	}
+
+	// \s+(?!\S) : leave the last space to be glued to the following word.
+	if wsEnd == n {
+		return wsEnd
+	}
+	if wsEnd-pos >= 2 {
+		return wsEnd - 1
+	}
+
+	// \s+
+	return wsEnd
+}
+
+// scan returns the index of the first rune at or after pos that does not
+// satisfy pred.
+func scan(runes []rune, pos int, pred func(rune) bool) int {
+	for pos < len(runes) && pred(runes[pos]) {
+		pos++
+	}
+	return pos
+}
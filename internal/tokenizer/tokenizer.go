@@ -0,0 +1,175 @@
+// Package tokenizer counts tokens for context management.
+//
+// Exact counts use the byte-pair encoding rank tables published for OpenAI
+// models (cl100k_base, o200k_base) in the tiktoken file format. The tables
+// are built in, gzipped, and parsed on first use; a table installed as
+// $XDG_DATA_HOME/ashron/tokenizers/<name>.tiktoken overrides the built-in one.
+// Should neither load, an estimator that knows the same piece boundaries and
+// how CJK text tokenizes is used instead.
+package tokenizer
+
+import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// tables holds the rank tables OpenAI publishes for tiktoken at
+// https://openaipublic.blob.core.windows.net/encodings/<name>.tiktoken,
+// gzipped.
+//
+//go:embed tables/*.tiktoken.gz
+var tables embed.FS
+
+// builtinTables is where builtinRanks looks; replaced in tests.
+var builtinTables fs.FS = tables
+
+// Tokenizer names accepted by New.
+const (
+	Chars  = "chars"
+	CL100K = "cl100k_base"
+	O200K  = "o200k_base"
+)
+
+// Names lists the tokenizer names accepted by New.
+var Names = []string{Chars, CL100K, O200K}
+
+// Tokenizer counts the tokens in a piece of text.
+type Tokenizer interface {
+	Name() string
+	Count(text string) int
+}
+
+// New returns the tokenizer with the given name.
+func New(name string) (Tokenizer, error) {
+	switch name {
+	case Chars:
+		return charsTokenizer{}, nil
+	case CL100K:
+		return &encoding{name: name, cjkWeight: 1.0}, nil
+	case O200K:
+		return &encoding{name: name, cjkWeight: 0.75}, nil
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (supported: %s)", name, strings.Join(Names, ", "))
+	}
+}
+
+// ForModel returns the tokenizer named by configured, or the encoding used by
+// model when configured is empty. Unknown models fall back to chars/4.
+func ForModel(configured, model string) (Tokenizer, error) {
+	if configured != "" {
+		return New(configured)
+	}
+	return New(guessEncoding(model))
+}
+
+// guessEncoding maps well-known OpenAI model names to their encoding.
+func guessEncoding(model string) string {
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4", "chatgpt-4o", "gpt-oss"} {
+		if strings.HasPrefix(m, prefix) {
+			return O200K
+		}
+	}
+	for _, prefix := range []string{"gpt-4", "gpt-3.5", "text-embedding-3", "text-embedding-ada-002"} {
+		if strings.HasPrefix(m, prefix) {
+			return CL100K
+		}
+	}
+	return Chars
+}
+
+// DataDir returns the directory searched for .tiktoken rank tables that
+// override the built-in ones.
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "ashron", "tokenizers")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = os.Getenv("HOME")
+	}
+	return filepath.Join(home, ".local", "share", "ashron", "tokenizers")
+}
+
+// charsTokenizer is the historical ~4 characters per token estimate.
+type charsTokenizer struct{}
+
+func (charsTokenizer) Name() string { return Chars }
+
+func (charsTokenizer) Count(text string) int { return len(text) / 4 }
+
+// encoding counts tokens with a BPE rank table when one loads and with
+// estimatePiece otherwise.
+type encoding struct {
+	name      string
+	cjkWeight float64 // estimated tokens per CJK character without a table
+
+	once  sync.Once
+	ranks map[string]int
+}
+
+func (e *encoding) Name() string {
+	e.load()
+	if e.ranks == nil {
+		return e.name + " (estimated)"
+	}
+	return e.name
+}
+
+func (e *encoding) load() {
+	e.once.Do(func() {
+		path := filepath.Join(DataDir(), e.name+".tiktoken")
+		ranks, err := loadRankFile(path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				slog.Warn("Failed to load tokenizer table, using the built-in one", "path", path, "error", err)
+			}
+			path = "built-in"
+			ranks, err = builtinRanks(e.name)
+		}
+		if err != nil {
+			slog.Warn("Failed to load tokenizer table, estimating instead", "name", e.name, "error", err)
+			return
+		}
+		slog.Info("Loaded tokenizer table", "name", e.name, "path", path, "tokens", len(ranks))
+		e.ranks = ranks
+	})
+}
+
+// builtinRanks reads the built-in rank table for the named encoding.
+func builtinRanks(name string) (map[string]int, error) {
+	f, err := builtinTables.Open("tables/" + name + ".tiktoken.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return loadRanks(zr, name)
+}
+
+func (e *encoding) Count(text string) int {
+	e.load()
+	total := 0
+	for _, piece := range pretokenize(text) {
+		if e.ranks != nil {
+			total += bytePairCount([]byte(piece), e.ranks)
+		} else {
+			total += e.estimatePiece(piece)
+		}
+	}
+	return total
+}
@@ -0,0 +1,154 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPretokenize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"Hello world", []string{"Hello", " world"}},
+		{"I'm fine", []string{"I", "'m", " fine"}},
+		{"x = 12345;", []string{"x", " =", " ", "123", "45", ";"}},
+		{"foo()\n\n  bar", []string{"foo", "()\n\n", " ", " bar"}},
+		{"a  \n", []string{"a", "  \n"}},
+		{"こんにちは世界", []string{"こんにちは世界"}},
+	}
+	for _, tt := range tests {
+		if got := pretokenize(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("pretokenize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestBytePairCount(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2, "ab": 3, "abc": 4, "bc": 5}
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abc", 1},
+		{"abab", 2},  // ab + ab
+		{"abcab", 2}, // ab merges first, then abc
+		{"cba", 3},   // no mergeable pairs
+	}
+	for _, tt := range tests {
+		if got := bytePairCount([]byte(tt.in), ranks); got != tt.want {
+			t.Errorf("bytePairCount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingLoadsTable(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	dir := filepath.Join(dataHome, "ashron", "tokenizers")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var table strings.Builder
+	for i, tok := range []string{"H", "e", "l", "o", " ", "w", "r", "d", "He", "ll", "Hell", "Hello", " w", "or"} {
+		_, _ = fmt.Fprintf(&table, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(tok)), i)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CL100K+".tiktoken"), []byte(table.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := New(CL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Name() != CL100K {
+		t.Fatalf("expected table to be loaded, name = %q", tok.Name())
+	}
+	// "Hello" + " w" "or" "l" "d"
+	if got := tok.Count("Hello world"); got != 5 {
+		t.Fatalf("Count = %d, want 5", got)
+	}
+}
+
+func TestEncodingUsesBuiltinTables(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	tests := []struct {
+		name, text string
+		want       int
+	}{
+		{CL100K, "hello world", 2},
+		{CL100K, "tiktoken is great!", 6},
+		{O200K, "hello world", 2},
+	}
+	for _, tt := range tests {
+		tok, err := New(tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Name() != tt.name {
+			t.Fatalf("expected the built-in table, name = %q", tok.Name())
+		}
+		if got := tok.Count(tt.text); got != tt.want {
+			t.Errorf("%s: Count(%q) = %d, want %d", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncodingEstimatesWithoutTable(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	builtinTables = fstest.MapFS{}
+	t.Cleanup(func() { builtinTables = tables })
+	tok, err := New(CL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Name() != CL100K+" (estimated)" {
+		t.Fatalf("unexpected name %q", tok.Name())
+	}
+	chars, _ := New(Chars)
+
+	// Japanese is roughly a token per character, far more than chars/4 of
+	// its UTF-8 bytes would suggest.
+	ja := "日本語のテキストはトークン数が多くなります"
+	if got, naive := tok.Count(ja), chars.Count(ja); got < 15 || got <= naive {
+		t.Fatalf("Japanese estimate = %d (chars/4 = %d), expected about one per character", got, naive)
+	}
+	// Short English words are a token each.
+	if got := tok.Count("the quick brown fox"); got != 4 {
+		t.Fatalf("English estimate = %d, want 4", got)
+	}
+}
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		configured, model, want string
+	}{
+		{"", "gpt-4o-mini", O200K},
+		{"", "openai/gpt-5", O200K},
+		{"", "o3-mini", O200K},
+		{"", "gpt-4-turbo", CL100K},
+		{"", "gpt-3.5-turbo", CL100K},
+		{"", "claude-sonnet-4", Chars},
+		{CL100K, "glm-4.7", CL100K},
+	}
+	for _, tt := range tests {
+		tok, err := ForModel(tt.configured, tt.model)
+		if err != nil {
+			t.Fatalf("ForModel(%q, %q): %v", tt.configured, tt.model, err)
+		}
+		if name := strings.TrimSuffix(tok.Name(), " (estimated)"); name != tt.want {
+			t.Errorf("ForModel(%q, %q) = %s, want %s", tt.configured, tt.model, name, tt.want)
+		}
+	}
+	if _, err := ForModel("bogus", "gpt-4o"); err == nil {
+		t.Fatal("expected error for unknown tokenizer")
+	}
+}
@@ -0,0 +1,45 @@
+package tools
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// IsAutoApproved reports whether a tool call may run without asking the
+// user, under the tools configuration alone. Front ends without per-session
+// grants (ACP, headless runs) use it directly.
+func IsAutoApproved(cfg *config.ToolsConfig, tc api.ToolCall) bool {
+	if cfg.Yolo {
+		return true
+	}
+	for _, name := range cfg.AutoApproveTools {
+		if name == tc.Function.Name {
+			return true
+		}
+	}
+	if tc.Function.Name == "execute_command" {
+		var args ExecuteCommandArgs
+		if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+			return false
+		}
+		if strings.EqualFold(EffectiveSandboxMode(cfg, args), "off") {
+			return false
+		}
+		for _, cmd := range cfg.AutoApproveCommands {
+			if strings.HasPrefix(cmd, "/") && strings.HasSuffix(cmd, "/") {
+				pattern := strings.TrimPrefix(strings.TrimSuffix(cmd, "/"), "/")
+				matched, err := regexp.MatchString(pattern, args.Command)
+				if err == nil && matched {
+					return true
+				}
+			} else if cmd == args.Command {
+				return true
+			}
+		}
+	}
+	return false
+}
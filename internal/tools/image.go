@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tokuhirom/ashron/internal/api"
+)
+
+// MaxImageSize is the largest image file that is attached to a message.
+// Providers reject larger inline images (Anthropic's limit is 5 MB).
+const MaxImageSize = 5 * 1024 * 1024
+
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// IsImagePath reports whether path has an image file extension.
+func IsImagePath(path string) bool {
+	_, ok := imageMIMETypes[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// LoadImage reads an image file into an image content part.
+func LoadImage(path string) (api.ContentPart, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return api.ContentPart{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(f, MaxImageSize+1))
+	if err != nil {
+		return api.ContentPart{}, err
+	}
+	if len(data) > MaxImageSize {
+		return api.ContentPart{}, fmt.Errorf("image %s is larger than %d bytes", path, MaxImageSize)
+	}
+	return ImagePart(data)
+}
+
+// ImagePart returns an image content part for data after checking that it
+// is in a format providers accept.
+func ImagePart(data []byte) (api.ContentPart, error) {
+	mimeType := http.DetectContentType(data)
+	for _, known := range imageMIMETypes {
+		if mimeType == known {
+			return api.NewImagePart(mimeType, data), nil
+		}
+	}
+	return api.ContentPart{}, fmt.Errorf("unsupported image type %s", mimeType)
+}
+
+// ToolMessage builds the history message for a tool result: the output is
+// compacted, and images are attached when the model supports vision or
+// replaced by a note when it does not.
+func ToolMessage(tc api.ToolCall, result api.ToolResult, vision bool) api.Message {
+	msg := api.NewToolMessage(tc.ID, CompactToolResultForHistory(tc.Function.Name, result.Output))
+	if len(result.Images) > 0 {
+		if vision {
+			msg.AttachImages(result.Images...)
+		} else {
+			msg.Content += "\n[Image not shown: the model is not configured with vision: true]"
+		}
+	}
+	return msg
+}
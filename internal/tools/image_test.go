@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// pngHeader is enough of a PNG file for content type detection.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestReadFileReturnsImagePart(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "shot.png")
+	if err := os.WriteFile(path, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	args, _ := json.Marshal(ReadFileArgs{Path: path})
+
+	result := ReadFile(&config.ToolsConfig{MaxOutputSize: 1024}, "c1", string(args))
+	if result.Error != nil {
+		t.Fatalf("ReadFile error: %v", result.Error)
+	}
+	if !strings.Contains(result.Output, "image/png") || len(result.Images) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if mime, _, ok := result.Images[0].ImageURL.DataURL(); !ok || mime != "image/png" {
+		t.Fatalf("unexpected image part: %+v", result.Images[0])
+	}
+}
+
+func TestLoadImageRejectsNonImage(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "fake.png")
+	if err := os.WriteFile(path, []byte("plain text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadImage(path); err == nil || !strings.Contains(err.Error(), "unsupported image type") {
+		t.Fatalf("expected unsupported image type error, got %v", err)
+	}
+}
+
+func TestToolMessageAttachesImagesOnlyWithVision(t *testing.T) {
+	t.Parallel()
+
+	tc := api.ToolCall{ID: "c1", Function: api.FunctionCall{Name: "read_file"}}
+	result := api.ToolResult{ToolCallID: "c1", Output: "Image file a.png", Images: []api.ContentPart{api.NewImagePart("image/png", pngHeader)}}
+
+	if msg := ToolMessage(tc, result, true); len(msg.Images()) != 1 {
+		t.Fatalf("expected image with vision: %+v", msg)
+	}
+	msg := ToolMessage(tc, result, false)
+	if msg.Parts != nil || !strings.Contains(msg.Content, "vision: true") {
+		t.Fatalf("expected note without vision: %+v", msg)
+	}
+}
@@ -38,6 +38,10 @@ func ReadFile(config *config.ToolsConfig, toolCallID string, argsJson string) ap
 	// Clean and validate path
 	path = filepath.Clean(path)
 
+	if IsImagePath(path) {
+		return readImageFile(result, path)
+	}
+
 	// Read file
 	file, err := os.Open(path)
 	if err != nil {
@@ -89,3 +93,19 @@ func ReadFile(config *config.ToolsConfig, toolCallID string, argsJson string) ap
 
 	return result
 }
+
+// readImageFile returns an image as an image part for vision-capable models,
+// with a short text description as the output.
+func readImageFile(result api.ToolResult, path string) api.ToolResult {
+	image, err := LoadImage(path)
+	if err != nil {
+		result.Error = err
+		result.Output = fmt.Sprintf("Error reading image: %v", err)
+		return result
+	}
+	mimeType, data, _ := image.ImageURL.DataURL()
+	result.Output = fmt.Sprintf("Image file %s (%s, %d bytes base64)", path, mimeType, len(data))
+	result.Images = []api.ContentPart{image}
+	slog.Info("Image read completed", slog.String("path", path), slog.String("mimeType", mimeType))
+	return result
+}
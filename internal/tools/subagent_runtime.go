@@ -19,6 +19,14 @@ func ConfigureSubagentRuntime(client *api.Client, ctxCfg *config.ContextConfig)
 	subagentManager = subagent.NewManager(client, ctxCfg)
 }
 
+// SetSubagentClient makes subagents spawned from now on use client, e.g. the
+// model picked for a single turn. Subagents already spawned are kept.
+func SetSubagentClient(client *api.Client) {
+	if mgr := getSubagentManager(); mgr != nil {
+		mgr.SetClient(client)
+	}
+}
+
 func getSubagentManager() *subagent.Manager {
 	subagentMu.RLock()
 	defer subagentMu.RUnlock()
@@ -29,6 +37,7 @@ func getSubagentManager() *subagent.Manager {
 type SubagentSummary struct {
 	ID       string
 	LastLine string
+	Queue    string // rate limit wait, e.g. "Queued: max_concurrent (1 ahead)"
 }
 
 // GetSubagentsSummary returns summaries of all currently running subagents.
@@ -41,7 +50,7 @@ func GetSubagentsSummary() []SubagentSummary {
 	raw := mgr.GetRunningSummary()
 	out := make([]SubagentSummary, len(raw))
 	for i, s := range raw {
-		out[i] = SubagentSummary{ID: s.ID, LastLine: s.LastLine}
+		out[i] = SubagentSummary{ID: s.ID, LastLine: s.LastLine, Queue: s.Queue}
 	}
 	return out
 }
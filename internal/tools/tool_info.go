@@ -56,7 +56,7 @@ func GetAllTools() []ToolInfo {
 		},
 		{
 			Name:        "read_file",
-			Description: "Read the contents of a file",
+			Description: "Read the contents of a file. Image files (PNG, JPEG, GIF, WebP) are returned as images when the model supports vision",
 			Parameters: api.FunctionParameters{
 				Type: "object",
 				Properties: map[string]api.FunctionProperty{
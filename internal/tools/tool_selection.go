@@ -60,7 +60,25 @@ var extendedToolsetKeywords = []string{
 // per-request token overhead. It switches to the full toolset when the prompt
 // likely needs edits or command execution.
 func SelectBuiltinTools(prompt string) []api.Tool {
-	if LikelyNeedsExtendedToolset(prompt) {
+	return selectBuiltinTools(LikelyNeedsExtendedToolset(prompt))
+}
+
+// Toolset selects the builtin toolset for each prompt of a conversation.
+// Once a prompt needed the extended toolset it is kept for the rest of the
+// conversation: the tool definitions lead every request, so switching back
+// would invalidate the provider's prompt cache.
+type Toolset struct {
+	extended bool
+}
+
+// Select returns the toolset for prompt.
+func (s *Toolset) Select(prompt string) []api.Tool {
+	s.extended = s.extended || LikelyNeedsExtendedToolset(prompt)
+	return selectBuiltinTools(s.extended)
+}
+
+func selectBuiltinTools(extended bool) []api.Tool {
+	if extended {
 		return GetBuiltinTools()
 	}
 	srcTools := GetAllTools()
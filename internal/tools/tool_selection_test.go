@@ -52,3 +52,16 @@ func TestSelectBuiltinTools_FullForEditPrompt(t *testing.T) {
 		t.Fatalf("execute_command must exist in full toolset")
 	}
 }
+
+func TestToolsetKeepsExtendedToolset(t *testing.T) {
+	t.Parallel()
+	var ts Toolset
+	minimal := len(ts.Select("read docs"))
+	extended := len(ts.Select("please edit this file"))
+	if extended <= minimal {
+		t.Fatalf("expected extended toolset, got %d tools (minimal %d)", extended, minimal)
+	}
+	if got := len(ts.Select("read docs again")); got != extended {
+		t.Fatalf("toolset should stay extended, got %d tools want %d", got, extended)
+	}
+}
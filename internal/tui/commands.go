@@ -1,7 +1,6 @@
 package tui
 
 import (
-	"fmt"
 	"sort"
 	"strings"
 
@@ -55,6 +54,13 @@ func NewCommandRegistry() *CommandRegistry {
 					return m.CompactContext()
 				},
 			},
+			"/retry": {
+				Name:        "/retry",
+				Description: "Resend the last request after an error",
+				Body: func(cr *CommandRegistry, m *SimpleModel, args []string) tea.Cmd {
+					return m.RetryLastTurn()
+				},
+			},
 			"/commit": {
 				Name:        "/commit",
 				Description: "Commit changes to git with a message",
@@ -99,6 +105,13 @@ func NewCommandRegistry() *CommandRegistry {
 					return m.RenderStatus()
 				},
 			},
+			"/cost": {
+				Name:        "/cost",
+				Description: "Show token usage and cost of this session per model",
+				Body: func(cr *CommandRegistry, m *SimpleModel, args []string) tea.Cmd {
+					return m.RenderCost()
+				},
+			},
 			"/sessions": {
 				Name:        "/sessions",
 				Description: "Manage sessions. Usage: /sessions [list|resume <id>|delete <id>]",
@@ -129,28 +142,16 @@ func NewCommandRegistry() *CommandRegistry {
 			},
 			"/model": {
 				Name:        "/model",
-				Description: "Show or switch model. Usage: /model [name]",
-				Body: func(cr *CommandRegistry, m *SimpleModel, args []string) tea.Cmd {
-					if len(args) == 0 {
-						m.textarea.SetValue("/model ")
-						m.textarea.CursorEnd()
-						m.updateCompletionState()
-						return nil
-					}
-
-					modelName := args[0]
-					if err := m.switchModel(modelName); err != nil {
-						errMsg := lipgloss.NewStyle().
-							Foreground(lipgloss.Color("#FF3333")).
-							Render(fmt.Sprintf("Error switching model: %v", err))
-						m.AddDisplayContent(errMsg, "")
-						return nil
-					}
-					successMsg := lipgloss.NewStyle().
-						Foreground(lipgloss.Color("#04B575")).
-						Render(fmt.Sprintf("Switched to model: %s (provider: %s)", m.currentModelName, m.currentProviderName))
-					m.AddDisplayContent(successMsg, "")
-					return nil
+				Description: "Show or switch model. Usage: /model [--refresh] [[--once] name [--save]]",
+				Body: func(cr *CommandRegistry, m *SimpleModel, args []string) tea.Cmd {
+					return m.modelCommand(args)
+				},
+			},
+			"/profile": {
+				Name:        "/profile",
+				Description: "Show or switch config profile. Usage: /profile [name|none]",
+				Body: func(cr *CommandRegistry, m *SimpleModel, args []string) tea.Cmd {
+					return m.profileCommand(args)
 				},
 			},
 		},
@@ -203,6 +204,7 @@ Keyboard Shortcuts:
   Enter      - Send message
   Ctrl+C     - Cancel current operation or exit
   Ctrl+L     - Clear screen
+  Ctrl+T     - Expand/collapse model thinking
   y/n        - Approve/Cancel tool execution (when prompted)
 
 Shell:
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+// configWatchTickMsg is sent periodically to look for edits to the config
+// files.
+type configWatchTickMsg time.Time
+
+func configWatchTick() tea.Cmd {
+	return tea.Tick(config.ReloadInterval, func(t time.Time) tea.Msg {
+		return configWatchTickMsg(t)
+	})
+}
+
+// handleConfigWatchTick reloads the configuration when its files were
+// edited. A reload waits until no request or approval is in progress.
+func (m *SimpleModel) handleConfigWatchTick() tea.Cmd {
+	if m.configWatcher.Changed() {
+		m.configReloadPending = true
+	}
+	if m.configReloadPending && !m.loading && !m.waitingForApproval {
+		m.configReloadPending = false
+		m.reloadConfig()
+	}
+	return configWatchTick()
+}
+
+// reloadConfig re-reads the config files and applies them, showing what
+// changed. An invalid configuration is reported and the current one kept.
+func (m *SimpleModel) reloadConfig() {
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF3333"))
+	cfg, changes, err := m.config.Reload()
+	if err == nil {
+		err = m.applyConfig(cfg)
+	}
+	if err != nil {
+		m.AddDisplayContent(errStyle.Render("Config reload failed; keeping the current configuration:"))
+		for _, line := range strings.Split(err.Error(), "\n") {
+			m.AddDisplayContent(errStyle.Render("  " + line))
+		}
+		m.AddDisplayContent("")
+		return
+	}
+	m.configWatcher.Reset(cfg)
+	if len(changes) == 0 {
+		return
+	}
+
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#04B575")).
+		Render(fmt.Sprintf("Reloaded configuration (model: %s, provider: %s):", m.currentModelName, m.currentProviderName)))
+	for _, c := range changes {
+		m.AddDisplayContent(infoStyle.Render("  " + c.String()))
+	}
+	if p := cfg.Project; p != nil && !p.Trusted && len(p.Sensitive) > 0 {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FFA500")).
+			Render(fmt.Sprintf("Ignoring %s from untrusted %s; restart ashron to trust it.", strings.Join(p.Sensitive, ", "), p.Path)))
+	}
+	m.AddDisplayContent("")
+	m.showConfigWarnings()
+}
+
+// applyConfig switches the session to cfg, rebuilding the API client,
+// context manager and tool executor from it. The conversation is kept.
+func (m *SimpleModel) applyConfig(cfg *config.Config) error {
+	provName, _, err := cfg.ActiveProvider()
+	if err != nil {
+		return err
+	}
+	modelName, modelCfg, err := cfg.ActiveModel()
+	if err != nil {
+		return err
+	}
+	activeCtx, err := cfg.ActiveContext()
+	if err != nil {
+		return err
+	}
+	apiClient, err := api.NewClientForModel(cfg, provName, modelName, activeCtx)
+	if err != nil {
+		return err
+	}
+
+	m.config = cfg
+	m.currentProviderName = provName
+	m.currentModelName = modelName
+	m.activeContext = *activeCtx
+	m.apiClient = apiClient
+	m.apiClient.SetUsageObserver(m.recordUsage)
+	m.contextMgr = newContextManager(&m.activeContext, modelCfg)
+	m.toolExec = tools.NewExecutor(&m.config.Tools, m.toolResultStore)
+	// The subagent manager reads m.activeContext, updated above; keep it so
+	// running subagents stay tracked.
+	tools.SetSubagentClient(m.apiClient)
+	return nil
+}
@@ -0,0 +1,106 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+const reloadTestConfig = `
+default: {provider: dummy, model: small}
+providers:
+  dummy:
+    type: openai-compat
+    base_url: http://127.0.0.1:0
+    api_key: dummy-key
+    models:
+      small: {model: small-model}
+      big: {model: big-model}
+tools:
+  auto_approve_tools: [read_file]
+`
+
+func TestConfigReloadAppliesEdits(t *testing.T) {
+	cfg, path := loadTestConfig(t, reloadTestConfig)
+	m, err := NewSimpleModel(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, exec := m.apiClient, m.toolExec
+
+	edited := strings.Replace(reloadTestConfig, "model: small}", "model: big}", 1)
+	edited = strings.Replace(edited, "[read_file]", "[read_file, list_tools]", 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Busy sessions reload once the turn is over.
+	m.loading = true
+	m.handleConfigWatchTick()
+	if m.currentModelName != "small" || !m.configReloadPending {
+		t.Fatal("the reload should wait for the turn to end")
+	}
+	m.loading = false
+	m.handleConfigWatchTick()
+	if m.currentModelName != "big" || m.apiClient == client || m.toolExec == exec {
+		t.Fatalf("model %s; the client and executor should be rebuilt", m.currentModelName)
+	}
+	if got := m.config.Tools.AutoApproveTools; len(got) != 2 {
+		t.Fatalf("auto approve tools = %v", got)
+	}
+	display := strings.Join(m.displayContent, "\n")
+	for _, want := range []string{"~ default.model: small -> big", "~ tools.auto_approve_tools: [read_file] -> [read_file, list_tools]"} {
+		if !strings.Contains(display, want) {
+			t.Errorf("display lacks %q:\n%s", want, display)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(edited+"  command_timeout: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.handleConfigWatchTick()
+	if m.currentModelName != "big" || len(m.config.Tools.AutoApproveTools) != 2 {
+		t.Fatal("an invalid config should leave the current one in place")
+	}
+	display = strings.Join(m.displayContent, "\n")
+	if !strings.Contains(display, "Config reload failed") || !strings.Contains(display, "tools.command_timeout") {
+		t.Errorf("display lacks the validation error:\n%s", display)
+	}
+}
+
+func TestConfigReloadKeepsSubagents(t *testing.T) {
+	server := newDummyChatServer(t, func(_ int, _ api.ChatCompletionRequest) []api.StreamResponse {
+		return []api.StreamResponse{{Choices: []api.Choice{{Delta: api.Message{Content: "done"}, FinishReason: "stop"}}}}
+	})
+	defer server.Close()
+	content := strings.Replace(reloadTestConfig, "http://127.0.0.1:0", server.URL, 1)
+	cfg, path := loadTestConfig(t, content)
+	m, err := NewSimpleModel(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := tools.SpawnSubagent(nil, "call_spawn", `{"prompt":"look around"}`)
+	if res.Error != nil {
+		t.Fatalf("spawn: %v", res.Error)
+	}
+	var out struct{ ID string }
+	if err := json.Unmarshal([]byte(res.Output), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := strings.Replace(content, "model: small}", "model: big}", 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m.handleConfigWatchTick()
+	if m.currentModelName != "big" {
+		t.Fatalf("model %s, want big", m.currentModelName)
+	}
+	if res := tools.WaitSubagent(nil, "call_wait", `{"id":"`+out.ID+`","timeout_seconds":5}`); res.Error != nil {
+		t.Fatalf("the subagent should survive the reload: %v", res.Error)
+	}
+}
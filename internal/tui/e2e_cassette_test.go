@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// TestE2E_CassetteRecordReplay records a tool round trip against the dummy
+// server and replays it with the server gone.
+func TestE2E_CassetteRecordReplay(t *testing.T) {
+	tmp := t.TempDir()
+	filePath := filepath.Join(tmp, "note.txt")
+	if err := os.WriteFile(filePath, []byte("cassette content\n"), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	cassettePath := filepath.Join(tmp, "session.jsonl")
+
+	server := newDummyChatServer(t, func(call int, _ api.ChatCompletionRequest) []api.StreamResponse {
+		if call == 0 {
+			args, _ := json.Marshal(map[string]string{"path": filePath})
+			return []api.StreamResponse{{Choices: []api.Choice{{
+				Delta: api.Message{ToolCalls: []api.ToolCall{{
+					ID: "call_1", Type: "function",
+					Function: api.FunctionCall{Name: "read_file", Arguments: string(args)},
+				}}},
+				FinishReason: "tool_calls",
+			}}}}
+		}
+		return []api.StreamResponse{
+			{Choices: []api.Choice{{Delta: api.Message{Content: "recorded answer"}}}},
+			{Choices: []api.Choice{{FinishReason: "stop"}}},
+		}
+	})
+
+	recording := newE2EModelWithProvider(t, config.ProviderConfig{
+		Type:    config.ProviderTypeOpenAICompat,
+		BaseURL: server.URL,
+		Timeout: 5 * time.Second,
+		Record:  cassettePath,
+	})
+	recording.workspaceRoot = "/"
+	if err := runCommandLoop(recording, recording.SendMessage("read the note"), 20); err != nil {
+		t.Fatalf("record run: %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Fatalf("expected 2 recorded exchanges, got %d", n)
+	}
+
+	replaying := newE2EModelWithProvider(t, config.ProviderConfig{
+		Type:    config.ProviderTypeReplay,
+		BaseURL: server.URL, // closed; any network access would fail
+		Timeout: 5 * time.Second,
+		Replay:  cassettePath,
+	})
+	replaying.workspaceRoot = "/"
+	if err := runCommandLoop(replaying, replaying.SendMessage("read the note"), 20); err != nil {
+		t.Fatalf("replay run: %v", err)
+	}
+
+	if len(replaying.messages) != len(recording.messages) {
+		t.Fatalf("replay produced %d messages, recording %d", len(replaying.messages), len(recording.messages))
+	}
+	last := replaying.messages[len(replaying.messages)-1]
+	if last.Role != "assistant" || last.Content != "recorded answer" {
+		t.Fatalf("unexpected replayed answer: %#v", last)
+	}
+}
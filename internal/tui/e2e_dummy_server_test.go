@@ -290,21 +290,28 @@ func TestE2E_ToolArgsDuplicatedInStartDelta(t *testing.T) {
 }
 
 func newE2EModel(t *testing.T, baseURL string) *SimpleModel {
+	t.Helper()
+	return newE2EModelWithProvider(t, config.ProviderConfig{
+		Type:    "openai-compat",
+		BaseURL: baseURL,
+		APIKey:  "dummy-key",
+		Timeout: 5 * time.Second,
+	})
+}
+
+// newE2EModelWithProvider builds a model whose only provider is prov, e.g.
+// one that records to or replays from a cassette.
+func newE2EModelWithProvider(t *testing.T, prov config.ProviderConfig) *SimpleModel {
 	t.Helper()
 	t.Setenv("XDG_DATA_HOME", t.TempDir())
 
+	prov.Models = map[string]config.ModelConfig{
+		"dummy-model": {Model: "dummy-model", Temperature: 0},
+	}
 	cfg := &config.Config{
 		Default: config.DefaultConfig{Provider: "dummy", Model: "dummy-model"},
 		Providers: map[string]config.ProviderConfig{
-			"dummy": {
-				Type:    "openai-compat",
-				BaseURL: baseURL,
-				APIKey:  "dummy-key",
-				Timeout: 5 * time.Second,
-				Models: map[string]config.ModelConfig{
-					"dummy-model": {Model: "dummy-model", Temperature: 0},
-				},
-			},
+			"dummy": prov,
 		},
 		Tools: config.ToolsConfig{
 			AutoApproveTools: []string{"read_file", "list_directory", "list_tools"},
@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// TestE2E_ScriptApprovalFlow drives a turn that needs tool approval from a
+// script provider, without any server.
+func TestE2E_ScriptApprovalFlow(t *testing.T) {
+	tmp := t.TempDir()
+	target := filepath.Join(tmp, "hello.txt")
+	script := filepath.Join(tmp, "demo.yaml")
+	if err := os.WriteFile(script, []byte(`
+turns:
+  - match: write
+    reasoning: The user wants a file.
+    content: Writing it now.
+    tool_calls:
+      - name: write_file
+        arguments:
+          path: `+target+`
+          content: hi
+  - content: Done, the file is written.
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newE2EModelWithProvider(t, config.ProviderConfig{Type: config.ProviderTypeScript, Script: script})
+	if err := runCommandLoop(m, m.SendMessage("write hello.txt"), 20); err != nil {
+		t.Fatalf("run command loop: %v", err)
+	}
+	if !m.waitingForApproval {
+		t.Fatal("write_file should wait for approval")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("file written before approval: %v", err)
+	}
+	if len(m.thinkingBlocks) != 1 {
+		t.Fatalf("expected the scripted reasoning as a thinking block, got %d", len(m.thinkingBlocks))
+	}
+
+	_, cmd := m.Update(tea.KeyPressMsg{Code: 'y', Text: "y"})
+	if err := runCommandLoop(m, cmd, 20); err != nil {
+		t.Fatalf("run command loop: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "hi" {
+		t.Fatalf("file = %q, err = %v", data, err)
+	}
+	last := m.messages[len(m.messages)-1]
+	if last.Role != "assistant" || last.Content != "Done, the file is written." {
+		t.Fatalf("unexpected final message: %#v", last)
+	}
+}
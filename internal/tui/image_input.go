@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+// clipboardImageMsg carries an image pasted from the clipboard.
+type clipboardImageMsg struct {
+	image api.ContentPart
+	err   error
+}
+
+// clipboardImageCommands are tried in order to read a PNG from the
+// clipboard: Wayland, X11, then macOS.
+var clipboardImageCommands = [][]string{
+	{"wl-paste", "--no-newline", "--type", "image/png"},
+	{"xclip", "-selection", "clipboard", "-t", "image/png", "-o"},
+	{"pngpaste", "-"},
+}
+
+// pasteClipboardImage reads an image from the system clipboard.
+func pasteClipboardImage() tea.Cmd {
+	return func() tea.Msg {
+		var errs []error
+		for _, args := range clipboardImageCommands {
+			if _, err := exec.LookPath(args[0]); err != nil {
+				continue
+			}
+			var stderr bytes.Buffer
+			cmd := exec.Command(args[0], args[1:]...)
+			cmd.Stderr = &stderr
+			data, err := cmd.Output()
+			if err != nil || len(data) == 0 {
+				errs = append(errs, fmt.Errorf("%s: %s", args[0], strings.TrimSpace(stderr.String())))
+				continue
+			}
+			if len(data) > tools.MaxImageSize {
+				return clipboardImageMsg{err: fmt.Errorf("clipboard image is larger than %d bytes", tools.MaxImageSize)}
+			}
+			image, err := tools.ImagePart(data)
+			return clipboardImageMsg{image: image, err: err}
+		}
+		if len(errs) == 0 {
+			return clipboardImageMsg{err: errors.New("no clipboard tool found (install wl-paste, xclip or pngpaste)")}
+		}
+		return clipboardImageMsg{err: fmt.Errorf("no image in clipboard: %w", errors.Join(errs...))}
+	}
+}
+
+// imageMentions returns the paths of image files mentioned as @path tokens
+// in input. Spaces in paths are escaped with a backslash, as inserted by @
+// completion.
+func imageMentions(input string) []string {
+	var paths []string
+	for _, token := range splitEscapedFields(input) {
+		path, ok := strings.CutPrefix(token, "@")
+		if ok && tools.IsImagePath(path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// splitEscapedFields splits s at unescaped whitespace and removes the
+// backslash escapes.
+func splitEscapedFields(s string) []string {
+	var (
+		fields []string
+		cur    strings.Builder
+		escape bool
+	)
+	for _, r := range s {
+		switch {
+		case escape:
+			cur.WriteRune(r)
+			escape = false
+		case r == '\\':
+			escape = true
+		case unicode.IsSpace(r):
+			if cur.Len() > 0 {
+				fields = append(fields, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		fields = append(fields, cur.String())
+	}
+	return fields
+}
+
+// takeInputImages returns the images to send with input: those pasted
+// since the last message followed by the @mentioned image files. Files that
+// cannot be loaded are reported as errors and skipped.
+func (m *SimpleModel) takeInputImages(input string) ([]api.ContentPart, []error) {
+	images := m.pendingImages
+	m.pendingImages = nil
+	var errs []error
+	for _, path := range imageMentions(input) {
+		image, err := tools.LoadImage(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		images = append(images, image)
+	}
+	return images, errs
+}
+
+// imageMarker is appended to a displayed user message with images.
+func imageMarker(n int) string {
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return " [1 image]"
+	default:
+		return fmt.Sprintf(" [%d images]", n)
+	}
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/session"
+)
+
+func TestImageMentions(t *testing.T) {
+	got := imageMentions(`compare @a.png and @dir/my\ shot.JPG with @notes.md`)
+	want := []string{"a.png", "dir/my shot.JPG"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("imageMentions = %q, want %q", got, want)
+	}
+}
+
+func TestUserMessageImagesPersistInSession(t *testing.T) {
+	server := newDummyChatServer(t, func(_ int, _ api.ChatCompletionRequest) []api.StreamResponse { return nil })
+	defer server.Close()
+
+	m := newE2EModel(t, server.URL)
+	m.addUserMessage("what is this?", api.NewImagePart("image/png", []byte("png")))
+
+	loaded, err := session.Load(m.sess.ID)
+	if err != nil {
+		t.Fatalf("Load session: %v", err)
+	}
+	last := loaded.Messages[len(loaded.Messages)-1]
+	if last.Content != "what is this?" || len(last.Images()) != 1 {
+		t.Fatalf("restored message = %+v", last)
+	}
+}
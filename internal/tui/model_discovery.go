@@ -0,0 +1,231 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// modelDiscoveryTimeout bounds asking all providers for their models.
+const modelDiscoveryTimeout = 30 * time.Second
+
+// modelsDiscoveredMsg carries the model lists fetched by discoverModels.
+type modelsDiscoveredMsg struct {
+	models map[string][]string
+	errs   map[string]error
+	// announce reports the result; automatic discovery stays quiet.
+	announce bool
+}
+
+// discoverModels asks providers for their models in the background. Without
+// force only providers whose cached list is stale are asked, and only once
+// per session.
+func (m *SimpleModel) discoverModels(force bool) tea.Cmd {
+	if !force && m.modelDiscoveryStarted {
+		return nil
+	}
+	m.modelDiscoveryStarted = true
+
+	providers := make(map[string]config.ProviderConfig)
+	for name, p := range m.config.Providers {
+		if !p.Discoverable() {
+			continue
+		}
+		if force || m.config.NeedsDiscovery(name) {
+			providers[name] = p
+		}
+	}
+	if len(providers) == 0 {
+		if force {
+			m.AddDisplayContent(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FFA500")).
+				Render("No providers support model discovery"), "")
+		}
+		return nil
+	}
+	if force {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262")).
+			Render("Discovering models..."), "")
+	}
+
+	ctxCfg := m.activeContext
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), modelDiscoveryTimeout)
+		defer cancel()
+
+		msg := modelsDiscoveredMsg{
+			models:   make(map[string][]string),
+			errs:     make(map[string]error),
+			announce: force,
+		}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, p := range providers {
+			wg.Go(func() {
+				ids, err := api.NewClient(&p, &config.ModelConfig{}, &ctxCfg).ListModels(ctx)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					msg.errs[name] = err
+				} else {
+					msg.models[name] = ids
+				}
+			})
+		}
+		wg.Wait()
+		return msg
+	}
+}
+
+// handleModelsDiscovered stores discovered models and refreshes the picker.
+func (m *SimpleModel) handleModelsDiscovered(msg modelsDiscoveredMsg) {
+	for name, ids := range msg.models {
+		m.config.SetDiscovered(name, ids)
+	}
+	if len(msg.models) > 0 {
+		if err := m.config.SaveModelCache(); err != nil {
+			slog.Warn("Failed to save model cache", slog.Any("error", err))
+		}
+	}
+	m.updateCompletionState()
+
+	for name, err := range msg.errs {
+		slog.Warn("Model discovery failed", slog.String("provider", name), slog.Any("error", err))
+	}
+	if !msg.announce {
+		return
+	}
+	names := make([]string, 0, len(msg.models))
+	for name := range msg.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#04B575")).
+			Render(fmt.Sprintf("%s: %d models", name, len(msg.models[name]))))
+	}
+	errNames := make([]string, 0, len(msg.errs))
+	for name := range msg.errs {
+		errNames = append(errNames, name)
+	}
+	sort.Strings(errNames)
+	for _, name := range errNames {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF7F50")).
+			Render(fmt.Sprintf("%s: discovery failed: %v", name, msg.errs[name])))
+	}
+	m.AddDisplayContent("")
+}
+
+// modelCommand implements /model [--refresh] [[--once] name [--save]].
+func (m *SimpleModel) modelCommand(args []string) tea.Cmd {
+	var name string
+	save, refresh, once := false, false, false
+	for _, arg := range args {
+		switch arg {
+		case "--save":
+			save = true
+		case "--refresh":
+			refresh = true
+		case "--once":
+			once = true
+		default:
+			name = arg
+		}
+	}
+	if refresh {
+		return m.discoverModels(true)
+	}
+	if name == "" {
+		input := "/model "
+		if once {
+			input = "/model --once "
+		}
+		m.textarea.SetValue(input)
+		m.textarea.CursorEnd()
+		m.updateCompletionState()
+		return m.discoverModels(false)
+	}
+
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FF3333"))
+	if once {
+		if save {
+			m.AddDisplayContent(errStyle.Render("--once cannot be combined with --save"), "")
+			return nil
+		}
+		m.useModelOnce(name)
+		return nil
+	}
+	if err := m.switchModel(name); err != nil {
+		m.AddDisplayContent(errStyle.Render(fmt.Sprintf("Error switching model: %v", err)), "")
+		return nil
+	}
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#04B575")).
+		Render(fmt.Sprintf("Switched to model: %s (provider: %s)", m.currentModelName, m.currentProviderName)), "")
+	if save {
+		ref := config.ModelRef{Provider: m.currentProviderName, Model: m.currentModelName}
+		path, err := m.config.SaveModel(ref)
+		if err != nil {
+			m.AddDisplayContent(errStyle.Render(fmt.Sprintf("Error saving model: %v", err)), "")
+			return nil
+		}
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262")).
+			Render(fmt.Sprintf("Saved %s to %s", ref, path)), "")
+	}
+	return nil
+}
+
+// filteredModelNames returns model names (across all providers) that match
+// the given prefix. A discovered model served by several providers is listed
+// once per provider as "provider/id".
+func (m *SimpleModel) filteredModelNames(prefix string) []string {
+	entries := m.config.AllModelNames()
+	discovered := make(map[string]int)
+	for _, entry := range entries {
+		if entry.Discovered {
+			discovered[entry.Model]++
+		}
+	}
+	seen := make(map[string]bool)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Model
+		if entry.Discovered && discovered[name] > 1 {
+			name = entry.Provider + "/" + name
+		}
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelProvider returns the provider column shown next to a model in the
+// picker.
+func (m *SimpleModel) modelProvider(modelName string) string {
+	for _, entry := range m.config.AllModelNames() {
+		if entry.Model == modelName || entry.Provider+"/"+entry.Model == modelName {
+			if entry.Discovered {
+				return entry.Provider + " (discovered)"
+			}
+			return entry.Provider
+		}
+	}
+	return ""
+}
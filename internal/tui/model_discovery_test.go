@@ -0,0 +1,28 @@
+package tui
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestFilteredModelNamesIncludesDiscoveredModels(t *testing.T) {
+	cfg := &config.Config{Providers: map[string]config.ProviderConfig{
+		"openai": {Models: map[string]config.ModelConfig{"gpt4": {Model: "gpt-4.1"}}},
+		"local":  {},
+	}}
+	cfg.SetDiscovered("openai", []string{"gpt-4.1", "gpt-5", "llama3"})
+	cfg.SetDiscovered("local", []string{"llama3"})
+	m := &SimpleModel{config: cfg}
+
+	if got := m.filteredModelNames("gpt"); !slices.Equal(got, []string{"gpt-5", "gpt4"}) {
+		t.Fatalf("gpt = %v", got)
+	}
+	if got := m.filteredModelNames(""); !slices.Contains(got, "local/llama3") || !slices.Contains(got, "openai/llama3") {
+		t.Fatalf("ambiguous models should carry their provider: %v", got)
+	}
+	if got := m.modelProvider("gpt-5"); got != "openai (discovered)" {
+		t.Fatalf("modelProvider = %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"charm.land/lipgloss/v2"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+// turnClient returns the client serving the current turn: the one-turn
+// model when one was picked, the session's model otherwise.
+func (m *SimpleModel) turnClient() *api.Client {
+	if m.onceClient != nil {
+		return m.onceClient
+	}
+	return m.apiClient
+}
+
+// newOnceClient creates a client for a named model without switching the
+// session to it.
+func (m *SimpleModel) newOnceClient(name string) (*api.Client, error) {
+	provName, modelName, modelCfg, err := m.resolveModel(name)
+	if err != nil {
+		return nil, err
+	}
+	ctxCfg := m.activeContext
+	if modelCfg.Context != nil {
+		ctxCfg = *modelCfg.Context
+	}
+	client, err := api.NewClientForModel(m.config, provName, modelName, &ctxCfg)
+	if err != nil {
+		return nil, err
+	}
+	client.SetUsageObserver(m.recordUsage)
+	return client, nil
+}
+
+// useModelOnce makes the next turn use the named model.
+func (m *SimpleModel) useModelOnce(name string) {
+	client, err := m.newOnceClient(name)
+	if err != nil {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF3333")).
+			Render(fmt.Sprintf("Error selecting model: %v", err)), "")
+		return
+	}
+	m.nextOnceClient = client
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#04B575")).
+		Render(fmt.Sprintf("Next turn uses model: %s", client.Name())), "")
+}
+
+// beginTurn starts an agentic turn with the model picked for it, if any.
+// Subagents spawned during the turn use the same model.
+func (m *SimpleModel) beginTurn() {
+	m.onceClient, m.nextOnceClient = m.nextOnceClient, nil
+	tools.SetSubagentClient(m.turnClient())
+}
+
+// endTurn reverts a one-turn model once the turn has finished or was
+// abandoned. A failed turn keeps it so /retry resends to the same model.
+func (m *SimpleModel) endTurn() {
+	if m.onceClient == nil {
+		return
+	}
+	m.onceClient = nil
+	tools.SetSubagentClient(m.apiClient)
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#626262")).
+		Render("Back to model: "+m.apiClient.Name()), "")
+}
+
+// parseModelOverride splits a "@@model prompt" input into the model name and
+// the prompt.
+func parseModelOverride(input string) (name, prompt string, ok bool) {
+	rest, found := strings.CutPrefix(input, "@@")
+	if !found {
+		return "", "", false
+	}
+	i := strings.IndexFunc(rest, unicode.IsSpace)
+	if i < 0 {
+		i = len(rest)
+	}
+	if i == 0 {
+		return "", "", false
+	}
+	return rest[:i], strings.TrimSpace(rest[i:]), true
+}
+
+// modelArgQuery reports whether input is completing a model name after
+// "/model ", "/model --once " or a leading "@@", and splits it into the text
+// before the name and the partial name.
+func modelArgQuery(input string) (prefix, query string, ok bool) {
+	for _, p := range []string{"/model --once ", "/model ", "@@"} {
+		if rest, found := strings.CutPrefix(input, p); found && !strings.ContainsAny(rest, " \t\n") {
+			return p, rest, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+	"github.com/tokuhirom/ashron/internal/tools"
+)
+
+func TestParseModelOverride(t *testing.T) {
+	tests := []struct {
+		input, name, prompt string
+		ok                  bool
+	}{
+		{"@@gpt-5 explain this", "gpt-5", "explain this", true},
+		{"@@openai/gpt-5\nmulti\nline", "openai/gpt-5", "multi\nline", true},
+		{"@@gpt-5", "gpt-5", "", true},
+		{"@@ hello", "", "", false},
+		{"hello @@gpt-5", "", "", false},
+		{"@main.go", "", "", false},
+	}
+	for _, tt := range tests {
+		name, prompt, ok := parseModelOverride(tt.input)
+		if name != tt.name || prompt != tt.prompt || ok != tt.ok {
+			t.Errorf("parseModelOverride(%q) = %q, %q, %v", tt.input, name, prompt, ok)
+		}
+	}
+}
+
+func TestE2E_ModelOverrideLastsOneTurn(t *testing.T) {
+	tmp := t.TempDir()
+	filePath := filepath.Join(tmp, "note.txt")
+	if err := os.WriteFile(filePath, []byte("note\n"), 0644); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+
+	var mu sync.Mutex
+	var models []string
+	server := newDummyChatServer(t, func(call int, req api.ChatCompletionRequest) []api.StreamResponse {
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		if call == 0 {
+			args, _ := json.Marshal(map[string]string{"path": filePath})
+			return []api.StreamResponse{{Choices: []api.Choice{{
+				Delta: api.Message{ToolCalls: []api.ToolCall{{
+					ID:       "call_1",
+					Type:     "function",
+					Function: api.FunctionCall{Name: "read_file", Arguments: string(args)},
+				}}},
+				FinishReason: "tool_calls",
+			}}}}
+		}
+		return []api.StreamResponse{
+			{Choices: []api.Choice{{Delta: api.Message{Content: "done"}}}},
+			{Choices: []api.Choice{{FinishReason: "stop"}}},
+		}
+	})
+	defer server.Close()
+
+	m := newE2EModel(t, server.URL)
+	prov := m.config.Providers["dummy"]
+	prov.Models = map[string]config.ModelConfig{
+		"dummy-model": {Model: "dummy-model"},
+		"big":         {Model: "big-model"},
+	}
+	m.config.Providers["dummy"] = prov
+
+	if err := runCommandLoop(m, m.SendMessage("@@big read the note"), 20); err != nil {
+		t.Fatalf("run command loop: %v", err)
+	}
+	if m.onceClient != nil {
+		t.Fatal("the override should end with the turn")
+	}
+	if err := runCommandLoop(m, m.SendMessage("thanks"), 10); err != nil {
+		t.Fatalf("run command loop: %v", err)
+	}
+
+	if want := []string{"big-model", "big-model", "dummy-model"}; !slices.Equal(models, want) {
+		t.Fatalf("requests used %v, want %v", models, want)
+	}
+
+	var prompts, answeredBy []string
+	for _, msg := range m.messages {
+		switch msg.Role {
+		case "user":
+			prompts = append(prompts, msg.Content)
+		case "assistant":
+			answeredBy = append(answeredBy, msg.Model)
+		}
+	}
+	if len(prompts) == 0 || prompts[0] != "read the note" {
+		t.Fatalf("the @@ prefix should be stripped: %q", prompts)
+	}
+	if want := []string{"dummy/big", "dummy/big", "dummy/dummy-model"}; !slices.Equal(answeredBy, want) {
+		t.Fatalf("assistant messages answered by %v", answeredBy)
+	}
+}
+
+func TestModelOnceAppliesToNextTurnOnly(t *testing.T) {
+	m := newE2EModel(t, "http://127.0.0.1:0")
+	m.modelCommand([]string{"--once", "dummy-model"})
+	if m.nextOnceClient == nil || m.onceClient != nil {
+		t.Fatal("--once should wait for the next message")
+	}
+	if got := m.config.Default.Model; got != "dummy-model" {
+		t.Fatalf("default model changed to %q", got)
+	}
+	m.beginTurn()
+	if m.turnClient() != m.onceClient || m.nextOnceClient != nil {
+		t.Fatal("the next turn should take the picked model")
+	}
+	m.beginTurn()
+	if m.turnClient() != m.apiClient {
+		t.Fatal("the turn after should use the session's model")
+	}
+}
+
+func TestSubagentsUseTheTurnModel(t *testing.T) {
+	var mu sync.Mutex
+	var models []string
+	server := newDummyChatServer(t, func(_ int, req api.ChatCompletionRequest) []api.StreamResponse {
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		return []api.StreamResponse{
+			{Choices: []api.Choice{{Delta: api.Message{Content: "done"}}}},
+			{Choices: []api.Choice{{FinishReason: "stop"}}},
+		}
+	})
+	defer server.Close()
+
+	m := newE2EModel(t, server.URL)
+	prov := m.config.Providers["dummy"]
+	prov.Models["big"] = config.ModelConfig{Model: "big-model"}
+	m.config.Providers["dummy"] = prov
+
+	spawnAndWait := func() {
+		t.Helper()
+		res := tools.SpawnSubagent(nil, "call_spawn", `{"prompt":"look around"}`)
+		if res.Error != nil {
+			t.Fatalf("spawn: %v", res.Error)
+		}
+		var out struct{ ID string }
+		if err := json.Unmarshal([]byte(res.Output), &out); err != nil {
+			t.Fatal(err)
+		}
+		if res := tools.WaitSubagent(nil, "call_wait", `{"id":"`+out.ID+`","timeout_seconds":5}`); res.Error != nil {
+			t.Fatalf("wait: %v", res.Error)
+		}
+	}
+
+	m.useModelOnce("big")
+	m.beginTurn()
+	spawnAndWait()
+	m.endTurn()
+	spawnAndWait()
+
+	if want := []string{"big-model", "dummy-model"}; !slices.Equal(models, want) {
+		t.Fatalf("subagent requests used %v, want %v", models, want)
+	}
+}
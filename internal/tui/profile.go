@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// profileCommand implements /profile [name|none].
+func (m *SimpleModel) profileCommand(args []string) tea.Cmd {
+	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262"))
+	if len(args) == 0 {
+		names := m.config.ProfileNames()
+		if len(names) == 0 {
+			m.AddDisplayContent(infoStyle.Render("No profiles configured. Add them under profiles: in "+config.FilePath()), "")
+			return nil
+		}
+		var sb strings.Builder
+		sb.WriteString("Profiles:")
+		for _, name := range names {
+			marker := "  "
+			if name == m.config.Profile {
+				marker = "* "
+			}
+			sb.WriteString("\n  " + marker + name)
+		}
+		sb.WriteString("\nUsage: /profile <name> to switch, /profile none to clear")
+		for _, line := range strings.Split(infoStyle.Render(sb.String()), "\n") {
+			m.AddDisplayContent(line)
+		}
+		m.AddDisplayContent("")
+		return nil
+	}
+
+	name := args[0]
+	if name == "none" {
+		name = ""
+	}
+	if err := m.switchProfile(name); err != nil {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#FF3333")).
+			Render(fmt.Sprintf("Error switching profile: %v", err)), "")
+		return nil
+	}
+	msg := "Cleared profile"
+	if name != "" {
+		msg = "Switched to profile: " + name
+	}
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#04B575")).
+		Render(fmt.Sprintf("%s (model: %s, provider: %s)", msg, m.currentModelName, m.currentProviderName)), "")
+	return nil
+}
+
+// switchProfile applies the named profile, or none for "". The
+// conversation is kept.
+func (m *SimpleModel) switchProfile(name string) error {
+	cfg, err := m.config.WithProfile(name)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	return m.applyConfig(cfg)
+}
+
+// filteredProfileNames returns the profile names, and "none", that match
+// the given prefix.
+func (m *SimpleModel) filteredProfileNames(prefix string) []string {
+	var names []string
+	for _, name := range append(m.config.ProfileNames(), "none") {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+// loadTestConfig writes content as the user's config file and loads it.
+func loadTestConfig(t *testing.T, content string) (*config.Config, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	path := filepath.Join(dir, "ashron", "ashron.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg, path
+}
+
+func TestProfileCommandSwitchesLive(t *testing.T) {
+	cfg, _ := loadTestConfig(t, `
+default: {provider: dummy, model: small}
+providers:
+  dummy:
+    type: openai-compat
+    base_url: http://127.0.0.1:0
+    api_key: dummy-key
+    models:
+      small: {model: small-model}
+      big: {model: big-model}
+default_context: {max_tokens: 8000}
+profiles:
+  work:
+    default: {model: big}
+    tools: {auto_approve_tools: [list_tools]}
+    default_context: {max_tokens: 2000}
+`)
+	m, err := NewSimpleModel(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, exec := m.apiClient, m.toolExec
+
+	if got := m.filteredProfileNames(""); !slices.Equal(got, []string{"none", "work"}) {
+		t.Fatalf("completions = %v", got)
+	}
+	m.profileCommand([]string{"work"})
+	if m.config.Profile != "work" || m.currentModelName != "big" || m.activeContext.MaxTokens != 2000 {
+		t.Fatalf("profile %q, model %s, max tokens %d", m.config.Profile, m.currentModelName, m.activeContext.MaxTokens)
+	}
+	if m.apiClient == client || m.toolExec == exec {
+		t.Fatal("the API client and tool executor should be rebuilt")
+	}
+	if !slices.Equal(m.config.Tools.AutoApproveTools, []string{"list_tools"}) {
+		t.Fatalf("auto approve tools = %v", m.config.Tools.AutoApproveTools)
+	}
+
+	m.profileCommand([]string{"home"})
+	if m.config.Profile != "work" {
+		t.Fatal("an unknown profile should leave the current one in place")
+	}
+	m.profileCommand([]string{"none"})
+	if m.config.Profile != "" || m.currentModelName != "small" || m.activeContext.MaxTokens != 8000 {
+		t.Fatalf("profile %q, model %s, max tokens %d", m.config.Profile, m.currentModelName, m.activeContext.MaxTokens)
+	}
+}
@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tokuhirom/ashron/internal/api"
+)
+
+func TestFailedStreamDropsPartialAssistantMessage(t *testing.T) {
+	m := &SimpleModel{width: 80, messages: []api.Message{api.NewUserMessage("hi")}}
+	stream := make(chan api.StreamEvent, 2)
+	stream <- api.StreamEvent{Data: &api.StreamResponse{Choices: []api.Choice{{Delta: api.Message{Role: "assistant", Content: "half an ans"}}}}}
+	stream <- api.StreamEvent{Error: errors.New("stream stalled")}
+	close(stream)
+
+	if _, ok := m.processStreamNew(context.Background(), stream).(errorMsg); !ok {
+		t.Fatal("expected errorMsg")
+	}
+	if len(m.messages) != 1 || m.messages[0].Role != "user" {
+		t.Fatalf("history should end at the user message: %+v", m.messages)
+	}
+}
+
+func TestRetryLastTurnNeedsUnansweredMessage(t *testing.T) {
+	m := &SimpleModel{width: 80, messages: []api.Message{
+		api.NewUserMessage("hi"),
+		{Role: "assistant", Content: "hello"},
+	}}
+	if cmd := m.RetryLastTurn(); cmd != nil || m.loading {
+		t.Fatal("nothing should be retried after a completed turn")
+	}
+}
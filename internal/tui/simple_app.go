@@ -30,7 +30,9 @@ import (
 	"github.com/tokuhirom/ashron/internal/config"
 	contextmgr "github.com/tokuhirom/ashron/internal/context"
 	"github.com/tokuhirom/ashron/internal/session"
+	"github.com/tokuhirom/ashron/internal/tokenizer"
 	"github.com/tokuhirom/ashron/internal/tools"
+	"github.com/tokuhirom/ashron/internal/usage"
 )
 
 // SimpleModel represents the simplified streaming application state
@@ -86,21 +88,50 @@ type SimpleModel struct {
 	operationStartedAt time.Time
 	lastUserInput      string
 
+	// pendingImages are clipboard images to attach to the next message.
+	pendingImages []api.ContentPart
+
+	// toolset keeps the tool definitions stable across the turns of a
+	// session for prompt caching.
+	toolset tools.Toolset
+
+	// answeredBy is the "provider/model" serving the current request; it
+	// differs from turnClient().Name() after a fallback.
+	answeredBy string
+
+	// onceClient serves the current agentic turn, tool loop included,
+	// instead of apiClient when the prompt picked a model for one turn.
+	onceClient *api.Client
+	// nextOnceClient is picked by /model --once and becomes onceClient when
+	// the next message is sent.
+	nextOnceClient *api.Client
+
 	commandRegistry *CommandRegistry
 
 	// Command completion state
 	showCompletion  bool
 	completionIndex int
+	// modelDiscoveryStarted is set once /model has asked providers for
+	// their models in this session.
+	modelDiscoveryStarted bool
 
 	// Display content - stores all conversation output
 	displayContent []string
+	// thinkingBlocks are the reasoning blocks in displayContent, which
+	// Ctrl+T expands or collapses together.
+	thinkingBlocks []thinkingBlock
+	showThinking   bool
 
 	// Token usage tracking
 	currentUsage            *api.Usage
 	sessionPromptTokens     int
+	sessionCachedTokens     int
 	sessionCompletionTokens int
-	toolResultStore         *tools.ResultStore
-	scratchpad              *tools.Scratchpad
+	// usageLedger accumulates usage and cost of every API request in the
+	// session, including summarization and subagents; saved with the session.
+	usageLedger     *usage.Ledger
+	toolResultStore *tools.ResultStore
+	scratchpad      *tools.Scratchpad
 
 	availableSkills         []skills.Skill
 	availableCustomCommands []customcmd.Command
@@ -125,6 +156,11 @@ type SimpleModel struct {
 
 	// viewportDirty tracks whether displayContent has changed since the last SetContent call.
 	viewportDirty bool
+
+	// configWatcher notices edits to the config files; a reload found while
+	// busy is marked pending until the turn ends.
+	configWatcher       *config.Watcher
+	configReloadPending bool
 }
 
 type fsAccessKind string
@@ -152,10 +188,13 @@ func NewSimpleModel(cfg *config.Config, sess *session.Session) (*SimpleModel, er
 	}
 	workspaceRoot = filepath.Clean(workspaceRoot)
 
-	provName, provCfg, err := cfg.ActiveProvider()
+	provName, _, err := cfg.ActiveProvider()
 	if err != nil {
 		return nil, err
 	}
+	if err := cfg.LoadModelCache(); err != nil {
+		slog.Warn("Failed to load model cache", slog.Any("error", err))
+	}
 	modelName, modelCfg, err := cfg.ActiveModel()
 	if err != nil {
 		return nil, err
@@ -166,16 +205,18 @@ func NewSimpleModel(cfg *config.Config, sess *session.Session) (*SimpleModel, er
 	}
 
 	// Create API client
-	apiClient := api.NewClient(provCfg, modelCfg, activeCtx)
+	apiClient, err := api.NewClientForModel(cfg, provName, modelName, activeCtx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create a context manager
-	ctxMgr := contextmgr.NewManager(activeCtx)
+	ctxMgr := newContextManager(activeCtx, modelCfg)
 
 	// Create tool executor
 	resultStore := tools.NewResultStore()
 	scratchpad := tools.NewScratchpad()
 	toolExec := tools.NewExecutor(&cfg.Tools, resultStore)
-	tools.ConfigureSubagentRuntime(apiClient, activeCtx)
 	tools.ConfigureScratchpad(scratchpad)
 
 	// Create UI components
@@ -244,7 +285,11 @@ func NewSimpleModel(cfg *config.Config, sess *session.Session) (*SimpleModel, er
 		displayContent:          initDisplay,
 		sess:                    sess,
 		isResume:                isResume,
+		usageLedger:             usage.NewLedger(sess.Usage),
+		configWatcher:           config.NewWatcher(cfg),
 	}
+	apiClient.SetUsageObserver(m.recordUsage)
+	tools.ConfigureSubagentRuntime(apiClient, &m.activeContext)
 
 	if isResume {
 		m.restoreSessionDisplay()
@@ -262,12 +307,19 @@ func (m *SimpleModel) SessionID() string {
 	return m.sess.ID
 }
 
+// recordUsage is the API client's usage observer. It runs on whichever
+// goroutine finished the request.
+func (m *SimpleModel) recordUsage(report api.UsageReport) {
+	m.usageLedger.Add(report)
+}
+
 // saveSession updates the session messages and writes to disk.
 func (m *SimpleModel) saveSession() {
 	if m.sess == nil {
 		return
 	}
 	m.sess.Messages = m.messages
+	m.sess.Usage = m.usageLedger.Records()
 	if err := m.sess.Save(); err != nil {
 		slog.Warn("Failed to save session", "error", err)
 	}
@@ -284,14 +336,15 @@ func (m *SimpleModel) restoreSessionDisplay() {
 	for _, msg := range m.messages {
 		switch msg.Role {
 		case "user":
-			if msg.Content == "" {
+			images := len(msg.Images())
+			if msg.Content == "" && images == 0 {
 				continue
 			}
 			displayInput := compactUserInputForDisplay(msg.Content)
 			line := lipgloss.NewStyle().
 				Foreground(lipgloss.Color("#04B575")).
 				Bold(true).
-				Render("You: ") + displayInput
+				Render("You: ") + displayInput + imageMarker(images)
 			m.displayContent = append(m.displayContent, line, "")
 		case "assistant":
 			if msg.Content == "" {
@@ -320,9 +373,27 @@ func (m *SimpleModel) restoreSessionDisplay() {
 	m.viewportDirty = true
 }
 
-// switchModel switches to a named model (searching all providers).
-func (m *SimpleModel) switchModel(modelName string) error {
-	provName, provCfg, modelCfg, err := m.config.FindModel(modelName)
+// resolveModel finds a named model, searching the configured models of all
+// providers and then the discovered ones, and returns the provider and alias
+// it is configured under.
+func (m *SimpleModel) resolveModel(modelName string) (string, string, *config.ModelConfig, error) {
+	provName, _, modelCfg, err := m.config.FindModel(modelName)
+	if err == nil {
+		return provName, modelName, modelCfg, nil
+	}
+	ref, err := m.config.UseDiscoveredModel(modelName)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if _, modelCfg, err = m.config.LookupModel(ref); err != nil {
+		return "", "", nil, err
+	}
+	return ref.Provider, ref.Model, modelCfg, nil
+}
+
+// switchModel switches to a named model.
+func (m *SimpleModel) switchModel(name string) error {
+	provName, modelName, modelCfg, err := m.resolveModel(name)
 	if err != nil {
 		return err
 	}
@@ -335,12 +406,30 @@ func (m *SimpleModel) switchModel(modelName string) error {
 	} else {
 		m.activeContext = m.config.DefaultContext
 	}
-	m.apiClient = api.NewClient(provCfg, modelCfg, &m.activeContext)
-	m.contextMgr = contextmgr.NewManager(&m.activeContext)
+	apiClient, err := api.NewClientForModel(m.config, provName, modelName, &m.activeContext)
+	if err != nil {
+		return err
+	}
+	m.apiClient = apiClient
+	m.apiClient.SetUsageObserver(m.recordUsage)
+	m.contextMgr = newContextManager(&m.activeContext, modelCfg)
 	tools.ConfigureSubagentRuntime(m.apiClient, &m.activeContext)
 	return nil
 }
 
+// newContextManager creates a context manager that counts tokens with the
+// tokenizer configured for (or guessed from) modelCfg.
+func newContextManager(ctxCfg *config.ContextConfig, modelCfg *config.ModelConfig) *contextmgr.Manager {
+	mgr := contextmgr.NewManager(ctxCfg)
+	tok, err := tokenizer.ForModel(modelCfg.Tokenizer, modelCfg.Model)
+	if err != nil {
+		slog.Warn("Unknown tokenizer, falling back to chars/4", "error", err)
+		return mgr
+	}
+	mgr.SetTokenizer(tok)
+	return mgr
+}
+
 // subagentTickMsg is sent by the periodic subagent status ticker.
 type subagentTickMsg time.Time
 
@@ -361,6 +450,7 @@ func loadingTick() tea.Cmd {
 
 // Init initializes the model
 func (m *SimpleModel) Init() tea.Cmd {
+	m.showConfigWarnings()
 	m.ReadAgentsMD()
 	m.viewportDirty = true
 	// Initialize viewport content
@@ -368,9 +458,23 @@ func (m *SimpleModel) Init() tea.Cmd {
 		textarea.Blink,
 		m.spinner.Tick,
 		subagentTick(),
+		configWatchTick(),
 	)
 }
 
+// showConfigWarnings shows the issues found in the config files, such as
+// unknown keys, which did not stop ashron from starting.
+func (m *SimpleModel) showConfigWarnings() {
+	if len(m.config.Warnings) == 0 {
+		return
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	for _, w := range m.config.Warnings {
+		m.AddDisplayContent(style.Render(w.String()))
+	}
+	m.AddDisplayContent(style.Render(`Run "ashron config validate" to check the config files.`), "")
+}
+
 func (m *SimpleModel) ReadAgentsMD() {
 	// On resume the AGENTS.md system message is already in the conversation history.
 	if m.isResume {
@@ -392,6 +496,12 @@ func (m *SimpleModel) ReadAgentsMD() {
 	m.session.Messages = append(m.session.Messages, api.NewSystemMessage(string(content)))
 }
 
+// InitialMessages returns the system messages a new session starts with,
+// for front ends that run the agent without the TUI.
+func InitialMessages(toolsCfg *config.ToolsConfig) []api.Message {
+	return initialMessagesForNewSession(skills.Discover(), toolsCfg)
+}
+
 func initialMessagesForNewSession(availableSkills []skills.Skill, toolsCfg *config.ToolsConfig) []api.Message {
 	systemPrompt := `You are Ashron, an AI coding assistant. You help users with programming tasks by:
 - Writing and editing code
@@ -453,6 +563,9 @@ func (m *SimpleModel) StartNewSession() tea.Cmd {
 
 	newSess := session.New(m.currentProviderName, m.currentModelName)
 	m.sess = newSess
+	m.usageLedger = usage.NewLedger(nil)
+	m.pendingImages = nil
+	m.toolset = tools.Toolset{}
 	m.isResume = false
 	m.scrolledToBottom = false
 
@@ -548,6 +661,12 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case 'j':
 				m.textarea.InsertString("\n")
 				return m, nil
+			case 'v':
+				m.statusMsg = "Reading image from clipboard..."
+				return m, pasteClipboardImage()
+			case 't':
+				m.toggleThinking()
+				return m, nil
 			}
 		}
 
@@ -655,9 +774,12 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Usage != nil {
 			m.currentUsage = msg.Usage
 			m.sessionPromptTokens += msg.Usage.PromptTokens
+			m.sessionCachedTokens += msg.Usage.CachedTokens()
 			m.sessionCompletionTokens += msg.Usage.CompletionTokens
 		}
 
+		m.addThinkingBlock(msg.Reasoning)
+
 		// Render the assistant's markdown text and add to display.
 		assistantLabel := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#FAFAFA")).
@@ -679,6 +801,11 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for _, line := range msg.ToolLines {
 			m.AddDisplayContent(line)
 		}
+		if msg.Model != "" && msg.Model != m.turnClient().Name() {
+			m.AddDisplayContent(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FFA500")).
+				Render("↪ Answered by fallback model " + msg.Model))
+		}
 
 		// Check if we have pending tool calls
 		if len(m.pendingToolCalls) > 0 {
@@ -692,6 +819,8 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.startToolExecution()
 		}
 
+		m.endTurn()
+
 		// Auto-save session after assistant response
 		m.saveSession()
 		m.savePlanIfNeeded(msg.AssistantText)
@@ -700,6 +829,15 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.sendCompletionNotification()
 		return m, nil
 
+	case clipboardImageMsg:
+		if msg.err != nil {
+			m.statusMsg = "Paste image failed: " + msg.err.Error()
+			return m, nil
+		}
+		m.pendingImages = append(m.pendingImages, msg.image)
+		m.statusMsg = fmt.Sprintf("Image attached (%d pending); it is sent with your next message", len(m.pendingImages))
+		return m, nil
+
 	case toolExecutionMsg:
 		// Handle tool execution result
 		m.handleToolResult(msg)
@@ -752,6 +890,12 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for _, line := range lines {
 			m.AddDisplayContent(line)
 		}
+		if hint := api.SuggestNextAction(msg.error); hint != "" {
+			m.AddDisplayContent(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#888888")).
+				Width(width).
+				Render("  → " + hint))
+		}
 		m.AddDisplayContent("")
 		return m, nil
 
@@ -772,10 +916,17 @@ func (m *SimpleModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.subagentSummary = tools.GetSubagentsSummary()
 		return m, subagentTick()
 
+	case configWatchTickMsg:
+		return m, m.handleConfigWatchTick()
+
 	case shellCmdMsg:
 		m.handleShellCmdMsg(msg)
 		return m, nil
 
+	case modelsDiscoveredMsg:
+		m.handleModelsDiscovered(msg)
+		return m, nil
+
 	case compactDoneMsg:
 		m.messages = msg.compacted
 		if msg.err != nil {
@@ -834,6 +985,9 @@ func (m *SimpleModel) View() tea.View {
 // completionItems returns the current list of completion candidates based on textarea input.
 func (m *SimpleModel) completionItems() []string {
 	input := m.textarea.Value()
+	if _, query, ok := modelArgQuery(input); ok {
+		return m.filteredModelNames(query)
+	}
 	if strings.HasPrefix(input, "/") {
 		spaceIdx := strings.Index(input, " ")
 		if spaceIdx == -1 {
@@ -846,6 +1000,8 @@ func (m *SimpleModel) completionItems() []string {
 		switch cmd {
 		case "/model":
 			return m.filteredModelNames(argPrefix)
+		case "/profile":
+			return m.filteredProfileNames(argPrefix)
 		}
 		return nil
 	}
@@ -861,6 +1017,9 @@ func (m *SimpleModel) completionItems() []string {
 // For argument completion it's the command + space (e.g. "/model ").
 func (m *SimpleModel) completionArgPrefix() string {
 	input := m.textarea.Value()
+	if prefix, _, ok := modelArgQuery(input); ok {
+		return prefix
+	}
 	if strings.HasPrefix(input, "/") {
 		spaceIdx := strings.Index(input, " ")
 		if spaceIdx == -1 {
@@ -941,28 +1100,6 @@ func escapePathForToken(path string) string {
 	return replacer.Replace(path)
 }
 
-// filteredModelNames returns model names (across all providers) that match the given prefix.
-func (m *SimpleModel) filteredModelNames(prefix string) []string {
-	var names []string
-	for _, entry := range m.config.AllModelNames() {
-		if strings.HasPrefix(entry.Model, prefix) {
-			names = append(names, entry.Model)
-		}
-	}
-	sort.Strings(names)
-	return names
-}
-
-// modelProvider returns the provider name for the given model alias.
-func (m *SimpleModel) modelProvider(modelName string) string {
-	for _, entry := range m.config.AllModelNames() {
-		if entry.Model == modelName {
-			return entry.Provider
-		}
-	}
-	return ""
-}
-
 // updateInputMode switches the textarea prompt and border to reflect whether the
 // user is typing a shell command (! prefix) or a normal message.
 func (m *SimpleModel) updateInputMode() {
@@ -999,7 +1136,7 @@ func (m *SimpleModel) renderCompletion() string {
 	isCommandMode := strings.HasPrefix(m.textarea.Value(), "/")
 	isArgMode := isCommandMode && strings.Contains(m.textarea.Value(), " ")
 
-	isModelArgMode := isArgMode && strings.HasPrefix(m.textarea.Value(), "/model ")
+	_, _, isModelArgMode := modelArgQuery(m.textarea.Value())
 
 	var sb strings.Builder
 	for i, item := range items {
@@ -1058,7 +1195,9 @@ func (m *SimpleModel) renderFooter() string {
 				Foreground(lipgloss.Color("#888888")).
 				Render(fmt.Sprintf("  🤖 %s: ", ag.ID))
 			lastLine := ag.LastLine
-			if lastLine == "" {
+			if ag.Queue != "" {
+				lastLine = ag.Queue
+			} else if lastLine == "" {
 				lastLine = "(starting...)"
 			}
 			maxLen := m.width - lipgloss.Width(label) - 2
@@ -1129,12 +1268,26 @@ func (m *SimpleModel) renderFooter() string {
 	b.WriteString(modeStr)
 
 	// Always-visible status line: provider/model + session token totals + context status
-	estimatedTokens, compactThreshold, autoCompact := m.contextMgr.CompactionStatus(m.messages)
+	estimatedTokens, compactThreshold, autoCompact := m.contextMgr.CompactionStatus(m.promptMessages())
 	b.WriteString("\n")
 	infoStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Italic(true)
 
 	providerModel := fmt.Sprintf("%s/%s", m.currentProviderName, m.currentModelName)
+	if m.config.Profile != "" {
+		providerModel = m.config.Profile + ": " + providerModel
+	}
+	if m.onceClient != nil {
+		providerModel = m.onceClient.Name() + " (this turn)"
+	} else if m.nextOnceClient != nil {
+		providerModel += " (next turn: " + m.nextOnceClient.Name() + ")"
+	}
 	tokenInfo := fmt.Sprintf("↑%d ↓%d", m.sessionPromptTokens, m.sessionCompletionTokens)
+	if m.sessionCachedTokens > 0 && m.sessionPromptTokens > 0 {
+		tokenInfo += fmt.Sprintf(" cache %d%%", m.sessionCachedTokens*100/m.sessionPromptTokens)
+	}
+	if total := usage.Total(m.usageLedger.Records()); total.Cost > 0 {
+		tokenInfo += fmt.Sprintf(" $%.2f", total.Cost)
+	}
 
 	if autoCompact && compactThreshold > 0 {
 		// Show context fill level with color-coded indicator
@@ -1531,33 +1684,60 @@ func (m *SimpleModel) RenderConfig() tea.Cmd {
 	_, modelCfg, _ := m.config.ActiveModel()
 
 	var temperature float32
-	var modelStr, timeout string
+	var modelStr, timeout, apiKey string
 	if modelCfg != nil {
 		temperature = modelCfg.Temperature
 		modelStr = modelCfg.Model
 	}
 	if provCfg != nil {
-		timeout = provCfg.Timeout.String()
+		timeout = fmt.Sprintf("%s (connect %s, first token %s, idle %s)",
+			provCfg.Timeout, provCfg.ConnectTimeout, provCfg.FirstTokenTimeout, provCfg.IdleTimeout)
+		apiKey = provCfg.APIKeySummary()
+	}
+
+	// Each value is marked with the config layer that set it.
+	prov := "providers." + m.currentProviderName
+	model := prov + ".models." + m.currentModelName
+	src := func(path string) string {
+		return "[" + m.config.Source(path) + "]"
+	}
+	maxTokensSrc := src("default_context.max_tokens")
+	if m.config.Source(model+".context.max_tokens") != config.LayerDefault {
+		maxTokensSrc = src(model + ".context.max_tokens")
+	}
+
+	profile := m.config.Profile
+	if profile == "" {
+		profile = "(none)"
 	}
 
 	configData := fmt.Sprintf(`Current Configuration:
-  Provider: %s
-  Model alias: %s (%s)
-  Temperature: %.2f
-  API Timeout: %s
-  Max Tokens: %d
-  Auto-Compact: %v
-  Sandbox Mode: %s
-  YOLO Mode: %v`,
-		m.currentProviderName,
-		m.currentModelName,
-		modelStr,
-		temperature,
-		timeout,
-		m.activeContext.MaxTokens,
-		m.activeContext.AutoCompact,
-		m.config.Tools.SandboxMode,
+  Profile: %s
+  Provider: %s %s
+  API Key: %s
+  Model alias: %s (%s) %s
+  Temperature: %.2f %s
+  API Timeout: %s %s
+  Max Tokens: %d %s
+  Auto-Compact: %v %s
+  Sandbox Mode: %s %s
+  YOLO Mode: %v
+  Auto-Approve Tools: %s %s
+  Auto-Approve Commands: %s %s
+  Project Config: %s`,
+		profile,
+		m.currentProviderName, src("default.provider"),
+		apiKey,
+		m.currentModelName, modelStr, src("default.model"),
+		temperature, src(model+".temperature"),
+		timeout, src(prov+".timeout"),
+		m.activeContext.MaxTokens, maxTokensSrc,
+		m.activeContext.AutoCompact, src("default_context.auto_compact"),
+		m.config.Tools.SandboxMode, src("tools.sandbox_mode"),
 		m.config.Tools.Yolo,
+		strings.Join(m.config.Tools.AutoApproveTools, ", "), src("tools.auto_approve_tools"),
+		strings.Join(m.config.Tools.AutoApproveCommands, ", "), src("tools.auto_approve_commands"),
+		projectConfigSummary(m.config.Project),
 	)
 
 	configDisplay := lipgloss.NewStyle().
@@ -1574,6 +1754,20 @@ func (m *SimpleModel) RenderConfig() tea.Cmd {
 	return nil
 }
 
+// projectConfigSummary describes the project config file and its trust.
+func projectConfigSummary(p *config.ProjectConfig) string {
+	switch {
+	case p == nil:
+		return "(none)"
+	case len(p.Sensitive) == 0:
+		return p.Path
+	case p.Trusted:
+		return p.Path + " (trusted)"
+	default:
+		return fmt.Sprintf("%s (untrusted, ignoring %s)", p.Path, strings.Join(p.Sensitive, ", "))
+	}
+}
+
 func (m *SimpleModel) RenderStatus() tea.Cmd {
 	cwd, _ := os.Getwd()
 	sessionID := "(none)"
@@ -1584,6 +1778,7 @@ func (m *SimpleModel) RenderStatus() tea.Cmd {
 	status := fmt.Sprintf(`Current Status:
   Mode: %s
   Model: %s (provider: %s)
+  Tokenizer: %s
   Working Dir: %s
   Session ID: %s
   Sandbox Mode: %s
@@ -1593,6 +1788,7 @@ func (m *SimpleModel) RenderStatus() tea.Cmd {
 		strings.ToUpper(m.collaborationMode),
 		m.currentModelName,
 		m.currentProviderName,
+		m.contextMgr.TokenizerName(),
 		cwd,
 		sessionID,
 		m.config.Tools.SandboxMode,
@@ -1611,6 +1807,31 @@ func (m *SimpleModel) RenderStatus() tea.Cmd {
 	return nil
 }
 
+// RenderCost shows the token usage and cost of the current session per model.
+func (m *SimpleModel) RenderCost() tea.Cmd {
+	records := m.usageLedger.Records()
+	if len(records) == 0 {
+		m.AddDisplayContent(lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#626262")).
+			Render("No API usage in this session yet."), "")
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Session usage:\n")
+	if err := usage.WriteTable(&sb, usage.Aggregate(records, "", []string{usage.KeyModel}), []string{usage.KeyModel}); err != nil {
+		slog.Warn("Failed to render usage table", "error", err)
+	}
+	msg := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#626262")).
+		Render(strings.TrimRight(sb.String(), "\n"))
+	for _, line := range strings.Split(msg, "\n") {
+		m.AddDisplayContent(line)
+	}
+	m.AddDisplayContent("")
+	return nil
+}
+
 func (m *SimpleModel) RenderSessions(args []string) tea.Cmd {
 	action := "list"
 	if len(args) > 0 {
@@ -1680,6 +1901,8 @@ func (m *SimpleModel) RenderSessions(args []string) tea.Cmd {
 		}
 
 		m.sess = sess
+		m.usageLedger = usage.NewLedger(sess.Usage)
+		m.toolset = tools.Toolset{}
 		m.isResume = true
 		m.messages = sess.Messages
 		m.session.Messages = sess.Messages
@@ -1840,6 +2063,7 @@ func (m *SimpleModel) runCustomCommand(cmd customcmd.Command, args []string) tea
 
 func (m *SimpleModel) resetDisplayHeader() {
 	m.displayContent = buildHeaderLines(m.config.Tools.Yolo)
+	m.thinkingBlocks = nil
 	m.viewportDirty = true
 }
 
@@ -1996,8 +2220,10 @@ func pathWithin(base, target string) bool {
 }
 
 // Helper functions for managing messages
-func (m *SimpleModel) addUserMessage(content string) {
-	m.messages = append(m.messages, api.NewUserMessage(content))
+func (m *SimpleModel) addUserMessage(content string, images ...api.ContentPart) {
+	msg := api.NewUserMessage(content)
+	msg.AttachImages(images...)
+	m.messages = append(m.messages, msg)
 	// Persist immediately so interrupted requests are resumable.
 	m.saveSession()
 }
@@ -2145,6 +2371,7 @@ func (m *SimpleModel) cancelPendingTools() {
 	m.waitingForApproval = false
 	m.pendingToolCalls = nil
 	m.statusMsg = "Tool execution cancelled"
+	m.endTurn()
 }
 
 // handleToolResult processes tool execution results
@@ -36,6 +36,11 @@ type StreamOutput struct {
 	// ToolLines contains pre-styled tool call summary lines (one entry per line).
 	ToolLines []string
 	Usage     *api.Usage
+	// Model is the "provider/model" that produced the response.
+	Model string
+	// Reasoning is the model's thinking: reasoning_content deltas and the
+	// contents of inline <think> blocks. It is shown as a collapsible block.
+	Reasoning string
 }
 
 // StreamingMsg represents a message chunk during streaming
@@ -45,17 +50,37 @@ type StreamingMsg struct {
 
 // SendMessage sends a user message to the API (SimpleModel version)
 func (m *SimpleModel) SendMessage(input string) tea.Cmd {
+	if name, prompt, ok := parseModelOverride(input); ok {
+		if prompt == "" {
+			m.textarea.SetValue("")
+			m.useModelOnce(name)
+			return nil
+		}
+		client, err := m.newOnceClient(name)
+		if err != nil {
+			m.AddDisplayContent(lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#FF3333")).
+				Render(fmt.Sprintf("Error selecting model: %v", err)), "")
+			return nil
+		}
+		m.nextOnceClient = client
+		input = prompt
+	}
+	m.beginTurn()
+
 	slog.Info("User sending message",
 		slog.Int("length", len(input)))
 
+	images, imageErrs := m.takeInputImages(input)
+
 	// Store the message for display
 	displayInput := compactUserInputForDisplay(input)
 	userMsg := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#04B575")).
 		Bold(true).
-		Render("You: ") + displayInput
+		Render("You: ") + displayInput + imageMarker(len(images))
 
-	m.addUserMessage(input)
+	m.addUserMessage(input, images...)
 	m.textarea.SetValue("")
 	m.loading = true
 	m.statusMsg = "Thinking..."
@@ -73,12 +98,44 @@ func (m *SimpleModel) SendMessage(input string) tea.Cmd {
 
 	// Add user message to display content
 	m.AddDisplayContent(userMsg, "")
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	for _, err := range imageErrs {
+		m.AddDisplayContent(warnStyle.Render("Image not attached: "+err.Error()), "")
+	}
+	if len(images) > 0 && !m.turnClient().SupportsVision() {
+		m.AddDisplayContent(warnStyle.Render("Images are not sent: the model is not configured with vision: true"), "")
+	}
 
 	// Return a command that processes the message, with a heartbeat tick to
 	// keep the display refreshed during the long-running streaming phase.
 	return tea.Batch(m.processMessage(), loadingTick())
 }
 
+// RetryLastTurn resends the conversation after a failed request. Failed
+// streams leave no partial assistant message behind, so the history already
+// ends at the last completed message.
+func (m *SimpleModel) RetryLastTurn() tea.Cmd {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color("#FFA500"))
+	if m.loading {
+		m.AddDisplayContent(style.Render("A request is already running"), "")
+		return nil
+	}
+	if n := len(m.messages); n == 0 || (m.messages[n-1].Role != "user" && m.messages[n-1].Role != "tool") {
+		m.AddDisplayContent(style.Render("Nothing to retry: the last turn completed"), "")
+		return nil
+	}
+
+	m.loading = true
+	m.statusMsg = "Thinking..."
+	m.currentMessage = ""
+	m.currentOperation = "Retrying"
+	m.operationStartedAt = time.Now()
+	m.AddDisplayContent(lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#626262")).
+		Render("Retrying from the last completed message..."), "")
+	return tea.Batch(m.processMessage(), loadingTick())
+}
+
 // cancelCurrentRequest cancels the in-flight API request and resets loading state.
 func (m *SimpleModel) cancelCurrentRequest() {
 	if m.cancelAPICall != nil {
@@ -98,6 +155,7 @@ func (m *SimpleModel) cancelCurrentRequest() {
 	m.statusMsg = "Cancelled"
 	m.currentOperation = ""
 	m.operationStartedAt = time.Time{}
+	m.endTurn()
 	m.saveSession()
 }
 
@@ -106,15 +164,34 @@ func (m *SimpleModel) processMessage() tea.Cmd {
 	// Create a cancellable context now (in Update goroutine) so Escape can cancel it.
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelAPICall = cancel
+	ctx = api.WithRetryNotifier(ctx, func(info api.RetryInfo) {
+		m.currentOperation = info.String()
+	})
+	ctx = api.WithQueueNotifier(ctx, func(info api.QueueInfo) {
+		if info.Waiting {
+			m.currentOperation = info.String()
+		}
+	})
+	client := m.turnClient()
+	m.answeredBy = client.Name()
+	ctx = api.WithFallbackNotifier(ctx, func(info api.FallbackInfo) {
+		m.answeredBy = info.To
+		m.currentOperation = info.String()
+	})
+
+	builtinTools := m.toolset.Select(m.lastUserInput)
 
 	return func() tea.Msg {
+		// Tool definitions count against the context window too.
+		m.contextMgr.SetTools(builtinTools)
+
 		// Staged context management: prune at 80%, summarize at 90%.
-		level := m.contextMgr.CompactionLevel(m.messages)
+		level := m.contextMgr.CompactionLevel(m.promptMessages())
 		if level == appcontext.CompactionPrune {
 			slog.Info("Pruning context (lightweight)", slog.Int("messages", len(m.messages)))
 			m.messages = m.contextMgr.Prune(m.messages)
 			// Re-check: if pruning didn't bring us below threshold, escalate.
-			if m.contextMgr.CompactionLevel(m.messages) >= appcontext.CompactionPrune {
+			if m.contextMgr.CompactionLevel(m.promptMessages()) >= appcontext.CompactionPrune {
 				slog.Info("Pruning insufficient, escalating to summarization")
 				level = appcontext.CompactionSummarize
 			}
@@ -123,7 +200,7 @@ func (m *SimpleModel) processMessage() tea.Cmd {
 			slog.Info("Summarizing context", slog.Int("beforeMessages", len(m.messages)))
 			// Prune is idempotent; skip if already pruned above.
 			msgs := m.messages
-			if m.contextMgr.CompactionLevel(msgs) == appcontext.CompactionSummarize {
+			if m.contextMgr.CompactionLevel(m.promptMessages()) == appcontext.CompactionSummarize {
 				msgs = m.contextMgr.Prune(msgs)
 			}
 			summary, err := m.apiClient.Summarize(ctx, msgs)
@@ -141,12 +218,11 @@ func (m *SimpleModel) processMessage() tea.Cmd {
 		}
 
 		// Stream the response
-		builtinTools := tools.SelectBuiltinTools(m.lastUserInput)
-		msgsToSend := stubOldToolResults(m.messages)
+		msgsToSend := m.promptMessages()
 		slog.Debug("Requesting streaming completion",
 			slog.Int("messages", len(msgsToSend)),
 			slog.Int("tools", len(builtinTools)))
-		stream, err := m.apiClient.StreamChatCompletionWithTools(ctx, msgsToSend, builtinTools)
+		stream, err := client.StreamChatCompletionWithTools(ctx, msgsToSend, builtinTools)
 		if err != nil {
 			if ctx.Err() != nil {
 				// Cancelled by user - not an error worth reporting
@@ -162,17 +238,37 @@ func (m *SimpleModel) processMessage() tea.Cmd {
 		}
 
 		// Start handling stream
-		return m.processStreamNew(ctx, stream)
+		result := m.processStreamNew(ctx, stream)
+		// Only calibrate against the primary model; a fallback model may
+		// tokenize differently.
+		if out, ok := result.(StreamOutput); ok && out.Usage != nil && out.Model == m.apiClient.Name() {
+			m.contextMgr.Calibrate(msgsToSend, out.Usage.PromptTokens)
+		}
+		return result
 	}
 }
 
+// promptMessages returns the history as it is sent to the model, with old
+// tool results stubbed. Token estimates are calibrated against the prompt
+// size the API reports for these messages, so they are also what
+// compaction and the status line estimate.
+func (m *SimpleModel) promptMessages() []api.Message {
+	return stubOldToolResults(m.messages)
+}
+
 // processStreamNew processes streaming responses with proper output collection
 func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.StreamEvent) tea.Msg {
 	var fullContent strings.Builder
 	var toolCalls []api.ToolCall
-	var toolLines []string // pre-styled tool call summary lines for display
+	var reasoningItems []json.RawMessage // opaque Responses API reasoning items
+	var reasoning strings.Builder        // thinking, shown but not sent back unless the model echoes it
+	var toolLines []string               // pre-styled tool call summary lines for display
 	var chunkCount int
 	var usage *api.Usage
+	// partial is set once the incremental assistant message below has been
+	// appended; a failed stream removes it so /retry starts from the last
+	// completed message.
+	partial := false
 	// thinkingFilter strips <think>...</think> blocks from the history while
 	// keeping them in the display output.  See thinking_filter.go for details.
 	var tf thinkingFilter
@@ -198,6 +294,9 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 				return nil
 			}
 			slog.Error("Stream error received", "error", event.Error)
+			if partial {
+				m.messages = m.messages[:len(m.messages)-1]
+			}
 			return errorMsg{error: event.Error}
 		}
 
@@ -215,6 +314,14 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 				choice := event.Data.Choices[0]
 				chunkCount++
 
+				reasoningItems = append(reasoningItems, choice.Delta.ReasoningItems...)
+				if choice.Delta.ReasoningContent != "" {
+					reasoning.WriteString(choice.Delta.ReasoningContent)
+					if fullContent.Len() == 0 {
+						m.currentOperation = fmt.Sprintf("Thinking (%d chars)", reasoning.Len())
+					}
+				}
+
 				// Handle content
 				if choice.Delta.Content != "" {
 					// Run the chunk through the thinking filter.
@@ -222,6 +329,7 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 					//                  renders the full text at stream end.
 					//   historyChunk - written to message history (<think> blocks stripped)
 					_, historyChunk := tf.Feed(choice.Delta.Content)
+					reasoning.WriteString(tf.TakeThought())
 
 					fullContent.WriteString(historyChunk)
 					m.streamingChars = fullContent.Len()
@@ -236,6 +344,7 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 							Role:    "assistant",
 							Content: fullContent.String(),
 						})
+						partial = true
 					}
 				}
 
@@ -329,16 +438,30 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 					if _, flushHistory := tf.Flush(); flushHistory != "" {
 						fullContent.WriteString(flushHistory)
 					}
+					reasoning.WriteString(tf.TakeThought())
+
+					// Reasoning stays out of history unless the model's API
+					// requires it back on later turns.
+					var reasoningContent string
+					if m.turnClient().EchoesReasoning() {
+						reasoningContent = reasoning.String()
+					}
 
 					// Update the complete message
 					if len(m.messages) > 0 && m.messages[len(m.messages)-1].Role == "assistant" {
 						m.messages[len(m.messages)-1].Content = fullContent.String()
 						m.messages[len(m.messages)-1].ToolCalls = toolCalls
+						m.messages[len(m.messages)-1].ReasoningItems = reasoningItems
+						m.messages[len(m.messages)-1].ReasoningContent = reasoningContent
+						m.messages[len(m.messages)-1].Model = m.answeredBy
 					} else {
 						msg := api.Message{
-							Role:      "assistant",
-							Content:   fullContent.String(),
-							ToolCalls: toolCalls,
+							Role:             "assistant",
+							Content:          fullContent.String(),
+							ToolCalls:        toolCalls,
+							ReasoningItems:   reasoningItems,
+							ReasoningContent: reasoningContent,
+							Model:            m.answeredBy,
 						}
 						m.messages = append(m.messages, msg)
 					}
@@ -351,8 +474,12 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 					// Store the final content
 					m.currentMessage = fullContent.String()
 
+					if trailing := api.DrainStream(stream); trailing != nil {
+						usage = trailing
+					}
+
 					// Return the complete output as a StreamOutput message
-					return StreamOutput{AssistantText: fullContent.String(), ToolLines: toolLines, Usage: usage}
+					return StreamOutput{AssistantText: fullContent.String(), ToolLines: toolLines, Usage: usage, Model: m.answeredBy, Reasoning: reasoning.String()}
 				}
 			}
 		}
@@ -363,7 +490,8 @@ func (m *SimpleModel) processStreamNew(ctx context.Context, stream <-chan api.St
 	if _, flushHistory := tf.Flush(); flushHistory != "" {
 		fullContent.WriteString(flushHistory)
 	}
-	return StreamOutput{AssistantText: fullContent.String(), ToolLines: toolLines, Usage: usage}
+	reasoning.WriteString(tf.TakeThought())
+	return StreamOutput{AssistantText: fullContent.String(), ToolLines: toolLines, Usage: usage, Reasoning: reasoning.String()}
 }
 
 // executeNextTool executes the first pending tool call and returns a
@@ -401,8 +529,7 @@ func (m *SimpleModel) executeNextTool() tea.Cmd {
 			m.toolResultStore.Store(tc.ID, result.Output)
 		}
 		// Keep tool outputs compact in message history to reduce prompt tokens.
-		historyOutput := tools.CompactToolResultForHistory(tc.Function.Name, result.Output)
-		m.messages = append(m.messages, api.NewToolMessage(tc.ID, historyOutput))
+		m.messages = append(m.messages, tools.ToolMessage(tc, result, m.turnClient().SupportsVision()))
 
 		return toolExecutionMsg{
 			results:   []api.ToolResult{result},
@@ -413,41 +540,52 @@ func (m *SimpleModel) executeNextTool() tea.Cmd {
 	}
 }
 
-// recentToolResultWindow is the number of most recent tool results to keep
-// in full when stubbing old tool results. Tool results within this window
-// are preserved verbatim for better context quality and prefix cache hits.
+// recentToolResultWindow is the maximum number of most recent tool results
+// kept in full when stubbing old tool results. Tool results within this
+// window are preserved verbatim for better context quality.
 const recentToolResultWindow = 10
 
+// toolResultStubBatch is the number of tool results stubbed at a time.
+// Stubbing rewrites the middle of the conversation and so invalidates the
+// provider's prompt cache from that point on; doing it in batches keeps the
+// request prefix identical for several turns in a row.
+const toolResultStubBatch = 5
+
 // stubOldToolResults replaces tool message content with a lightweight reference
 // for tool messages outside the recent window. The AI can retrieve the full
 // content on demand via the get_tool_result tool.
 //
 // This implements observation masking: tool call metadata (name, arguments) is
 // always preserved so the agent remembers what it did, while verbose outputs
-// are replaced with stubs to reduce context noise.
+// are replaced with stubs to reduce context noise. The oldest tool results
+// are stubbed in batches of toolResultStubBatch, so between
+// recentToolResultWindow-toolResultStubBatch and recentToolResultWindow
+// results stay in full and the result only changes once per batch.
 func stubOldToolResults(messages []api.Message) []api.Message {
-	// Collect indices of all tool messages in reverse order.
 	var toolIndices []int
-	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].Role == "tool" {
+	for i, msg := range messages {
+		if msg.Role == "tool" {
 			toolIndices = append(toolIndices, i)
 		}
 	}
-	if len(toolIndices) == 0 {
+	excess := len(toolIndices) - recentToolResultWindow
+	if excess <= 0 {
 		return messages
 	}
+	// Round up to a whole number of batches.
+	stubCount := (excess + toolResultStubBatch - 1) / toolResultStubBatch * toolResultStubBatch
 
-	// Determine which tool messages are within the recent window.
-	recentSet := make(map[int]bool, recentToolResultWindow)
-	for i := 0; i < len(toolIndices) && i < recentToolResultWindow; i++ {
-		recentSet[toolIndices[i]] = true
+	stubbed := make(map[int]bool, stubCount)
+	for _, i := range toolIndices[:stubCount] {
+		stubbed[i] = true
 	}
 
 	result := make([]api.Message, len(messages))
 	copy(result, messages)
 	for i, msg := range result {
-		if msg.Role == "tool" && !recentSet[i] {
+		if stubbed[i] {
 			result[i].Content = fmt.Sprintf("[stored: use get_tool_result with id=%q to retrieve full output]", msg.ToolCallID)
+			result[i].Parts = nil
 		}
 	}
 	return result
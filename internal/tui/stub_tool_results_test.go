@@ -109,3 +109,64 @@ func TestStubOldToolResults_OldToolsOutsideWindow(t *testing.T) {
 		t.Fatalf("last tool should be kept full, got %q", got[lastToolIdx].Content)
 	}
 }
+
+func TestStubOldToolResults_StubsInBatches(t *testing.T) {
+	t.Parallel()
+
+	build := func(n int) []api.Message {
+		msgs := []api.Message{{Role: "user", Content: "go"}}
+		for i := range n {
+			id := "c" + strings.Repeat("x", i+1)
+			msgs = append(msgs,
+				api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{ID: id}}},
+				api.Message{Role: "tool", ToolCallID: id, Content: "result " + id},
+			)
+		}
+		return msgs
+	}
+	countStubs := func(msgs []api.Message) int {
+		n := 0
+		for _, msg := range msgs {
+			if strings.Contains(msg.Content, "get_tool_result") {
+				n++
+			}
+		}
+		return n
+	}
+
+	// The stubbed prefix must stay the same while tool results accumulate
+	// within a batch, so prompt caches keep hitting.
+	for n, want := range map[int]int{10: 0, 11: 5, 15: 5, 16: 10, 20: 10} {
+		if got := countStubs(stubOldToolResults(build(n))); got != want {
+			t.Errorf("%d tool results: %d stubbed, want %d", n, got, want)
+		}
+	}
+}
+
+func TestContextStatusEstimatesSentMessages(t *testing.T) {
+	server := newDummyChatServer(t, func(_ int, _ api.ChatCompletionRequest) []api.StreamResponse { return nil })
+	defer server.Close()
+	m := newE2EModel(t, server.URL)
+	m.width = 80
+
+	// Five large old tool results are stubbed when sent, so they must not
+	// count towards the context estimate either.
+	m.messages = []api.Message{{Role: "user", Content: "go"}}
+	for i := range 15 {
+		id := "c" + strings.Repeat("x", i+1)
+		content := "ok"
+		if i < 5 {
+			content = strings.Repeat("x", 40000)
+		}
+		m.messages = append(m.messages,
+			api.Message{Role: "assistant", ToolCalls: []api.ToolCall{{ID: id}}},
+			api.Message{Role: "tool", ToolCallID: id, Content: content},
+		)
+	}
+	sent := m.promptMessages()
+	m.contextMgr.Calibrate(sent, m.contextMgr.GetTokenUsage(sent))
+
+	if footer := m.renderFooter(); !strings.Contains(footer, "ctx ~0k/") || strings.Contains(footer, "summarize soon") {
+		t.Fatalf("the estimate should cover the stubbed messages:\n%s", footer)
+	}
+}
@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// thinkingBlock is a model's reasoning shown in the display. It occupies one
+// displayContent entry, rendered collapsed to a summary line or expanded to
+// the full text depending on SimpleModel.showThinking.
+type thinkingBlock struct {
+	line int // index into displayContent
+	text string
+}
+
+var thinkingStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Italic(true)
+
+// addThinkingBlock appends reasoning to the display as a collapsible block.
+func (m *SimpleModel) addThinkingBlock(text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	m.thinkingBlocks = append(m.thinkingBlocks, thinkingBlock{line: len(m.displayContent), text: text})
+	m.AddDisplayContent(renderThinkingBlock(text, m.showThinking, m.width))
+}
+
+// toggleThinking expands or collapses every thinking block.
+func (m *SimpleModel) toggleThinking() {
+	m.showThinking = !m.showThinking
+	for _, b := range m.thinkingBlocks {
+		if b.line < len(m.displayContent) {
+			m.displayContent[b.line] = renderThinkingBlock(b.text, m.showThinking, m.width)
+		}
+	}
+	m.viewportDirty = true
+	m.updateViewportContent()
+	if m.showThinking {
+		m.statusMsg = "Thinking expanded"
+	} else {
+		m.statusMsg = "Thinking collapsed"
+	}
+}
+
+// renderThinkingBlock renders text as a summary line, or when expanded as a
+// header followed by the text wrapped to width beside a left border.
+func renderThinkingBlock(text string, expanded bool, width int) string {
+	if !expanded {
+		lines := strings.Count(text, "\n") + 1
+		return thinkingStyle.Render(fmt.Sprintf("▸ Thought (%d lines, Ctrl+T to expand)", lines))
+	}
+	body := thinkingStyle.
+		Border(lipgloss.NormalBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color("#626262")).
+		PaddingLeft(1)
+	if width > 4 {
+		body = body.Width(width - 2)
+	}
+	return thinkingStyle.Render("▾ Thought (Ctrl+T to collapse)") + "\n" + body.Render(text)
+}
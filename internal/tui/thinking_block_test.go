@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToggleThinkingRerendersBlocks(t *testing.T) {
+	m := &SimpleModel{width: 80}
+	m.AddDisplayContent("before")
+	m.addThinkingBlock("first step\nsecond step\n")
+	m.AddDisplayContent("after")
+
+	if len(m.thinkingBlocks) != 1 || m.thinkingBlocks[0].line != 1 {
+		t.Fatalf("unexpected blocks: %+v", m.thinkingBlocks)
+	}
+	if got := m.displayContent[1]; !strings.Contains(got, "Thought (2 lines") || strings.Contains(got, "first step") {
+		t.Fatalf("block should start collapsed: %q", got)
+	}
+
+	m.toggleThinking()
+	if got := m.displayContent[1]; !strings.Contains(got, "first step") || !strings.Contains(got, "second step") {
+		t.Fatalf("expanded block should show the text: %q", got)
+	}
+	if m.displayContent[2] != "after" {
+		t.Fatalf("other lines must not move: %q", m.displayContent)
+	}
+
+	m.toggleThinking()
+	if strings.Contains(m.displayContent[1], "first step") {
+		t.Fatalf("block should collapse again: %q", m.displayContent[1])
+	}
+}
@@ -38,6 +38,10 @@ type thinkingFilter struct {
 	// continues with "nk>" (opening tag) or something else (not a tag).
 	// The maximum carry length is len("</think>") - 1 = 7 bytes.
 	carry string
+
+	// thought accumulates the text inside <think> blocks (without the tags)
+	// until TakeThought hands it over to the reasoning display.
+	thought strings.Builder
 }
 
 const (
@@ -70,6 +74,7 @@ func (f *thinkingFilter) Feed(chunk string) (displayPart, historyPart string) {
 				// Found the closing tag.
 				// Everything up to and including </think> belongs to display only.
 				display.WriteString(s[:idx+len(thinkClose)])
+				f.thought.WriteString(s[:idx])
 				s = s[idx+len(thinkClose):]
 				f.inThinking = false
 			} else {
@@ -78,6 +83,7 @@ func (f *thinkingFilter) Feed(chunk string) (displayPart, historyPart string) {
 				// maxCarry bytes in carry so the next Feed() can complete the tag.
 				if len(s) > maxCarry {
 					display.WriteString(s[:len(s)-maxCarry])
+					f.thought.WriteString(s[:len(s)-maxCarry])
 					f.carry = s[len(s)-maxCarry:]
 				} else {
 					f.carry = s
@@ -126,8 +132,18 @@ func (f *thinkingFilter) Flush() (displayPart, historyPart string) {
 	f.carry = ""
 	if f.inThinking {
 		// Still inside a think block at EOF — treat as display-only.
+		f.thought.WriteString(s)
 		return s, ""
 	}
 	// Not inside a think block: the carry is plain content.
 	return s, s
 }
+
+// TakeThought returns the text seen inside <think> blocks since the last
+// call, without the tags, so inline thinking can be shown the same way as
+// reasoning_content deltas.
+func (f *thinkingFilter) TakeThought() string {
+	s := f.thought.String()
+	f.thought.Reset()
+	return s
+}
@@ -129,3 +129,22 @@ func TestThinkingFilter_MultipleBlocks(t *testing.T) {
 		t.Errorf("history = %q, want %q", h, "midend")
 	}
 }
+
+// TestThinkingFilter_TakeThought verifies that the text inside think blocks
+// is collected without the tags, including tags split across chunks.
+func TestThinkingFilter_TakeThought(t *testing.T) {
+	var f thinkingFilter
+	var thought strings.Builder
+	for _, c := range []string{"<thi", "nk>step one, ", "step two</th", "ink>answer<think>more"} {
+		f.Feed(c)
+		thought.WriteString(f.TakeThought())
+	}
+	f.Flush()
+	thought.WriteString(f.TakeThought())
+	if got, want := thought.String(), "step one, step twomore"; got != want {
+		t.Errorf("thought = %q, want %q", got, want)
+	}
+	if f.TakeThought() != "" {
+		t.Error("TakeThought should reset the accumulated thought")
+	}
+}
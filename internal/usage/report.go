@@ -0,0 +1,78 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+)
+
+// FormatCost formats the cost of r, marking costs that leave out requests
+// to models without pricing.
+func FormatCost(r Record) string {
+	switch {
+	case r.Unpriced == r.Requests:
+		return "n/a"
+	case r.Unpriced > 0:
+		return fmt.Sprintf("$%.4f*", r.Cost)
+	default:
+		return fmt.Sprintf("$%.4f", r.Cost)
+	}
+}
+
+// WriteTable writes rows as an aligned table with one column per grouping
+// key followed by the usage columns and a total line.
+func WriteTable(w io.Writer, rows []Row, keys []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var header []any
+	for _, k := range Keys {
+		if slices.Contains(keys, k) {
+			header = append(header, k)
+		}
+	}
+	columns := len(header)
+	header = append(header, "requests", "input", "cached", "output", "cost")
+	writeRow(tw, header)
+
+	var total Record
+	for _, row := range rows {
+		var cells []any
+		if slices.Contains(keys, KeyDay) {
+			cells = append(cells, row.Date)
+		}
+		if slices.Contains(keys, KeyProject) {
+			cells = append(cells, row.Project)
+		}
+		if slices.Contains(keys, KeyModel) {
+			cells = append(cells, row.Model)
+		}
+		writeRow(tw, append(cells, row.Requests, row.PromptTokens, row.CachedTokens, row.CompletionTokens, FormatCost(row.Record)))
+		total.add(row.Record)
+	}
+	if len(rows) > 1 {
+		cells := make([]any, columns)
+		for i := range cells {
+			cells[i] = ""
+		}
+		cells[0] = "total"
+		writeRow(tw, append(cells, total.Requests, total.PromptTokens, total.CachedTokens, total.CompletionTokens, FormatCost(total)))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if total.Unpriced > 0 && total.Unpriced < total.Requests {
+		_, err := io.WriteString(w, "* excludes requests to models without pricing\n")
+		return err
+	}
+	return nil
+}
+
+func writeRow(w io.Writer, cells []any) {
+	for i, c := range cells {
+		if i > 0 {
+			_, _ = io.WriteString(w, "\t")
+		}
+		_, _ = fmt.Fprint(w, c)
+	}
+	_, _ = io.WriteString(w, "\t\n")
+}
@@ -0,0 +1,173 @@
+// Package usage accumulates token usage and cost per day and model, and
+// aggregates it across sessions for reporting.
+package usage
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+)
+
+// Record is the usage of one model on one day.
+type Record struct {
+	Date             string  `json:"date"`  // local date, YYYY-MM-DD
+	Model            string  `json:"model"` // "provider/model"
+	Requests         int     `json:"requests"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CachedTokens     int     `json:"cached_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost,omitempty"`
+	// Unpriced counts requests made while the model had no pricing, so
+	// reports can tell a zero cost from an unknown one.
+	Unpriced int `json:"unpriced,omitempty"`
+}
+
+func (r *Record) add(o Record) {
+	r.Requests += o.Requests
+	r.PromptTokens += o.PromptTokens
+	r.CachedTokens += o.CachedTokens
+	r.CompletionTokens += o.CompletionTokens
+	r.Cost += o.Cost
+	r.Unpriced += o.Unpriced
+}
+
+// Ledger accumulates usage reports. It is safe for concurrent use, since
+// subagents report usage from their own goroutines.
+type Ledger struct {
+	mu      sync.Mutex
+	records []Record
+	now     func() time.Time
+}
+
+// NewLedger returns a ledger continuing from records (e.g. loaded from a
+// resumed session).
+func NewLedger(records []Record) *Ledger {
+	return &Ledger{records: slices.Clone(records), now: time.Now}
+}
+
+// Add records one API request. Its cost is computed with the pricing in
+// effect now, so later price changes do not rewrite history.
+func (l *Ledger) Add(report api.UsageReport) {
+	rec := Record{
+		Date:             l.now().Format(time.DateOnly),
+		Model:            report.Model,
+		Requests:         1,
+		PromptTokens:     report.Usage.PromptTokens,
+		CachedTokens:     report.Usage.CachedTokens(),
+		CompletionTokens: report.Usage.CompletionTokens,
+	}
+	if report.Pricing != nil {
+		rec.Cost = report.Pricing.Cost(rec.PromptTokens, rec.CachedTokens, rec.CompletionTokens)
+	} else {
+		rec.Unpriced = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.records {
+		if l.records[i].Date == rec.Date && l.records[i].Model == rec.Model {
+			l.records[i].add(rec)
+			return
+		}
+	}
+	l.records = append(l.records, rec)
+}
+
+// Records returns a copy of the accumulated records.
+func (l *Ledger) Records() []Record {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return slices.Clone(l.records)
+}
+
+// Since returns the records dated on or after day (YYYY-MM-DD).
+func Since(records []Record, day string) []Record {
+	var out []Record
+	for _, r := range records {
+		if r.Date >= day {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Total returns the sum of all records.
+func Total(records []Record) Record {
+	var total Record
+	for _, r := range records {
+		total.add(r)
+	}
+	return total
+}
+
+// Keys accepted by Aggregate.
+const (
+	KeyDay     = "day"
+	KeyProject = "project"
+	KeyModel   = "model"
+)
+
+// Keys lists the grouping keys accepted by Aggregate.
+var Keys = []string{KeyDay, KeyProject, KeyModel}
+
+// Row is one line of an aggregated report. Date, Project and Model are set
+// only when grouping by them.
+type Row struct {
+	Project string
+	Record
+}
+
+// Aggregate sums records from one project (a session working directory)
+// grouped by keys.
+func Aggregate(records []Record, project string, keys []string) []Row {
+	return AggregateSessions([]SessionRecords{{Project: project, Records: records}}, keys)
+}
+
+// SessionRecords is the usage stored in one session.
+type SessionRecords struct {
+	Project string
+	Records []Record
+}
+
+// AggregateSessions sums the records of many sessions grouped by keys. Rows
+// are sorted by day and project, then by descending cost.
+func AggregateSessions(sessions []SessionRecords, keys []string) []Row {
+	type groupKey struct{ day, project, model string }
+	index := make(map[groupKey]int)
+	var rows []Row
+	for _, s := range sessions {
+		for _, r := range s.Records {
+			var key groupKey
+			for _, k := range keys {
+				switch k {
+				case KeyDay:
+					key.day = r.Date
+				case KeyProject:
+					key.project = s.Project
+				case KeyModel:
+					key.model = r.Model
+				}
+			}
+			i, ok := index[key]
+			if !ok {
+				i = len(rows)
+				index[key] = i
+				rows = append(rows, Row{Project: key.project, Record: Record{Date: key.day, Model: key.model}})
+			}
+			rows[i].add(r)
+		}
+	}
+	slices.SortFunc(rows, func(a, b Row) int {
+		return cmp.Or(
+			strings.Compare(a.Date, b.Date),
+			strings.Compare(a.Project, b.Project),
+			-cmp.Compare(a.Cost, b.Cost),
+			strings.Compare(a.Model, b.Model),
+		)
+	})
+	return rows
+}
@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tokuhirom/ashron/internal/api"
+	"github.com/tokuhirom/ashron/internal/config"
+)
+
+func TestLedgerAccumulatesPerDayAndModel(t *testing.T) {
+	day := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	l := NewLedger(nil)
+	l.now = func() time.Time { return day }
+
+	pricing := &config.Pricing{Input: 2, Output: 8, Cached: 0.5}
+	l.Add(api.UsageReport{Model: "openai/gpt", Pricing: pricing, Usage: api.Usage{
+		PromptTokens: 1_000_000, CompletionTokens: 500_000,
+		PromptTokensDetails: &api.PromptTokensDetails{CachedTokens: 400_000},
+	}})
+	l.Add(api.UsageReport{Model: "openai/gpt", Pricing: pricing, Usage: api.Usage{PromptTokens: 100_000}})
+	l.Add(api.UsageReport{Model: "local/llama", Usage: api.Usage{PromptTokens: 10, CompletionTokens: 5}})
+	day = day.AddDate(0, 0, 1)
+	l.Add(api.UsageReport{Model: "openai/gpt", Pricing: pricing, Usage: api.Usage{CompletionTokens: 1_000_000}})
+
+	records := l.Records()
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %+v", records)
+	}
+	gpt := records[0]
+	// 600k input * $2 + 400k cached * $0.5 + 500k output * $8 + 100k input * $2
+	if gpt.Date != "2026-03-01" || gpt.Requests != 2 || gpt.PromptTokens != 1_100_000 || gpt.CachedTokens != 400_000 {
+		t.Fatalf("unexpected record: %+v", gpt)
+	}
+	if want := 1.2 + 0.2 + 4 + 0.2; gpt.Cost < want-1e-9 || gpt.Cost > want+1e-9 {
+		t.Fatalf("cost = %v, want %v", gpt.Cost, want)
+	}
+	if records[1].Unpriced != 1 || FormatCost(records[1]) != "n/a" {
+		t.Fatalf("unpriced model should be marked: %+v", records[1])
+	}
+	if records[2].Date != "2026-03-02" || records[2].Cost != 8 {
+		t.Fatalf("unexpected next-day record: %+v", records[2])
+	}
+	if got := len(Since(records, "2026-03-02")); got != 1 {
+		t.Fatalf("Since returned %d records, want 1", got)
+	}
+}
+
+func TestAggregateSessions(t *testing.T) {
+	sessions := []SessionRecords{
+		{Project: "/src/a", Records: []Record{
+			{Date: "2026-03-01", Model: "p/x", Requests: 1, PromptTokens: 10, Cost: 1},
+			{Date: "2026-03-02", Model: "p/y", Requests: 2, PromptTokens: 20, Cost: 3},
+		}},
+		{Project: "/src/b", Records: []Record{
+			{Date: "2026-03-01", Model: "p/x", Requests: 1, PromptTokens: 5, Cost: 0.5},
+		}},
+	}
+
+	byModel := AggregateSessions(sessions, []string{KeyModel})
+	if len(byModel) != 2 || byModel[0].Model != "p/y" || byModel[1].Model != "p/x" || byModel[1].PromptTokens != 15 {
+		t.Fatalf("unexpected rows by model: %+v", byModel)
+	}
+
+	byDayProject := AggregateSessions(sessions, []string{KeyDay, KeyProject})
+	if len(byDayProject) != 3 || byDayProject[0].Date != "2026-03-01" || byDayProject[0].Project != "/src/a" || byDayProject[0].Model != "" {
+		t.Fatalf("unexpected rows by day and project: %+v", byDayProject)
+	}
+
+	var sb strings.Builder
+	if err := WriteTable(&sb, byModel, []string{KeyModel}); err != nil {
+		t.Fatal(err)
+	}
+	out := sb.String()
+	for _, want := range []string{"model", "p/y", "$3.0000", "total", "$4.5000"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("table missing %q:\n%s", want, out)
+		}
+	}
+}